@@ -0,0 +1,430 @@
+package collab
+
+import (
+	"ai-task-manager/embeddings"
+	"ai-task-manager/events"
+	"ai-task-manager/models"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	// historyLimit bounds the edits kept per session for transforming late
+	// operations; a client further behind has to join again.
+	historyLimit = 1000
+	// MaxLength bounds a description edited collaboratively, in characters.
+	MaxLength = 100000
+)
+
+var (
+	ErrNotEditing    = errors.New("not editing this task")
+	ErrStaleRevision = errors.New("revision is too old, join again")
+	ErrTooLong       = fmt.Errorf("description must not exceed %d characters", MaxLength)
+)
+
+// Frames sent to editors.
+const (
+	FrameJoined  = "edit_joined"
+	FrameAck     = "edit_ack"
+	FrameApplied = "edit_applied"
+	FrameLeft    = "edit_left"
+	FrameClosed  = "edit_closed"
+)
+
+// Frame is a message to an editor. Revision is the document revision after
+// the frame; an editor that sees a gap in revisions missed a frame and has
+// to join again.
+type Frame struct {
+	Type     string     `json:"type"`
+	TaskID   uuid.UUID  `json:"taskID"`
+	Revision int        `json:"revision"`
+	Text     *string    `json:"text,omitempty"`
+	Op       *Operation `json:"op,omitempty"`
+	// UserID is the author of an applied edit, or nil for a change saved
+	// through the REST API.
+	UserID  *uuid.UUID  `json:"userID,omitempty"`
+	Editors []uuid.UUID `json:"editors,omitempty"`
+	Reason  string      `json:"reason,omitempty"`
+}
+
+// Peer is a connection taking part in editing sessions. Send must not block.
+type Peer interface {
+	UserID() uuid.UUID
+	Send(frame Frame)
+}
+
+// Hub holds one editing session per task being edited on this instance.
+type Hub struct {
+	db *gorm.DB
+	// load reads a task when a peer joins.
+	load func(taskID uuid.UUID) (*models.Task, error)
+
+	mu       sync.Mutex
+	sessions map[uuid.UUID]*session
+	editing  map[Peer]map[uuid.UUID]bool
+}
+
+// session is the live document of one task. revision counts the edits
+// applied since the session started; history holds the latest of them.
+type session struct {
+	mu       sync.Mutex
+	taskID   uuid.UUID
+	text     []rune
+	revision int
+	history  []Operation
+	peers    map[Peer]bool
+	closed   bool
+
+	// saved is the description last read from or written to the database,
+	// at savedRevision.
+	saved         []rune
+	savedRevision int
+	lastEditor    uuid.UUID
+}
+
+// Default is the hub configured at startup.
+var Default *Hub
+
+func NewHub(db *gorm.DB) *Hub {
+	return newHub(db, func(taskID uuid.UUID) (*models.Task, error) {
+		var task models.Task
+		if err := db.First(&task, "task_id = ?", taskID).Error; err != nil {
+			return nil, err
+		}
+		return &task, nil
+	})
+}
+
+func newHub(db *gorm.DB, load func(taskID uuid.UUID) (*models.Task, error)) *Hub {
+	return &Hub{
+		db:       db,
+		load:     load,
+		sessions: make(map[uuid.UUID]*session),
+		editing:  make(map[Peer]map[uuid.UUID]bool),
+	}
+}
+
+// Join adds peer to the task's session, starting one from the stored
+// description if needed, and sends it the current text. Only the task's
+// owner and assignee may join; anyone else is told the task does not exist.
+func (h *Hub) Join(peer Peer, taskID uuid.UUID) error {
+	for {
+		task, err := h.load(taskID)
+		if err != nil {
+			return err
+		}
+		if !task.VisibleTo(peer.UserID()) {
+			return gorm.ErrRecordNotFound
+		}
+		s := h.session(task)
+
+		s.mu.Lock()
+		if s.closed {
+			// The last editor left while we were joining; start over.
+			s.mu.Unlock()
+			continue
+		}
+		s.peers[peer] = true
+		h.mu.Lock()
+		if h.editing[peer] == nil {
+			h.editing[peer] = make(map[uuid.UUID]bool)
+		}
+		h.editing[peer][taskID] = true
+		h.mu.Unlock()
+
+		text := string(s.text)
+		peer.Send(Frame{Type: FrameJoined, TaskID: taskID, Revision: s.revision, Text: &text, Editors: s.editors()})
+		s.mu.Unlock()
+		return nil
+	}
+}
+
+// session returns the task's session, starting one from task if there is
+// none.
+func (h *Hub) session(task *models.Task) *session {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if s := h.sessions[task.TaskID]; s != nil {
+		return s
+	}
+	s := &session{
+		taskID: task.TaskID,
+		text:   []rune(task.Description),
+		saved:  []rune(task.Description),
+		peers:  make(map[Peer]bool),
+	}
+	h.sessions[task.TaskID] = s
+	return s
+}
+
+// Edit applies op, made by peer at revision, to the task's document. The op
+// is transformed against the edits applied since, acknowledged to peer and
+// sent to the other editors.
+func (h *Hub) Edit(peer Peer, taskID uuid.UUID, revision int, op Operation) error {
+	h.mu.Lock()
+	s := h.sessions[taskID]
+	h.mu.Unlock()
+	if s == nil {
+		return ErrNotEditing
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.peers[peer] {
+		return ErrNotEditing
+	}
+	if revision < 0 || revision > s.revision {
+		return fmt.Errorf("%w: unknown revision %d", ErrInvalidOperation, revision)
+	}
+	if revision < s.revision-len(s.history) {
+		return ErrStaleRevision
+	}
+
+	for _, concurrent := range s.history[len(s.history)-(s.revision-revision):] {
+		var err error
+		if op, _, err = Transform(op, concurrent); err != nil {
+			return err
+		}
+	}
+	text, err := op.Apply(s.text)
+	if err != nil {
+		return err
+	}
+	if len(text) > MaxLength {
+		return ErrTooLong
+	}
+
+	userID := peer.UserID()
+	s.apply(op, text)
+	s.lastEditor = userID
+	peer.Send(Frame{Type: FrameAck, TaskID: taskID, Revision: s.revision})
+	s.broadcast(peer, Frame{Type: FrameApplied, TaskID: taskID, Revision: s.revision, Op: &op, UserID: &userID})
+	return nil
+}
+
+// Leave removes peer from the task's session. The last editor to leave saves
+// the document and ends the session.
+func (h *Hub) Leave(peer Peer, taskID uuid.UUID) error {
+	h.mu.Lock()
+	if !h.editing[peer][taskID] {
+		h.mu.Unlock()
+		return ErrNotEditing
+	}
+	delete(h.editing[peer], taskID)
+	if len(h.editing[peer]) == 0 {
+		delete(h.editing, peer)
+	}
+	s := h.sessions[taskID]
+	h.mu.Unlock()
+
+	if s != nil {
+		h.leave(s, peer)
+	}
+	peer.Send(Frame{Type: FrameLeft, TaskID: taskID})
+	return nil
+}
+
+// LeaveAll removes peer from every session, for a closed connection.
+func (h *Hub) LeaveAll(peer Peer) {
+	h.mu.Lock()
+	var sessions []*session
+	for taskID := range h.editing[peer] {
+		if s := h.sessions[taskID]; s != nil {
+			sessions = append(sessions, s)
+		}
+	}
+	delete(h.editing, peer)
+	h.mu.Unlock()
+
+	for _, s := range sessions {
+		h.leave(s, peer)
+	}
+}
+
+func (h *Hub) leave(s *session, peer Peer) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.peers, peer)
+	if len(s.peers) > 0 || s.closed {
+		return
+	}
+	if err := h.save(s); err != nil {
+		log.Printf("Error saving description of task %s: %v", s.taskID, err)
+	}
+	h.close(s)
+}
+
+// close ends a session. The caller holds s.mu.
+func (h *Hub) close(s *session) {
+	s.closed = true
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.sessions[s.taskID] == s {
+		delete(h.sessions, s.taskID)
+	}
+	for peer := range s.peers {
+		delete(h.editing[peer], s.taskID)
+		if len(h.editing[peer]) == 0 {
+			delete(h.editing, peer)
+		}
+	}
+}
+
+// Run saves the open sessions every interval until ctx is cancelled.
+func (h *Hub) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		h.mu.Lock()
+		sessions := make([]*session, 0, len(h.sessions))
+		for _, s := range h.sessions {
+			sessions = append(sessions, s)
+		}
+		h.mu.Unlock()
+
+		for _, s := range sessions {
+			s.mu.Lock()
+			if !s.closed {
+				if err := h.save(s); err != nil {
+					log.Printf("Error saving description of task %s: %v", s.taskID, err)
+				}
+			}
+			s.mu.Unlock()
+		}
+	}
+}
+
+// save writes the document to the task. A description changed through the
+// REST API since the last save is first merged into the document as an edit
+// of its own, so neither side loses its changes. A deleted task ends the
+// session. The caller holds s.mu.
+func (h *Hub) save(s *session) error {
+	var before, after models.Task
+	deleted, changed := false, false
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&before, "task_id = ?", s.taskID).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			deleted = true
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if stored := []rune(before.Description); string(stored) != string(s.saved) {
+			s.merge(stored)
+		}
+		// Descriptions must not be empty, so an emptied document waits for
+		// more text.
+		if string(s.text) == before.Description || len(s.text) == 0 {
+			return nil
+		}
+
+		after = before
+		after.Description = string(s.text)
+		if err := models.SaveTaskVersioned(tx, &after); err != nil {
+			return err
+		}
+		changed = true
+		return models.RecordTaskEvents(tx, models.DiffTask(s.lastEditor, &before, &after)...)
+	})
+	if err != nil {
+		return err
+	}
+	if deleted {
+		s.broadcast(nil, Frame{Type: FrameClosed, TaskID: s.taskID, Revision: s.revision, Reason: "task deleted"})
+		h.close(s)
+		return nil
+	}
+	if !changed {
+		return nil
+	}
+
+	s.saved = []rune(after.Description)
+	s.savedRevision = s.revision
+	events.Publish(events.NewTaskChanged(s.lastEditor, &before, &after)...)
+	if embeddings.Default != nil {
+		embeddings.Default.Enqueue(after.TaskID)
+	}
+	return nil
+}
+
+// merge applies the change from s.saved to stored, made outside the
+// session, as an edit based on savedRevision. The caller holds s.mu.
+func (s *session) merge(stored []rune) {
+	op := Diff(s.saved, stored)
+	base := s.savedRevision
+	defer func() {
+		s.saved, s.savedRevision = stored, s.revision
+	}()
+
+	if s.revision-len(s.history) <= base {
+		transformed := op
+		var err error
+		for _, concurrent := range s.history[len(s.history)-(s.revision-base):] {
+			if transformed, _, err = Transform(transformed, concurrent); err != nil {
+				break
+			}
+		}
+		if err == nil {
+			if text, err := transformed.Apply(s.text); err == nil {
+				s.apply(transformed, text)
+				s.broadcast(nil, Frame{Type: FrameApplied, TaskID: s.taskID, Revision: s.revision, Op: &transformed})
+				return
+			}
+		}
+	}
+
+	// The edits since the last save are too many to transform against, so
+	// the stored description wins.
+	op = Diff(s.text, stored)
+	s.apply(op, stored)
+	s.broadcast(nil, Frame{Type: FrameApplied, TaskID: s.taskID, Revision: s.revision, Op: &op})
+}
+
+func (s *session) apply(op Operation, text []rune) {
+	s.text = text
+	s.revision++
+	s.history = append(s.history, op)
+	if len(s.history) > historyLimit {
+		s.history = s.history[len(s.history)-historyLimit:]
+	}
+}
+
+// broadcast sends frame to every editor but except.
+func (s *session) broadcast(except Peer, frame Frame) {
+	for peer := range s.peers {
+		if peer != except {
+			peer.Send(frame)
+		}
+	}
+}
+
+// editors lists the users in the session.
+func (s *session) editors() []uuid.UUID {
+	seen := make(map[uuid.UUID]bool)
+	var editors []uuid.UUID
+	for peer := range s.peers {
+		if userID := peer.UserID(); !seen[userID] {
+			seen[userID] = true
+			editors = append(editors, userID)
+		}
+	}
+	return editors
+}
@@ -0,0 +1,242 @@
+package collab
+
+import (
+	"ai-task-manager/models"
+	"errors"
+	"math/rand"
+	"sync"
+	"testing"
+
+	"github.com/gofrs/uuid"
+	"gorm.io/gorm"
+)
+
+// testPeer queues the frames it is sent for its editor to read.
+type testPeer struct {
+	userID uuid.UUID
+	frames chan Frame
+}
+
+func newTestPeer(userID uuid.UUID) *testPeer {
+	return &testPeer{userID: userID, frames: make(chan Frame, 4096)}
+}
+
+func (p *testPeer) UserID() uuid.UUID {
+	return p.userID
+}
+
+func (p *testPeer) Send(frame Frame) {
+	p.frames <- frame
+}
+
+func newTestHub(task models.Task) *Hub {
+	return newHub(nil, func(taskID uuid.UUID) (*models.Task, error) {
+		if taskID != task.TaskID {
+			return nil, gorm.ErrRecordNotFound
+		}
+		loaded := task
+		return &loaded, nil
+	})
+}
+
+// editor is a client in the style of ot.js: it applies its own edits at
+// once, sends one at a time and transforms the ones not acknowledged yet
+// against the edits of others.
+type editor struct {
+	t      *testing.T
+	hub    *Hub
+	peer   *testPeer
+	taskID uuid.UUID
+
+	doc      []rune
+	revision int
+	// pending holds the local edits the server has not acknowledged. The
+	// first one has been sent.
+	pending []Operation
+}
+
+func (e *editor) join() {
+	if err := e.hub.Join(e.peer, e.taskID); err != nil {
+		e.t.Fatalf("Join: %v", err)
+	}
+	frame := <-e.peer.frames
+	e.doc, e.revision = []rune(*frame.Text), frame.Revision
+}
+
+func (e *editor) edit(op Operation) {
+	doc, err := op.Apply(e.doc)
+	if err != nil {
+		e.t.Errorf("local edit: %v", err)
+		return
+	}
+	e.doc = doc
+	e.pending = append(e.pending, op)
+	if len(e.pending) == 1 {
+		e.send()
+	}
+}
+
+func (e *editor) send() {
+	if err := e.hub.Edit(e.peer, e.taskID, e.revision, e.pending[0]); err != nil {
+		e.t.Errorf("Edit at revision %d: %v", e.revision, err)
+	}
+}
+
+// receive handles the frames that have arrived so far.
+func (e *editor) receive() {
+	for {
+		select {
+		case frame := <-e.peer.frames:
+			e.handle(frame)
+		default:
+			return
+		}
+	}
+}
+
+func (e *editor) handle(frame Frame) {
+	if frame.Revision != e.revision+1 {
+		e.t.Errorf("%s frame at revision %d, expected %d", frame.Type, frame.Revision, e.revision+1)
+	}
+	e.revision = frame.Revision
+
+	switch frame.Type {
+	case FrameAck:
+		e.pending = e.pending[1:]
+		if len(e.pending) > 0 {
+			e.send()
+		}
+	case FrameApplied:
+		op := *frame.Op
+		for i := range e.pending {
+			var err error
+			if e.pending[i], op, err = Transform(e.pending[i], op); err != nil {
+				e.t.Errorf("transforming against a remote edit: %v", err)
+				return
+			}
+		}
+		doc, err := op.Apply(e.doc)
+		if err != nil {
+			e.t.Errorf("remote edit: %v", err)
+			return
+		}
+		e.doc = doc
+	}
+}
+
+func TestJoinRequiresOwnerOrAssignee(t *testing.T) {
+	owner, assignee, stranger := uuid.Must(uuid.NewV4()), uuid.Must(uuid.NewV4()), uuid.Must(uuid.NewV4())
+	task := models.Task{TaskID: uuid.Must(uuid.NewV4()), UserID: owner, AssignedTo: assignee, Description: "text"}
+	hub := newTestHub(task)
+
+	for _, user := range []uuid.UUID{owner, assignee} {
+		if err := hub.Join(newTestPeer(user), task.TaskID); err != nil {
+			t.Errorf("Join by %s: %v", user, err)
+		}
+	}
+	if err := hub.Join(newTestPeer(stranger), task.TaskID); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Errorf("Join by a stranger: err = %v, want ErrRecordNotFound", err)
+	}
+	if err := hub.Edit(newTestPeer(stranger), task.TaskID, 0, Operation{}); !errors.Is(err, ErrNotEditing) {
+		t.Errorf("Edit by a stranger: err = %v, want ErrNotEditing", err)
+	}
+}
+
+// TestConcurrentEditorsConverge has editors type at once, each sending its
+// edits based on whatever revision it has caught up to, and checks that they
+// all end with the server's document.
+func TestConcurrentEditorsConverge(t *testing.T) {
+	// An editor may fall behind by all the others' edits, which must stay
+	// within the history.
+	const editors, edits = 8, historyLimit / 8
+	owner := uuid.Must(uuid.NewV4())
+	task := models.Task{TaskID: uuid.Must(uuid.NewV4()), UserID: owner, Description: "the quick brown fox"}
+	hub := newTestHub(task)
+
+	clients := make([]*editor, editors)
+	for i := range clients {
+		clients[i] = &editor{t: t, hub: hub, peer: newTestPeer(owner), taskID: task.TaskID}
+		clients[i].join()
+	}
+
+	var wg sync.WaitGroup
+	for i, client := range clients {
+		wg.Add(1)
+		go func(client *editor, seed int64) {
+			defer wg.Done()
+			r := rand.New(rand.NewSource(seed))
+			for n := 0; n < edits; n++ {
+				// Falling behind makes the next edits arrive at stale
+				// revisions.
+				if r.Intn(3) == 0 {
+					client.receive()
+				}
+				client.edit(randomOperation(r, len(client.doc)))
+			}
+		}(client, int64(i))
+	}
+	wg.Wait()
+
+	// Handling an acknowledgement sends the next buffered edit, which sends
+	// frames to the others, so catch up until nothing is left in flight.
+	for settled := false; !settled; {
+		settled = true
+		for _, client := range clients {
+			if len(client.peer.frames) > 0 {
+				settled = false
+				client.receive()
+			}
+		}
+	}
+
+	s := hub.sessions[task.TaskID]
+	s.mu.Lock()
+	want, revision := string(s.text), s.revision
+	s.mu.Unlock()
+	if revision != editors*edits {
+		t.Errorf("server revision = %d, want %d", revision, editors*edits)
+	}
+	for i, client := range clients {
+		if len(client.pending) > 0 || client.revision != revision {
+			t.Errorf("editor %d is at revision %d with %d edits pending", i, client.revision, len(client.pending))
+		}
+		if got := string(client.doc); got != want {
+			t.Errorf("editor %d has %q, server has %q", i, got, want)
+		}
+	}
+}
+
+func TestEditBeyondHistoryIsStale(t *testing.T) {
+	owner := uuid.Must(uuid.NewV4())
+	task := models.Task{TaskID: uuid.Must(uuid.NewV4()), UserID: owner, Description: "x"}
+	hub := newTestHub(task)
+	busy, idle := newTestPeer(owner), newTestPeer(owner)
+	for _, peer := range []*testPeer{busy, idle} {
+		if err := hub.Join(peer, task.TaskID); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	length := 1
+	for revision := 0; revision <= historyLimit; revision++ {
+		var op Operation
+		op.Retain(length).Insert([]rune("y"))
+		if err := hub.Edit(busy, task.TaskID, revision, op); err != nil {
+			t.Fatalf("Edit at revision %d: %v", revision, err)
+		}
+		length++
+		// Nobody reads these frames; keep the queues from filling up.
+		for len(busy.frames) > 0 {
+			<-busy.frames
+		}
+		for len(idle.frames) > 0 {
+			<-idle.frames
+		}
+	}
+
+	var op Operation
+	op.Retain(1).Insert([]rune("z"))
+	if err := hub.Edit(idle, task.TaskID, 0, op); !errors.Is(err, ErrStaleRevision) {
+		t.Errorf("Edit at an evicted revision: err = %v, want ErrStaleRevision", err)
+	}
+}
@@ -0,0 +1,285 @@
+// Package collab lets several users edit a task description at once. Edits
+// are operational transforms in the format of ot.js: the server orders them,
+// transforms each against the edits its author had not seen yet, and saves
+// the result to the task periodically.
+package collab
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"unicode/utf8"
+)
+
+var ErrInvalidOperation = errors.New("invalid operation")
+
+// component is one step of an operation: it retains, deletes or inserts.
+type component struct {
+	retain int
+	delete int
+	insert []rune
+}
+
+// Operation transforms a document of BaseLength characters into one of
+// TargetLength characters, walking it from start to end. In JSON it is an
+// array where a positive number retains that many characters, a negative
+// number deletes that many and a string is inserted, for example
+//
+//	[5, "brave ", -3, 12]
+//
+// Lengths count Unicode code points, not bytes or UTF-16 units.
+type Operation struct {
+	components   []component
+	BaseLength   int
+	TargetLength int
+}
+
+func (o *Operation) Retain(n int) *Operation {
+	if n <= 0 {
+		return o
+	}
+	o.BaseLength += n
+	o.TargetLength += n
+	if last := len(o.components) - 1; last >= 0 && o.components[last].retain > 0 {
+		o.components[last].retain += n
+		return o
+	}
+	o.components = append(o.components, component{retain: n})
+	return o
+}
+
+// Insert adds text at the current position. Inserts always come before an
+// adjacent delete, so equal edits have a single representation.
+func (o *Operation) Insert(text []rune) *Operation {
+	if len(text) == 0 {
+		return o
+	}
+	o.TargetLength += len(text)
+	last := len(o.components) - 1
+	switch {
+	case last >= 0 && o.components[last].insert != nil:
+		o.components[last].insert = append(o.components[last].insert, text...)
+	case last >= 0 && o.components[last].delete > 0:
+		if last > 0 && o.components[last-1].insert != nil {
+			o.components[last-1].insert = append(o.components[last-1].insert, text...)
+		} else {
+			deleted := o.components[last]
+			o.components[last] = component{insert: append([]rune(nil), text...)}
+			o.components = append(o.components, deleted)
+		}
+	default:
+		o.components = append(o.components, component{insert: append([]rune(nil), text...)})
+	}
+	return o
+}
+
+func (o *Operation) Delete(n int) *Operation {
+	if n <= 0 {
+		return o
+	}
+	o.BaseLength += n
+	if last := len(o.components) - 1; last >= 0 && o.components[last].delete > 0 {
+		o.components[last].delete += n
+		return o
+	}
+	o.components = append(o.components, component{delete: n})
+	return o
+}
+
+// IsNoop reports whether the operation leaves the document unchanged.
+func (o Operation) IsNoop() bool {
+	for _, c := range o.components {
+		if c.retain == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Apply returns the document the operation turns doc into.
+func (o Operation) Apply(doc []rune) ([]rune, error) {
+	if len(doc) != o.BaseLength {
+		return nil, fmt.Errorf("%w: it applies to %d characters, not %d", ErrInvalidOperation, o.BaseLength, len(doc))
+	}
+	result := make([]rune, 0, o.TargetLength)
+	position := 0
+	for _, c := range o.components {
+		switch {
+		case c.retain > 0:
+			if c.retain > len(doc)-position {
+				return nil, fmt.Errorf("%w: it retains past the end of the document", ErrInvalidOperation)
+			}
+			result = append(result, doc[position:position+c.retain]...)
+			position += c.retain
+		case c.delete > 0:
+			if c.delete > len(doc)-position {
+				return nil, fmt.Errorf("%w: it deletes past the end of the document", ErrInvalidOperation)
+			}
+			position += c.delete
+		default:
+			result = append(result, c.insert...)
+		}
+	}
+	return result, nil
+}
+
+// Transform takes two operations made concurrently on the same document and
+// returns a' and b' such that applying a then b' gives the same document as
+// applying b then a'. When both insert at the same place, a's text comes
+// first.
+func Transform(a, b Operation) (Operation, Operation, error) {
+	if a.BaseLength != b.BaseLength {
+		return Operation{}, Operation{}, fmt.Errorf("%w: concurrent operations apply to %d and %d characters", ErrInvalidOperation, a.BaseLength, b.BaseLength)
+	}
+
+	var aPrime, bPrime Operation
+	ops1, ops2 := a.components, b.components
+	op1, op2 := pop(&ops1), pop(&ops2)
+
+	for op1 != nil || op2 != nil {
+		if op1 != nil && op1.insert != nil {
+			aPrime.Insert(op1.insert)
+			bPrime.Retain(len(op1.insert))
+			op1 = pop(&ops1)
+			continue
+		}
+		if op2 != nil && op2.insert != nil {
+			aPrime.Retain(len(op2.insert))
+			bPrime.Insert(op2.insert)
+			op2 = pop(&ops2)
+			continue
+		}
+		if op1 == nil || op2 == nil {
+			return Operation{}, Operation{}, fmt.Errorf("%w: operations do not cover the same document", ErrInvalidOperation)
+		}
+
+		length1, length2 := op1.retain+op1.delete, op2.retain+op2.delete
+		n := min(length1, length2)
+		switch {
+		case op1.retain > 0 && op2.retain > 0:
+			aPrime.Retain(n)
+			bPrime.Retain(n)
+		case op1.delete > 0 && op2.retain > 0:
+			aPrime.Delete(n)
+		case op1.retain > 0 && op2.delete > 0:
+			bPrime.Delete(n)
+		}
+		// Both deleting the same characters leaves nothing to do.
+
+		op1 = shorten(op1, n, &ops1)
+		op2 = shorten(op2, n, &ops2)
+	}
+	return aPrime, bPrime, nil
+}
+
+// pop takes a copy of the first component off ops, or returns nil.
+func pop(ops *[]component) *component {
+	if len(*ops) == 0 {
+		return nil
+	}
+	c := (*ops)[0]
+	*ops = (*ops)[1:]
+	return &c
+}
+
+// shorten consumes n characters of a retain or delete, moving on to the next
+// component once it is used up.
+func shorten(c *component, n int, ops *[]component) *component {
+	if c.retain > 0 {
+		c.retain -= n
+		if c.retain > 0 {
+			return c
+		}
+	} else {
+		c.delete -= n
+		if c.delete > 0 {
+			return c
+		}
+	}
+	return pop(ops)
+}
+
+// Diff returns an operation turning from into to, as a single replacement
+// between their common prefix and suffix.
+func Diff(from, to []rune) Operation {
+	prefix := 0
+	for prefix < len(from) && prefix < len(to) && from[prefix] == to[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(from)-prefix && suffix < len(to)-prefix && from[len(from)-1-suffix] == to[len(to)-1-suffix] {
+		suffix++
+	}
+
+	var op Operation
+	op.Retain(prefix)
+	op.Insert(to[prefix : len(to)-suffix])
+	op.Delete(len(from) - prefix - suffix)
+	op.Retain(suffix)
+	return op
+}
+
+func (o Operation) MarshalJSON() ([]byte, error) {
+	parts := make([]any, len(o.components))
+	for i, c := range o.components {
+		switch {
+		case c.retain > 0:
+			parts[i] = c.retain
+		case c.delete > 0:
+			parts[i] = -c.delete
+		default:
+			parts[i] = string(c.insert)
+		}
+	}
+	return json.Marshal(parts)
+}
+
+// UnmarshalJSON decodes an operation from a client. No single retain, delete
+// or insert may cover more than MaxLength characters, and the lengths of the
+// whole operation must not overflow.
+func (o *Operation) UnmarshalJSON(data []byte) error {
+	var parts []json.RawMessage
+	if err := json.Unmarshal(data, &parts); err != nil {
+		return fmt.Errorf("%w: an operation is an array of numbers and strings", ErrInvalidOperation)
+	}
+
+	*o = Operation{}
+	for _, part := range parts {
+		if strings.HasPrefix(string(part), `"`) {
+			var text string
+			if err := json.Unmarshal(part, &text); err != nil || text == "" || !utf8.ValidString(text) {
+				return fmt.Errorf("%w: inserts must be non-empty strings", ErrInvalidOperation)
+			}
+			runes := []rune(text)
+			if len(runes) > MaxLength {
+				return fmt.Errorf("%w: an insert must not exceed %d characters", ErrInvalidOperation, MaxLength)
+			}
+			if o.TargetLength > math.MaxInt-len(runes) {
+				return fmt.Errorf("%w: the operation is too long", ErrInvalidOperation)
+			}
+			o.Insert(runes)
+			continue
+		}
+		var n int
+		if err := json.Unmarshal(part, &n); err != nil || n == 0 {
+			return fmt.Errorf("%w: retains and deletes must be non-zero integers", ErrInvalidOperation)
+		}
+		if n < -MaxLength || n > MaxLength {
+			return fmt.Errorf("%w: a retain or delete must not exceed %d characters", ErrInvalidOperation, MaxLength)
+		}
+		if n > 0 {
+			if o.BaseLength > math.MaxInt-n || o.TargetLength > math.MaxInt-n {
+				return fmt.Errorf("%w: the operation is too long", ErrInvalidOperation)
+			}
+			o.Retain(n)
+		} else {
+			if o.BaseLength > math.MaxInt+n {
+				return fmt.Errorf("%w: the operation is too long", ErrInvalidOperation)
+			}
+			o.Delete(-n)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,152 @@
+package collab
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+const alphabet = "abcdé漢"
+
+func randomText(r *rand.Rand, n int) []rune {
+	letters := []rune(alphabet)
+	text := make([]rune, n)
+	for i := range text {
+		text[i] = letters[r.Intn(len(letters))]
+	}
+	return text
+}
+
+// randomOperation returns an operation on a document of length characters.
+func randomOperation(r *rand.Rand, length int) Operation {
+	var op Operation
+	for position := 0; position < length; {
+		n := 1 + r.Intn(length-position)
+		switch r.Intn(3) {
+		case 0:
+			op.Retain(n)
+			position += n
+		case 1:
+			op.Delete(n)
+			position += n
+		default:
+			op.Insert(randomText(r, 1+r.Intn(3)))
+		}
+	}
+	if r.Intn(2) == 0 {
+		op.Insert(randomText(r, 1+r.Intn(3)))
+	}
+	return op
+}
+
+func mustApply(t *testing.T, op Operation, doc []rune) []rune {
+	t.Helper()
+	result, err := op.Apply(doc)
+	if err != nil {
+		t.Fatalf("Apply(%v) to %q: %v", op, string(doc), err)
+	}
+	return result
+}
+
+func TestTransformConverges(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 5000; i++ {
+		doc := randomText(r, r.Intn(20))
+		a, b := randomOperation(r, len(doc)), randomOperation(r, len(doc))
+
+		aPrime, bPrime, err := Transform(a, b)
+		if err != nil {
+			t.Fatalf("Transform(%v, %v): %v", a, b, err)
+		}
+		left := mustApply(t, bPrime, mustApply(t, a, doc))
+		right := mustApply(t, aPrime, mustApply(t, b, doc))
+		if string(left) != string(right) {
+			t.Fatalf("doc %q, a %v, b %v: a then b' gives %q, b then a' gives %q", string(doc), a, b, string(left), string(right))
+		}
+	}
+}
+
+func TestTransformInsertTieBreak(t *testing.T) {
+	doc := []rune("ac")
+	var a, b Operation
+	a.Retain(1).Insert([]rune("X")).Retain(1)
+	b.Retain(1).Insert([]rune("Y")).Retain(1)
+
+	aPrime, bPrime, err := Transform(a, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := string(mustApply(t, bPrime, mustApply(t, a, doc))); got != "aXYc" {
+		t.Errorf("a then b' = %q, want %q", got, "aXYc")
+	}
+	if got := string(mustApply(t, aPrime, mustApply(t, b, doc))); got != "aXYc" {
+		t.Errorf("b then a' = %q, want %q", got, "aXYc")
+	}
+}
+
+func TestTransformRejectsDifferentBases(t *testing.T) {
+	var a, b Operation
+	a.Retain(3)
+	b.Retain(4)
+	if _, _, err := Transform(a, b); !errors.Is(err, ErrInvalidOperation) {
+		t.Errorf("Transform of operations on different lengths: err = %v", err)
+	}
+}
+
+func TestDiff(t *testing.T) {
+	r := rand.New(rand.NewSource(2))
+	for i := 0; i < 1000; i++ {
+		from, to := randomText(r, r.Intn(12)), randomText(r, r.Intn(12))
+		if got := mustApply(t, Diff(from, to), from); string(got) != string(to) {
+			t.Fatalf("Diff(%q, %q) applied gives %q", string(from), string(to), string(got))
+		}
+	}
+}
+
+func TestOperationJSON(t *testing.T) {
+	var op Operation
+	if err := json.Unmarshal([]byte(`[5, "brave ", -3, 12]`), &op); err != nil {
+		t.Fatal(err)
+	}
+	if op.BaseLength != 20 || op.TargetLength != 23 {
+		t.Errorf("lengths = %d, %d, want 20, 23", op.BaseLength, op.TargetLength)
+	}
+	encoded, err := json.Marshal(op)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(encoded) != `[5,"brave ",-3,12]` {
+		t.Errorf("Marshal = %s", encoded)
+	}
+}
+
+func TestOperationJSONRejectsOversizedCounts(t *testing.T) {
+	tests := []string{
+		`{}`,
+		`[0]`,
+		`[""]`,
+		`[1.5]`,
+		fmt.Sprintf(`[%d]`, MaxLength+1),
+		fmt.Sprintf(`[%d]`, -MaxLength-1),
+		`[9223372036854775807, 1]`,
+		`[-9223372036854775807, -1]`,
+		fmt.Sprintf(`[%q]`, strings.Repeat("a", MaxLength+1)),
+	}
+	for _, input := range tests {
+		var op Operation
+		if err := json.Unmarshal([]byte(input), &op); !errors.Is(err, ErrInvalidOperation) {
+			t.Errorf("Unmarshal(%.40s): err = %v, want ErrInvalidOperation", input, err)
+		}
+	}
+}
+
+func TestApplyRejectsWrongLength(t *testing.T) {
+	var op Operation
+	op.Retain(2).Delete(1)
+	if _, err := op.Apply([]rune("ab")); !errors.Is(err, ErrInvalidOperation) {
+		t.Errorf("Apply to a shorter document: err = %v", err)
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
@@ -27,6 +29,68 @@ type Config struct {
 	JWTSecret     string
 	JWTExpiryTime time.Duration
 	OpenAIAPIKey  string
+	AdminEmails   []string
+
+	// Embeddings and duplicate detection
+	EmbeddingProvider  string
+	EmbeddingModel     string
+	DuplicateThreshold float64
+
+	// Bulk task operations
+	BulkMaxOperations int
+
+	// Optimistic concurrency: reject task writes without an If-Match header
+	RequireIfMatch bool
+
+	// Idempotency keys
+	IdempotencyKeyTTL time.Duration
+
+	// Task import
+	ImportMaxSize int64
+
+	// Trash
+	TrashRetention     time.Duration
+	TrashPurgeInterval time.Duration
+
+	// File attachments
+	StorageBackend         string
+	StorageLocalDir        string
+	S3Endpoint             string
+	S3Region               string
+	S3Bucket               string
+	S3AccessKey            string
+	S3SecretKey            string
+	S3UsePathStyle         bool
+	AttachmentMaxSize      int64
+	AttachmentAllowedTypes []string
+	AttachmentURLSecret    string
+	AttachmentURLTTL       time.Duration
+
+	// WebSockets
+	WebSocketAllowedOrigins []string
+	WebSocketTicketTTL      time.Duration
+	WebSocketSendQueueSize  int
+	WebSocketSlowClient     string
+	WebSocketPongTimeout    time.Duration
+	WebSocketWriteTimeout   time.Duration
+	WebSocketMaxMessageSize int64
+
+	// Events
+	EventBackend         string
+	EventChannel         string
+	EventLogRetention    time.Duration
+	EventLogMaxEntries   int64
+	EventReplayLimit     int
+	EventStreamKeepAlive time.Duration
+
+	// Collaborative editing
+	CollabSaveInterval time.Duration
+
+	// Webhooks
+	WebhookTimeout              time.Duration
+	WebhookMaxAttempts          int
+	WebhookAllowPrivateNetworks bool
+	WebhookDeliveryRetention    time.Duration
 }
 
 func LoadEnvFile() error {
@@ -62,8 +126,144 @@ func LoadConfig() error {
 			SSLMode:       os.Getenv("DB_SSLMODE"),
 			JWTExpiryTime: expiryTime,
 			OpenAIAPIKey:  os.Getenv("OPENAI_API_KEY"),
+
+			EventBackend:        getEnvOrDefault("EVENT_BACKEND", "inprocess"),
+			EventChannel:        getEnvOrDefault("EVENT_CHANNEL", "task_manager_events"),
+			StorageBackend:      getEnvOrDefault("STORAGE_BACKEND", "local"),
+			StorageLocalDir:     getEnvOrDefault("STORAGE_LOCAL_DIR", "./uploads"),
+			S3Endpoint:          os.Getenv("S3_ENDPOINT"),
+			S3Region:            getEnvOrDefault("S3_REGION", "us-east-1"),
+			S3Bucket:            os.Getenv("S3_BUCKET"),
+			S3AccessKey:         os.Getenv("S3_ACCESS_KEY"),
+			S3SecretKey:         os.Getenv("S3_SECRET_KEY"),
+			AttachmentURLSecret: getEnvOrDefault("ATTACHMENT_URL_SECRET", os.Getenv("JWT_SECRET")),
+			EmbeddingProvider:   getEnvOrDefault("EMBEDDING_PROVIDER", "openai"),
+			EmbeddingModel:      getEnvOrDefault("EMBEDDING_MODEL", "text-embedding-3-small"),
 		}
 
+		if config.S3UsePathStyle, err = parseBoolEnv("S3_USE_PATH_STYLE", true); err != nil {
+			loadErr = err
+			return
+		}
+		if config.AttachmentMaxSize, err = parseInt64Env("ATTACHMENT_MAX_SIZE", 10<<20); err != nil {
+			loadErr = err
+			return
+		}
+		if config.AttachmentURLTTL, err = parseDurationEnv("ATTACHMENT_URL_TTL", 15*time.Minute); err != nil {
+			loadErr = err
+			return
+		}
+		if config.DuplicateThreshold, err = parseFloatEnv("DUPLICATE_THRESHOLD", 0.85); err != nil {
+			loadErr = err
+			return
+		}
+		bulkMaxOperations, err := parseInt64Env("BULK_MAX_OPERATIONS", 100)
+		if err != nil {
+			loadErr = err
+			return
+		}
+		config.BulkMaxOperations = int(bulkMaxOperations)
+		if config.RequireIfMatch, err = parseBoolEnv("REQUIRE_IF_MATCH", false); err != nil {
+			loadErr = err
+			return
+		}
+		if config.IdempotencyKeyTTL, err = parseDurationEnv("IDEMPOTENCY_KEY_TTL", 24*time.Hour); err != nil {
+			loadErr = err
+			return
+		}
+		if config.ImportMaxSize, err = parseInt64Env("IMPORT_MAX_SIZE", 50<<20); err != nil {
+			loadErr = err
+			return
+		}
+		if config.TrashRetention, err = parseDurationEnv("TRASH_RETENTION", 30*24*time.Hour); err != nil {
+			loadErr = err
+			return
+		}
+		if config.TrashPurgeInterval, err = parseIntervalEnv("TRASH_PURGE_INTERVAL", time.Hour); err != nil {
+			loadErr = err
+			return
+		}
+		if config.WebSocketTicketTTL, err = parseDurationEnv("WEBSOCKET_TICKET_TTL", 30*time.Second); err != nil {
+			loadErr = err
+			return
+		}
+		sendQueueSize, err := parseInt64Env("WEBSOCKET_SEND_QUEUE_SIZE", 64)
+		if err != nil {
+			loadErr = err
+			return
+		}
+		if sendQueueSize < 1 {
+			loadErr = fmt.Errorf("invalid WEBSOCKET_SEND_QUEUE_SIZE %d: must be at least 1", sendQueueSize)
+			return
+		}
+		config.WebSocketSendQueueSize = int(sendQueueSize)
+		config.WebSocketSlowClient = getEnvOrDefault("WEBSOCKET_SLOW_CLIENT_POLICY", "disconnect")
+		if config.WebSocketSlowClient != "disconnect" && config.WebSocketSlowClient != "drop" {
+			loadErr = fmt.Errorf("invalid WEBSOCKET_SLOW_CLIENT_POLICY %q: must be disconnect or drop", config.WebSocketSlowClient)
+			return
+		}
+		if config.WebSocketPongTimeout, err = parseDurationEnv("WEBSOCKET_PONG_TIMEOUT", 60*time.Second); err != nil {
+			loadErr = err
+			return
+		}
+		if config.WebSocketWriteTimeout, err = parseDurationEnv("WEBSOCKET_WRITE_TIMEOUT", 10*time.Second); err != nil {
+			loadErr = err
+			return
+		}
+		if config.WebSocketMaxMessageSize, err = parseInt64Env("WEBSOCKET_MAX_MESSAGE_SIZE", 64<<10); err != nil {
+			loadErr = err
+			return
+		}
+		if config.EventLogRetention, err = parseDurationEnv("EVENT_LOG_RETENTION", 24*time.Hour); err != nil {
+			loadErr = err
+			return
+		}
+		if config.EventLogMaxEntries, err = parseInt64Env("EVENT_LOG_MAX_ENTRIES", 100000); err != nil {
+			loadErr = err
+			return
+		}
+		replayLimit, err := parseInt64Env("EVENT_REPLAY_LIMIT", 1000)
+		if err != nil {
+			loadErr = err
+			return
+		}
+		config.EventReplayLimit = int(replayLimit)
+		if config.EventStreamKeepAlive, err = parseIntervalEnv("EVENT_STREAM_KEEPALIVE", 15*time.Second); err != nil {
+			loadErr = err
+			return
+		}
+		if config.CollabSaveInterval, err = parseIntervalEnv("COLLAB_SAVE_INTERVAL", 5*time.Second); err != nil {
+			loadErr = err
+			return
+		}
+		if config.WebhookTimeout, err = parseDurationEnv("WEBHOOK_TIMEOUT", 10*time.Second); err != nil {
+			loadErr = err
+			return
+		}
+		webhookMaxAttempts, err := parseInt64Env("WEBHOOK_MAX_ATTEMPTS", 8)
+		if err != nil {
+			loadErr = err
+			return
+		}
+		if webhookMaxAttempts < 1 {
+			loadErr = fmt.Errorf("invalid WEBHOOK_MAX_ATTEMPTS %d: must be at least 1", webhookMaxAttempts)
+			return
+		}
+		config.WebhookMaxAttempts = int(webhookMaxAttempts)
+		if config.WebhookAllowPrivateNetworks, err = parseBoolEnv("WEBHOOK_ALLOW_PRIVATE_NETWORKS", false); err != nil {
+			loadErr = err
+			return
+		}
+		if config.WebhookDeliveryRetention, err = parseDurationEnv("WEBHOOK_DELIVERY_RETENTION", 30*24*time.Hour); err != nil {
+			loadErr = err
+			return
+		}
+		config.AdminEmails = parseListEnv("ADMIN_EMAILS", nil)
+		config.WebSocketAllowedOrigins = parseListEnv("WEBSOCKET_ALLOWED_ORIGINS", nil)
+		config.AttachmentAllowedTypes = parseListEnv("ATTACHMENT_ALLOWED_TYPES", []string{
+			"image/*", "text/plain", "application/pdf", "application/zip",
+		})
+
 		// Validate required fields
 		requiredFields := map[string]string{
 			"DB_HOST":        config.DBHost,
@@ -98,3 +298,85 @@ func GetConfig() *Config {
 	}
 	return &config
 }
+
+func getEnvOrDefault(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
+
+func parseBoolEnv(key string, fallback bool) (bool, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback, nil
+	}
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		return fallback, fmt.Errorf("invalid %s format: %w", key, err)
+	}
+	return parsed, nil
+}
+
+func parseInt64Env(key string, fallback int64) (int64, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback, nil
+	}
+	parsed, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return fallback, fmt.Errorf("invalid %s format: %w", key, err)
+	}
+	return parsed, nil
+}
+
+func parseFloatEnv(key string, fallback float64) (float64, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback, nil
+	}
+	parsed, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return fallback, fmt.Errorf("invalid %s format: %w", key, err)
+	}
+	return parsed, nil
+}
+
+func parseDurationEnv(key string, fallback time.Duration) (time.Duration, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback, nil
+	}
+	parsed, err := time.ParseDuration(value)
+	if err != nil {
+		return fallback, fmt.Errorf("invalid %s format: %w", key, err)
+	}
+	return parsed, nil
+}
+
+// parseIntervalEnv parses a duration that drives a ticker, so it must be
+// positive.
+func parseIntervalEnv(key string, fallback time.Duration) (time.Duration, error) {
+	parsed, err := parseDurationEnv(key, fallback)
+	if err != nil {
+		return fallback, err
+	}
+	if parsed <= 0 {
+		return fallback, fmt.Errorf("invalid %s %s: must be positive", key, parsed)
+	}
+	return parsed, nil
+}
+
+func parseListEnv(key string, fallback []string) []string {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
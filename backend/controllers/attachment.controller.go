@@ -0,0 +1,339 @@
+package controllers
+
+import (
+	"ai-task-manager/config"
+	"ai-task-manager/models"
+	"ai-task-manager/storage"
+	"ai-task-manager/utils"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// multipartOverhead is the slack allowed on top of the file size limit for
+// multipart boundaries and part headers.
+const multipartOverhead = 1 << 20
+
+type AttachmentController interface {
+	UploadAttachment(c *gin.Context)
+	GetAttachments(c *gin.Context)
+	GetDownloadURL(c *gin.Context)
+	DownloadAttachment(c *gin.Context)
+	DeleteAttachment(c *gin.Context)
+}
+
+type attachmentController struct {
+	db    *gorm.DB
+	store storage.Storage
+}
+
+func NewAttachmentController(db *gorm.DB, store storage.Storage) AttachmentController {
+	return &attachmentController{
+		db:    db,
+		store: store,
+	}
+}
+
+type attachmentResponse struct {
+	models.Attachment
+	DownloadURL string `json:"downloadURL"`
+}
+
+func (a *attachmentController) UploadAttachment(c *gin.Context) {
+	uuidUserID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	uuidTaskID, err := utils.IsUUID(c.Param("taskID"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "Error: convert taskID into UUID", err.Error())
+		return
+	}
+
+	task, ok := findVisibleTask(c, a.db, uuidUserID, uuidTaskID)
+	if !ok {
+		return
+	}
+
+	cfg := config.GetConfig()
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, cfg.AttachmentMaxSize+multipartOverhead)
+
+	reader, err := c.Request.MultipartReader()
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid input", err.Error())
+		return
+	}
+
+	// Stream the "file" part to a temporary file while hashing it, so large
+	// uploads are never held in memory.
+	var (
+		fileName    string
+		contentType string
+		size        int64
+		hash        string
+		tmp         *os.File
+	)
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Invalid multipart body", err.Error())
+			return
+		}
+		if part.FormName() != "file" || part.FileName() == "" {
+			part.Close()
+			continue
+		}
+
+		fileName = filepath.Base(part.FileName())
+		head := make([]byte, 512)
+		n, err := io.ReadFull(part, head)
+		if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Error reading file", err.Error())
+			return
+		}
+		head = head[:n]
+		contentType = http.DetectContentType(head)
+		if !isAllowedContentType(contentType, cfg.AttachmentAllowedTypes) {
+			utils.ErrorResponse(c, http.StatusUnsupportedMediaType, "File type not allowed", contentType)
+			return
+		}
+
+		tmp, err = os.CreateTemp("", "attachment-*")
+		if err != nil {
+			utils.ErrorResponse(c, http.StatusInternalServerError, "Error storing upload", err.Error())
+			return
+		}
+		defer os.Remove(tmp.Name())
+		defer tmp.Close()
+
+		hasher := sha256.New()
+		body := io.LimitReader(io.MultiReader(bytes.NewReader(head), part), cfg.AttachmentMaxSize+1)
+		size, err = io.Copy(io.MultiWriter(tmp, hasher), body)
+		if err != nil {
+			var maxBytesErr *http.MaxBytesError
+			if errors.As(err, &maxBytesErr) {
+				utils.ErrorResponse(c, http.StatusRequestEntityTooLarge, "File too large", fmt.Sprintf("maximum size is %d bytes", cfg.AttachmentMaxSize))
+				return
+			}
+			utils.ErrorResponse(c, http.StatusBadRequest, "Error reading file", err.Error())
+			return
+		}
+		if size > cfg.AttachmentMaxSize {
+			utils.ErrorResponse(c, http.StatusRequestEntityTooLarge, "File too large", fmt.Sprintf("maximum size is %d bytes", cfg.AttachmentMaxSize))
+			return
+		}
+		hash = hex.EncodeToString(hasher.Sum(nil))
+		break
+	}
+
+	if tmp == nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid input", "multipart field \"file\" is required")
+		return
+	}
+
+	key := storage.ContentKey(hash)
+	attachment := models.Attachment{
+		TaskID:      task.TaskID,
+		UserID:      uuidUserID,
+		FileName:    fileName,
+		ContentType: contentType,
+		Size:        size,
+		Hash:        hash,
+		StorageKey:  key,
+	}
+	err = a.db.Transaction(func(tx *gorm.DB) error {
+		// The lock keeps the orphan sweeper from deleting an existing object
+		// between the check below and the new reference being committed.
+		if err := models.LockStorageKey(tx, key); err != nil {
+			return err
+		}
+		exists, err := a.store.Exists(c.Request.Context(), key)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+				return err
+			}
+			if err := a.store.Put(c.Request.Context(), key, tmp, size, contentType); err != nil {
+				return err
+			}
+		}
+		return tx.Create(&attachment).Error
+	})
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Error storing upload", err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusCreated, "Attachment uploaded successfully", newAttachmentResponse(attachment))
+}
+
+// GetAttachments lists the attachments of a task the caller owns or is
+// assigned, with signed download URLs.
+func (a *attachmentController) GetAttachments(c *gin.Context) {
+	uuidUserID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	uuidTaskID, err := utils.IsUUID(c.Param("taskID"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "Error: convert taskID into UUID", err.Error())
+		return
+	}
+	if _, ok := findVisibleTask(c, a.db, uuidUserID, uuidTaskID); !ok {
+		return
+	}
+
+	var attachments []models.Attachment
+	if err := a.db.Where("task_id = ?", uuidTaskID).Order("created_at ASC").Find(&attachments).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Error retrieving attachments", err.Error())
+		return
+	}
+
+	response := make([]attachmentResponse, 0, len(attachments))
+	for _, attachment := range attachments {
+		response = append(response, newAttachmentResponse(attachment))
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Attachments retrieved successfully", response)
+}
+
+// GetDownloadURL signs a fresh download URL for an attachment of a task the
+// caller owns or is assigned.
+func (a *attachmentController) GetDownloadURL(c *gin.Context) {
+	uuidUserID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	attachment, ok := a.findAttachment(c)
+	if !ok {
+		return
+	}
+	if _, ok := findVisibleTask(c, a.db, uuidUserID, attachment.TaskID); !ok {
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Download URL generated successfully", newAttachmentResponse(attachment))
+}
+
+// DownloadAttachment serves the file for a signed, time-limited URL. It is
+// registered without the auth middleware; the signature is the credential.
+func (a *attachmentController) DownloadAttachment(c *gin.Context) {
+	attachmentID := c.Param("attachmentID")
+	if !utils.VerifyExpiring(config.GetConfig().AttachmentURLSecret, attachmentID, c.Query("expires"), c.Query("signature")) {
+		utils.ErrorResponse(c, http.StatusForbidden, "Invalid or expired download link", utils.ErrUnauthorized)
+		return
+	}
+
+	attachment, ok := a.findAttachment(c)
+	if !ok {
+		return
+	}
+
+	object, err := a.store.Get(c.Request.Context(), attachment.StorageKey)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, storage.ErrNotFound) {
+			status = http.StatusNotFound
+		}
+		utils.ErrorResponse(c, status, "Error reading attachment", err.Error())
+		return
+	}
+	defer object.Close()
+
+	c.DataFromReader(http.StatusOK, attachment.Size, attachment.ContentType, object, map[string]string{
+		"Content-Disposition": mime.FormatMediaType("attachment", map[string]string{"filename": attachment.FileName}),
+		"ETag":                strconv.Quote(attachment.Hash),
+	})
+}
+
+func (a *attachmentController) DeleteAttachment(c *gin.Context) {
+	uuidUserID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	attachment, ok := a.findAttachment(c)
+	if !ok {
+		return
+	}
+
+	if attachment.UserID != uuidUserID {
+		var task models.Task
+		if err := a.db.First(&task, "task_id = ?", attachment.TaskID).Error; err != nil || task.UserID != uuidUserID {
+			utils.ErrorResponse(c, http.StatusForbidden, "Only the uploader or task owner can delete this attachment", utils.ErrUnauthorized)
+			return
+		}
+	}
+
+	if err := a.db.Delete(&attachment).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Error deleting attachment", err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Attachment deleted successfully", nil)
+}
+
+func (a *attachmentController) findAttachment(c *gin.Context) (models.Attachment, bool) {
+	var attachment models.Attachment
+
+	uuidAttachmentID, err := utils.IsUUID(c.Param("attachmentID"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "Error: convert attachmentID into UUID", err.Error())
+		return attachment, false
+	}
+
+	if err := a.db.First(&attachment, "attachment_id = ?", uuidAttachmentID).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "Attachment not found", err.Error())
+		return attachment, false
+	}
+
+	return attachment, true
+}
+
+func newAttachmentResponse(attachment models.Attachment) attachmentResponse {
+	cfg := config.GetConfig()
+	id := attachment.AttachmentID.String()
+	expires, signature := utils.SignExpiring(cfg.AttachmentURLSecret, id, cfg.AttachmentURLTTL)
+	return attachmentResponse{
+		Attachment:  attachment,
+		DownloadURL: fmt.Sprintf("/api/v1/attachments/download/%s?expires=%s&signature=%s", id, expires, signature),
+	}
+}
+
+// isAllowedContentType matches a sniffed MIME type against the configured
+// allowlist, which may contain wildcards such as "image/*".
+func isAllowedContentType(contentType string, allowed []string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	for _, pattern := range allowed {
+		if pattern == "*/*" || pattern == mediaType {
+			return true
+		}
+		if strings.HasSuffix(pattern, "/*") && strings.HasPrefix(mediaType, strings.TrimSuffix(pattern, "*")) {
+			return true
+		}
+	}
+	return false
+}
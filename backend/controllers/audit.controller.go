@@ -0,0 +1,147 @@
+package controllers
+
+import (
+	"ai-task-manager/models"
+	"ai-task-manager/utils"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultHistoryPageSize = 50
+	maxHistoryPageSize     = 200
+)
+
+type AuditController interface {
+	GetTaskHistory(c *gin.Context)
+	GetAuditLogs(c *gin.Context)
+}
+
+type auditController struct {
+	db *gorm.DB
+}
+
+func NewAuditController(db *gorm.DB) AuditController {
+	return &auditController{
+		db: db,
+	}
+}
+
+// GetTaskHistory lists the changes made to a task the caller owns or is
+// assigned, oldest first.
+func (a *auditController) GetTaskHistory(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	uuidTaskID, err := utils.IsUUID(c.Param("taskID"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "Error: convert taskID into UUID", err.Error())
+		return
+	}
+
+	// Deleted tasks still have a history worth reading.
+	var task models.Task
+	if err := a.db.Unscoped().Scopes(models.VisibleTasks(userID)).First(&task, "task_id = ?", uuidTaskID).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "Task not found", err.Error())
+		return
+	}
+
+	page, limit := utils.ParsePagination(c.Query("page"), c.Query("limit"), defaultHistoryPageSize, maxHistoryPageSize)
+
+	query := a.db.Model(&models.TaskEvent{}).Where("task_id = ?", uuidTaskID)
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Error counting task history", err.Error())
+		return
+	}
+
+	var events []models.TaskEvent
+	if err := query.Order("created_at ASC, event_id ASC").
+		Offset((page - 1) * limit).
+		Limit(limit).
+		Find(&events).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Error retrieving task history", err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Task history retrieved successfully", gin.H{
+		"events": events,
+		"page":   page,
+		"limit":  limit,
+		"total":  total,
+	})
+}
+
+// GetAuditLogs lists audit entries for admins, filterable by actorID,
+// entityType, entityID, action and an RFC 3339 from/to time range.
+func (a *auditController) GetAuditLogs(c *gin.Context) {
+	query := a.db.Model(&models.AuditLog{})
+
+	if actorID := c.Query("actorID"); actorID != "" {
+		uuidActorID, err := utils.IsUUID(actorID)
+		if err != nil {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Invalid actorID", err.Error())
+			return
+		}
+		query = query.Where("actor_id = ?", uuidActorID)
+	}
+	if entityType := c.Query("entityType"); entityType != "" {
+		query = query.Where("entity_type = ?", entityType)
+	}
+	if entityID := c.Query("entityID"); entityID != "" {
+		uuidEntityID, err := utils.IsUUID(entityID)
+		if err != nil {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Invalid entityID", err.Error())
+			return
+		}
+		query = query.Where("entity_id = ?", uuidEntityID)
+	}
+	if action := c.Query("action"); action != "" {
+		query = query.Where("action = ?", action)
+	}
+	if from := c.Query("from"); from != "" {
+		fromTime, err := time.Parse(time.RFC3339, from)
+		if err != nil {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Invalid from time, expected RFC 3339", err.Error())
+			return
+		}
+		query = query.Where("created_at >= ?", fromTime)
+	}
+	if to := c.Query("to"); to != "" {
+		toTime, err := time.Parse(time.RFC3339, to)
+		if err != nil {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Invalid to time, expected RFC 3339", err.Error())
+			return
+		}
+		query = query.Where("created_at < ?", toTime)
+	}
+
+	page, limit := utils.ParsePagination(c.Query("page"), c.Query("limit"), defaultHistoryPageSize, maxHistoryPageSize)
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Error counting audit logs", err.Error())
+		return
+	}
+
+	var logs []models.AuditLog
+	if err := query.Order("created_at DESC, audit_log_id DESC").
+		Offset((page - 1) * limit).
+		Limit(limit).
+		Find(&logs).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Error retrieving audit logs", err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Audit logs retrieved successfully", gin.H{
+		"logs":  logs,
+		"page":  page,
+		"limit": limit,
+		"total": total,
+	})
+}
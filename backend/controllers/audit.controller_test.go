@@ -0,0 +1,27 @@
+package controllers
+
+import (
+	"ai-task-manager/database/dbtest"
+	"net/http"
+	"testing"
+
+	"github.com/gofrs/uuid"
+)
+
+func TestTaskHistoryIsHiddenFromStrangers(t *testing.T) {
+	owner, stranger := uuid.Must(uuid.NewV4()), uuid.Must(uuid.NewV4())
+	task := testTask(owner, uuid.Nil)
+	db, fake := dbtest.New(t)
+	onTask(fake, task)
+
+	recorder := serveAs(stranger, "/task-history/:taskID", http.MethodGet, "/task-history/"+task.TaskID.String(), "",
+		NewAuditController(db).GetTaskHistory)
+	expectStatus(t, recorder, http.StatusNotFound)
+	if read := fake.Statements(`task_events`); len(read) > 0 {
+		t.Errorf("the history was read: %s", read[0].SQL)
+	}
+
+	recorder = serveAs(owner, "/task-history/:taskID", http.MethodGet, "/task-history/"+task.TaskID.String(), "",
+		NewAuditController(db).GetTaskHistory)
+	expectStatus(t, recorder, http.StatusOK)
+}
@@ -0,0 +1,327 @@
+package controllers
+
+import (
+	"ai-task-manager/config"
+	"ai-task-manager/embeddings"
+	"ai-task-manager/events"
+	"ai-task-manager/models"
+	"ai-task-manager/utils"
+	"ai-task-manager/validations"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gofrs/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	BulkModeAtomic     = "atomic"
+	BulkModeBestEffort = "best_effort"
+
+	BulkOpCreate       = "create"
+	BulkOpUpdate       = "update"
+	BulkOpChangeStatus = "change_status"
+	BulkOpDelete       = "delete"
+	BulkOpMoveProject  = "move_project"
+	BulkOpAddLabel     = "add_label"
+
+	BulkStatusOK         = "ok"
+	BulkStatusError      = "error"
+	BulkStatusRolledBack = "rolled_back"
+	BulkStatusSkipped    = "skipped"
+)
+
+var errBulkForbidden = errors.New("you are not allowed to modify this task")
+
+type bulkTaskFields struct {
+	Title       *string `json:"title"`
+	Description *string `json:"description"`
+	Status      *string `json:"status"`
+	AssignedTo  *string `json:"assignedTo"`
+}
+
+type bulkOperation struct {
+	Op        string          `json:"op"`
+	TaskID    string          `json:"taskID"`
+	Fields    *bulkTaskFields `json:"fields"`
+	Status    string          `json:"status"`
+	ProjectID *string         `json:"projectID"`
+	Label     string          `json:"label"`
+	// Version, when set, must match the task's current version, like an
+	// If-Match header on the single-task endpoints.
+	Version *int64 `json:"version"`
+}
+
+type bulkRequest struct {
+	Mode       string          `json:"mode"`
+	Operations []bulkOperation `json:"operations"`
+}
+
+type bulkResult struct {
+	Index  int          `json:"index"`
+	Op     string       `json:"op"`
+	TaskID string       `json:"taskID,omitempty"`
+	Status string       `json:"status"`
+	Error  string       `json:"error,omitempty"`
+	Task   *models.Task `json:"task,omitempty"`
+
+	// before is the task as it was, for the tasks.bulk events published
+	// once the batch has committed. It is nil for a created task.
+	before *models.Task
+}
+
+// BulkTasks runs a batch of task operations in one transaction. In atomic
+// mode the first failure rolls everything back; in best_effort mode each
+// operation runs in its own savepoint and failures are reported per item.
+// Once the batch has committed, every affected user gets one tasks.bulk event
+// listing the successful changes to their tasks.
+func (t *taskController) BulkTasks(c *gin.Context) {
+	actorID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	var request bulkRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid input", err.Error())
+		return
+	}
+	if request.Mode == "" {
+		request.Mode = BulkModeAtomic
+	}
+
+	var fieldErrors []validations.FieldError
+	if request.Mode != BulkModeAtomic && request.Mode != BulkModeBestEffort {
+		fieldErrors = append(fieldErrors, validations.FieldError{Field: "mode", Message: "must be 'atomic' or 'best_effort'"})
+	}
+	maxOperations := config.GetConfig().BulkMaxOperations
+	if len(request.Operations) == 0 || len(request.Operations) > maxOperations {
+		fieldErrors = append(fieldErrors, validations.FieldError{Field: "operations", Message: fmt.Sprintf("must contain between 1 and %d operations", maxOperations)})
+	}
+	if len(fieldErrors) > 0 {
+		utils.FieldErrorsResponse(c, http.StatusBadRequest, "Invalid bulk request", fieldErrors)
+		return
+	}
+
+	results := make([]bulkResult, len(request.Operations))
+	for i, operation := range request.Operations {
+		results[i] = bulkResult{Index: i, Op: operation.Op, TaskID: operation.TaskID, Status: BulkStatusSkipped}
+	}
+
+	err := t.db.Transaction(func(tx *gorm.DB) error {
+		for i, operation := range request.Operations {
+			var before, task *models.Task
+			run := func(tx *gorm.DB) error {
+				var err error
+				before, task, err = t.applyBulkOperation(tx, actorID, operation)
+				return err
+			}
+
+			var err error
+			if request.Mode == BulkModeBestEffort {
+				err = tx.Transaction(run)
+			} else {
+				err = run(tx)
+			}
+
+			if err != nil {
+				results[i].Status = BulkStatusError
+				results[i].Error = err.Error()
+				if request.Mode == BulkModeAtomic {
+					for j := 0; j < i; j++ {
+						results[j].Status = BulkStatusRolledBack
+						results[j].Task = nil
+						results[j].before = nil
+					}
+					return err
+				}
+				continue
+			}
+
+			results[i].Status = BulkStatusOK
+			results[i].TaskID = task.TaskID.String()
+			results[i].Task = task
+			results[i].before = before
+		}
+		return nil
+	})
+	if err != nil && request.Mode == BulkModeAtomic {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"success": false,
+			"message": "Bulk operation failed and was rolled back",
+			"error":   err.Error(),
+			"data": gin.H{
+				"mode":    request.Mode,
+				"results": results,
+			},
+		})
+		return
+	}
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Error running bulk operation", err.Error())
+		return
+	}
+
+	var changes []events.BulkChange
+	for i, result := range results {
+		if result.Status != BulkStatusOK {
+			continue
+		}
+		changes = append(changes, events.BulkChange{Index: result.Index, Op: result.Op, Before: result.before, After: result.Task})
+		op := request.Operations[i].Op
+		if embeddings.Default != nil && (op == BulkOpCreate || op == BulkOpUpdate) {
+			embeddings.Default.Enqueue(result.Task.TaskID)
+		}
+	}
+	events.Publish(events.NewTasksBulk(actorID, changes)...)
+
+	utils.SuccessResponse(c, http.StatusOK, fmt.Sprintf("%d of %d operations succeeded", len(changes), len(results)), gin.H{
+		"mode":    request.Mode,
+		"results": results,
+	})
+}
+
+func (t *taskController) applyBulkOperation(tx *gorm.DB, actorID uuid.UUID, operation bulkOperation) (*models.Task, *models.Task, error) {
+	if operation.Op == BulkOpCreate {
+		task, err := bulkCreateTask(tx, actorID, operation.Fields)
+		return nil, task, err
+	}
+
+	uuidTaskID, err := utils.IsUUID(operation.TaskID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid taskID: %w", err)
+	}
+	var task models.Task
+	if err := tx.Preload("Labels").First(&task, "task_id = ?", uuidTaskID).Error; err != nil {
+		return nil, nil, fmt.Errorf("task not found: %w", err)
+	}
+	if task.UserID != actorID && task.AssignedTo != actorID {
+		return nil, nil, errBulkForbidden
+	}
+	if operation.Version != nil && *operation.Version != task.Version {
+		return nil, nil, models.ErrVersionConflict
+	}
+	before := task
+
+	switch operation.Op {
+	case BulkOpUpdate:
+		if operation.Fields == nil {
+			return nil, nil, errors.New("fields are required for update")
+		}
+		if err := applyBulkFields(&task, operation.Fields); err != nil {
+			return nil, nil, err
+		}
+	case BulkOpChangeStatus:
+		if err := validations.ValidateTaskStatus(operation.Status); err != nil {
+			return nil, nil, err
+		}
+		task.Status = operation.Status
+	case BulkOpDelete:
+		if task.UserID != actorID {
+			return nil, nil, errBulkForbidden
+		}
+		if err := models.DeleteTaskVersioned(tx, &task); err != nil {
+			return nil, nil, err
+		}
+		err := models.RecordTaskEvents(tx, models.TaskEvent{
+			TaskID:  task.TaskID,
+			ActorID: actorID,
+			Action:  models.TaskActionDelete,
+		})
+		return &before, &task, err
+	case BulkOpMoveProject:
+		task.ProjectID = nil
+		if operation.ProjectID != nil && *operation.ProjectID != "" {
+			projectID, err := utils.IsUUID(*operation.ProjectID)
+			if err != nil {
+				return nil, nil, fmt.Errorf("invalid projectID: %w", err)
+			}
+			if err := ensureProjectOwner(tx, task.UserID, &projectID); err != nil {
+				return nil, nil, err
+			}
+			task.ProjectID = &projectID
+		}
+	case BulkOpAddLabel:
+		labels, err := models.FindOrCreateLabels(tx, task.UserID, []string{operation.Label})
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(labels) == 0 {
+			return nil, nil, errors.New("label is required for add_label")
+		}
+		for _, existing := range task.Labels {
+			if existing.LabelID == labels[0].LabelID {
+				return &before, &task, nil
+			}
+		}
+		previous := models.LabelNames(task.Labels)
+		if err := tx.Model(&task).Association("Labels").Append(labels); err != nil {
+			return nil, nil, err
+		}
+		if err := models.UpdateTaskVersioned(tx, &task, models.Task{}); err != nil {
+			return nil, nil, err
+		}
+		if err := models.RecordTaskEvents(tx, models.TaskEvent{
+			TaskID:   task.TaskID,
+			ActorID:  actorID,
+			Action:   models.TaskActionUpdate,
+			Field:    "labels",
+			OldValue: previous,
+			NewValue: models.LabelNames(task.Labels),
+		}); err != nil {
+			return nil, nil, err
+		}
+		return &before, &task, nil
+	default:
+		return nil, nil, fmt.Errorf("unknown operation %q", operation.Op)
+	}
+
+	if err := models.SaveTaskVersioned(tx, &task); err != nil {
+		return nil, nil, err
+	}
+	err = models.RecordTaskEvents(tx, models.DiffTask(actorID, &before, &task)...)
+	return &before, &task, err
+}
+
+func bulkCreateTask(tx *gorm.DB, actorID uuid.UUID, fields *bulkTaskFields) (*models.Task, error) {
+	if fields == nil {
+		return nil, errors.New("fields are required for create")
+	}
+	task := models.Task{UserID: actorID, Status: "pending"}
+	if err := applyBulkFields(&task, fields); err != nil {
+		return nil, err
+	}
+	if err := tx.Omit("Labels").Create(&task).Error; err != nil {
+		return nil, err
+	}
+	return &task, models.RecordTaskEvents(tx, models.TaskEvent{
+		TaskID:  task.TaskID,
+		ActorID: actorID,
+		Action:  models.TaskActionCreate,
+	})
+}
+
+func applyBulkFields(task *models.Task, fields *bulkTaskFields) error {
+	if fields.Title != nil {
+		task.Title = *fields.Title
+	}
+	if fields.Description != nil {
+		task.Description = *fields.Description
+	}
+	if fields.Status != nil {
+		task.Status = *fields.Status
+	}
+	if fields.AssignedTo != nil {
+		task.AssignedTo = uuid.Nil
+		if *fields.AssignedTo != "" {
+			assignee, err := utils.IsUUID(*fields.AssignedTo)
+			if err != nil {
+				return fmt.Errorf("invalid assignedTo: %w", err)
+			}
+			task.AssignedTo = assignee
+		}
+	}
+	return nil
+}
@@ -0,0 +1,783 @@
+package controllers
+
+import (
+	"ai-task-manager/events"
+	"ai-task-manager/models"
+	"ai-task-manager/taskio"
+	"ai-task-manager/utils"
+	"ai-task-manager/webdav"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"path"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gofrs/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	// CalDAVPrefix is where the CalDAV tree is mounted.
+	CalDAVPrefix = "/caldav"
+	// caldavInbox is the calendar holding tasks that have no project.
+	caldavInbox = "inbox"
+	// maxCalendarObjectSize bounds a PUT body.
+	maxCalendarObjectSize = 1 << 20
+
+	calendarObjectContentType = "text/calendar; charset=utf-8; component=VTODO"
+)
+
+var caldavMethods = "OPTIONS, GET, HEAD, PUT, DELETE, PROPFIND, REPORT"
+
+type CalDAVController interface {
+	Options(c *gin.Context)
+	Propfind(c *gin.Context)
+	Report(c *gin.Context)
+	GetObject(c *gin.Context)
+	PutObject(c *gin.Context)
+	DeleteObject(c *gin.Context)
+}
+
+type caldavController struct {
+	db *gorm.DB
+}
+
+// NewCalDAVController serves each of the user's projects, plus an inbox for
+// tasks without a project, as a CalDAV calendar of VTODOs (RFC 4791). The
+// tree is:
+//
+//	/caldav/principals/<userID>/
+//	/caldav/calendars/<userID>/
+//	/caldav/calendars/<userID>/<projectID|inbox>/
+//	/caldav/calendars/<userID>/<projectID|inbox>/<name>.ics
+//
+// Clients sync with getctag and per-task ETags, the same ETags as the REST
+// API. Writes go through the same task write paths as the REST handlers.
+func NewCalDAVController(db *gorm.DB) CalDAVController {
+	return &caldavController{db: db}
+}
+
+type caldavResourceKind int
+
+const (
+	caldavRoot caldavResourceKind = iota
+	caldavPrincipal
+	caldavHome
+	caldavCalendar
+	caldavObject
+)
+
+// caldavPath is a parsed request path.
+type caldavPath struct {
+	kind       caldavResourceKind
+	userID     uuid.UUID
+	collection string
+	projectID  *uuid.UUID
+	name       string
+}
+
+func parseCalDAVPath(raw string) (caldavPath, error) {
+	var p caldavPath
+	segments := strings.FieldsFunc(strings.TrimPrefix(raw, CalDAVPrefix), func(r rune) bool { return r == '/' })
+	if len(segments) == 0 {
+		return p, nil
+	}
+	if len(segments) < 2 || (segments[0] != "principals" && segments[0] != "calendars") {
+		return p, errors.New("unknown resource")
+	}
+	userID, err := uuid.FromString(segments[1])
+	if err != nil {
+		return p, errors.New("unknown user")
+	}
+	p.userID = userID
+
+	switch {
+	case segments[0] == "principals" && len(segments) == 2:
+		p.kind = caldavPrincipal
+	case segments[0] == "calendars" && len(segments) == 2:
+		p.kind = caldavHome
+	case segments[0] == "calendars" && len(segments) <= 4:
+		p.kind = caldavCalendar
+		p.collection = segments[2]
+		if p.collection != caldavInbox {
+			projectID, err := uuid.FromString(p.collection)
+			if err != nil {
+				return p, errors.New("unknown calendar")
+			}
+			p.projectID = &projectID
+		}
+		if len(segments) == 4 {
+			p.kind = caldavObject
+			p.name = segments[3]
+		}
+	default:
+		return p, errors.New("unknown resource")
+	}
+	return p, nil
+}
+
+func principalHref(userID uuid.UUID) string {
+	return CalDAVPrefix + "/principals/" + userID.String() + "/"
+}
+
+func calendarHomeHref(userID uuid.UUID) string {
+	return CalDAVPrefix + "/calendars/" + userID.String() + "/"
+}
+
+func calendarHref(userID uuid.UUID, collection string) string {
+	return calendarHomeHref(userID) + collection + "/"
+}
+
+// resolve parses the request path and checks that it belongs to the caller.
+// It writes the error response and returns false on failure.
+func (d *caldavController) resolve(c *gin.Context) (caldavPath, uuid.UUID, bool) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return caldavPath{}, uuid.Nil, false
+	}
+	p, err := parseCalDAVPath(c.Request.URL.Path)
+	if err != nil {
+		c.String(http.StatusNotFound, err.Error())
+		return p, userID, false
+	}
+	if p.kind != caldavRoot && p.userID != userID {
+		c.String(http.StatusForbidden, "resource belongs to another user")
+		return p, userID, false
+	}
+	return p, userID, true
+}
+
+// calendarCollection is a project, or the inbox when project is nil.
+type calendarCollection struct {
+	name    string
+	project *models.Project
+}
+
+func (cc calendarCollection) displayName() string {
+	if cc.project == nil {
+		return "Tasks"
+	}
+	return cc.project.Name
+}
+
+func (cc calendarCollection) projectID() *uuid.UUID {
+	if cc.project == nil {
+		return nil
+	}
+	return &cc.project.ProjectID
+}
+
+// scope limits a task query to the collection.
+func (cc calendarCollection) scope(tx *gorm.DB, userID uuid.UUID) *gorm.DB {
+	tx = tx.Where("user_id = ?", userID)
+	if cc.project == nil {
+		return tx.Where("project_id IS NULL")
+	}
+	return tx.Where("project_id = ?", cc.project.ProjectID)
+}
+
+func (d *caldavController) collections(userID uuid.UUID) ([]calendarCollection, error) {
+	var projects []models.Project
+	if err := d.db.Where("user_id = ?", userID).Order("created_at").Find(&projects).Error; err != nil {
+		return nil, err
+	}
+	collections := []calendarCollection{{name: caldavInbox}}
+	for i := range projects {
+		collections = append(collections, calendarCollection{name: projects[i].ProjectID.String(), project: &projects[i]})
+	}
+	return collections, nil
+}
+
+func (d *caldavController) collection(p caldavPath) (calendarCollection, error) {
+	if p.projectID == nil {
+		return calendarCollection{name: caldavInbox}, nil
+	}
+	var project models.Project
+	if err := d.db.First(&project, "project_id = ? AND user_id = ?", *p.projectID, p.userID).Error; err != nil {
+		return calendarCollection{}, err
+	}
+	return calendarCollection{name: p.collection, project: &project}, nil
+}
+
+// ctag changes whenever a task in the collection is created, changed,
+// deleted or moved in or out of it.
+func (d *caldavController) ctag(cc calendarCollection, userID uuid.UUID) (string, error) {
+	var stats struct {
+		Live     int64
+		Versions int64
+		Updated  *time.Time
+		Deleted  *time.Time
+	}
+	err := cc.scope(d.db.Unscoped().Model(&models.Task{}), userID).
+		Select("COUNT(*) FILTER (WHERE deleted_at IS NULL) AS live, COALESCE(SUM(version) FILTER (WHERE deleted_at IS NULL), 0) AS versions, MAX(updated_at) AS updated, MAX(deleted_at) AS deleted").
+		Scan(&stats).Error
+	if err != nil {
+		return "", err
+	}
+	stamp := func(t *time.Time) int64 {
+		if t == nil {
+			return 0
+		}
+		return t.UnixNano()
+	}
+	return fmt.Sprintf("%d-%d-%d-%d", stats.Live, stats.Versions, stamp(stats.Updated), stamp(stats.Deleted)), nil
+}
+
+// calendarTask is a task with the href name and UID it is served under.
+type calendarTask struct {
+	task models.Task
+	name string
+	uid  string
+}
+
+func (d *caldavController) calendarTasks(cc calendarCollection, userID uuid.UUID, taskIDs []uuid.UUID) ([]calendarTask, error) {
+	query := cc.scope(d.db.Preload("Labels"), userID)
+	if taskIDs != nil {
+		query = query.Where("task_id IN ?", taskIDs)
+	}
+	var tasks []models.Task
+	if err := query.Order("created_at").Find(&tasks).Error; err != nil {
+		return nil, err
+	}
+	return d.withObjectNames(tasks)
+}
+
+func (d *caldavController) withObjectNames(tasks []models.Task) ([]calendarTask, error) {
+	ids := make([]uuid.UUID, len(tasks))
+	for i, task := range tasks {
+		ids[i] = task.TaskID
+	}
+	objects := make(map[uuid.UUID]models.CalendarObject)
+	if len(ids) > 0 {
+		var rows []models.CalendarObject
+		if err := d.db.Where("task_id IN ?", ids).Find(&rows).Error; err != nil {
+			return nil, err
+		}
+		for _, row := range rows {
+			objects[row.TaskID] = row
+		}
+	}
+
+	result := make([]calendarTask, len(tasks))
+	for i := range tasks {
+		result[i] = calendarTask{task: tasks[i], name: tasks[i].TaskID.String() + ".ics", uid: taskio.CalendarUID(&tasks[i])}
+		if object, ok := objects[tasks[i].TaskID]; ok {
+			result[i].name, result[i].uid = object.Name, object.UID
+		}
+	}
+	return result, nil
+}
+
+// findObject looks up a task by the resource name it is served under.
+func (d *caldavController) findObject(cc calendarCollection, userID uuid.UUID, name string) (*calendarTask, error) {
+	var object models.CalendarObject
+	err := d.db.First(&object, "user_id = ? AND name = ?", userID, name).Error
+	var taskID uuid.UUID
+	switch {
+	case err == nil:
+		taskID = object.TaskID
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		if taskID, err = uuid.FromString(strings.TrimSuffix(name, ".ics")); err != nil {
+			return nil, gorm.ErrRecordNotFound
+		}
+	default:
+		return nil, err
+	}
+
+	tasks, err := d.calendarTasks(cc, userID, []uuid.UUID{taskID})
+	if err != nil {
+		return nil, err
+	}
+	if len(tasks) == 0 || tasks[0].name != name {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &tasks[0], nil
+}
+
+func (ct *calendarTask) data() (string, error) {
+	var b strings.Builder
+	writer := taskio.NewCalendarWriter(&b, taskio.ComponentTodo, "")
+	writer.UID = func(*models.Task) string { return ct.uid }
+	if err := writer.Write(&ct.task); err != nil {
+		return "", err
+	}
+	if err := writer.Close(); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+func (d *caldavController) Options(c *gin.Context) {
+	c.Header("DAV", "1, 3, calendar-access")
+	c.Header("Allow", caldavMethods)
+	c.Status(http.StatusOK)
+}
+
+// Propfind answers PROPFIND with Depth 0 or 1; infinity is treated as 1.
+func (d *caldavController) Propfind(c *gin.Context) {
+	p, userID, ok := d.resolve(c)
+	if !ok {
+		return
+	}
+	request, err := webdav.ParseRequest(c.Request.Body)
+	if err != nil {
+		c.String(http.StatusBadRequest, err.Error())
+		return
+	}
+	children := c.GetHeader("Depth") != "0"
+
+	var responses []webdav.Response
+	add := func(href string, props []webdav.Property) {
+		responses = append(responses, request.Select(href, props, webdav.CalDAV("calendar-data")))
+	}
+
+	switch p.kind {
+	case caldavRoot:
+		add(CalDAVPrefix+"/", d.rootProps(userID))
+	case caldavPrincipal:
+		add(principalHref(userID), d.principalProps(userID))
+	case caldavHome:
+		add(calendarHomeHref(userID), d.homeProps(userID))
+		if children {
+			collections, err := d.collections(userID)
+			if err != nil {
+				c.String(http.StatusInternalServerError, err.Error())
+				return
+			}
+			for _, cc := range collections {
+				props, err := d.calendarProps(cc, userID)
+				if err != nil {
+					c.String(http.StatusInternalServerError, err.Error())
+					return
+				}
+				add(calendarHref(userID, cc.name), props)
+			}
+		}
+	case caldavCalendar:
+		cc, ok := d.loadCollection(c, p)
+		if !ok {
+			return
+		}
+		props, err := d.calendarProps(cc, userID)
+		if err != nil {
+			c.String(http.StatusInternalServerError, err.Error())
+			return
+		}
+		add(calendarHref(userID, cc.name), props)
+		if children {
+			tasks, err := d.calendarTasks(cc, userID, nil)
+			if err != nil {
+				c.String(http.StatusInternalServerError, err.Error())
+				return
+			}
+			for i := range tasks {
+				add(calendarHref(userID, cc.name)+tasks[i].name, objectProps(&tasks[i], request))
+			}
+		}
+	case caldavObject:
+		cc, ok := d.loadCollection(c, p)
+		if !ok {
+			return
+		}
+		task, ok := d.loadObject(c, cc, userID, p.name)
+		if !ok {
+			return
+		}
+		add(calendarHref(userID, cc.name)+task.name, objectProps(task, request))
+	}
+
+	if err := webdav.WriteMultistatus(c.Writer, responses); err != nil {
+		log.Printf("Error writing CalDAV response: %v", err)
+	}
+}
+
+// Report answers calendar-query and calendar-multiget on a calendar.
+// calendar-query returns every to-do in the calendar; time ranges and
+// property filters are left to the client.
+func (d *caldavController) Report(c *gin.Context) {
+	p, userID, ok := d.resolve(c)
+	if !ok {
+		return
+	}
+	if p.kind != caldavCalendar && p.kind != caldavObject {
+		webdav.WriteError(c.Writer, http.StatusForbidden, webdav.DAV("supported-report"))
+		return
+	}
+	request, err := webdav.ParseRequest(c.Request.Body)
+	if err != nil {
+		c.String(http.StatusBadRequest, err.Error())
+		return
+	}
+	cc, ok := d.loadCollection(c, p)
+	if !ok {
+		return
+	}
+	base := calendarHref(userID, cc.name)
+
+	var responses []webdav.Response
+	switch request.Root {
+	case webdav.CalDAV("calendar-query"):
+		// A filter on any other component, such as VEVENT, matches nothing.
+		if len(request.CompFilters) > 1 && !slices.Contains(request.CompFilters[1:], taskio.ComponentTodo) {
+			break
+		}
+		tasks, err := d.calendarTasks(cc, userID, nil)
+		if err != nil {
+			c.String(http.StatusInternalServerError, err.Error())
+			return
+		}
+		for i := range tasks {
+			responses = append(responses, request.Select(base+tasks[i].name, objectProps(&tasks[i], request)))
+		}
+	case webdav.CalDAV("calendar-multiget"):
+		for _, href := range request.Hrefs {
+			name, ok := objectNameFromHref(href, base)
+			if !ok {
+				responses = append(responses, webdav.Response{Href: href, Status: http.StatusNotFound})
+				continue
+			}
+			task, err := d.findObject(cc, userID, name)
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				responses = append(responses, webdav.Response{Href: href, Status: http.StatusNotFound})
+				continue
+			}
+			if err != nil {
+				c.String(http.StatusInternalServerError, err.Error())
+				return
+			}
+			responses = append(responses, request.Select(base+task.name, objectProps(task, request)))
+		}
+	default:
+		webdav.WriteError(c.Writer, http.StatusForbidden, webdav.DAV("supported-report"))
+		return
+	}
+
+	if err := webdav.WriteMultistatus(c.Writer, responses); err != nil {
+		log.Printf("Error writing CalDAV response: %v", err)
+	}
+}
+
+// objectNameFromHref returns the resource name of an href, which may be a
+// full URL, if it lies directly in the calendar at base.
+func objectNameFromHref(href, base string) (string, bool) {
+	parsed, err := url.Parse(href)
+	if err != nil {
+		return "", false
+	}
+	dir, name := path.Split(parsed.Path)
+	if dir != base || name == "" {
+		return "", false
+	}
+	return name, true
+}
+
+func (d *caldavController) GetObject(c *gin.Context) {
+	p, userID, ok := d.resolve(c)
+	if !ok {
+		return
+	}
+	if p.kind != caldavObject {
+		c.Header("Allow", "OPTIONS, PROPFIND, REPORT")
+		c.String(http.StatusMethodNotAllowed, "collections cannot be downloaded")
+		return
+	}
+	cc, ok := d.loadCollection(c, p)
+	if !ok {
+		return
+	}
+	task, ok := d.loadObject(c, cc, userID, p.name)
+	if !ok {
+		return
+	}
+
+	etag := taskETag(task.task)
+	c.Header("ETag", etag)
+	if utils.ETagMatches(c.GetHeader("If-None-Match"), etag, true) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+	data, err := task.data()
+	if err != nil {
+		c.String(http.StatusInternalServerError, err.Error())
+		return
+	}
+	c.Data(http.StatusOK, calendarObjectContentType, []byte(data))
+}
+
+// PutObject creates or replaces a to-do. If-Match and If-None-Match: * are
+// honoured so clients don't overwrite changes made elsewhere.
+func (d *caldavController) PutObject(c *gin.Context) {
+	p, userID, ok := d.resolve(c)
+	if !ok {
+		return
+	}
+	if p.kind != caldavObject {
+		c.String(http.StatusMethodNotAllowed, "only calendar objects can be written")
+		return
+	}
+	cc, ok := d.loadCollection(c, p)
+	if !ok {
+		return
+	}
+
+	record, err := taskio.ReadCalendarObject(http.MaxBytesReader(c.Writer, c.Request.Body, maxCalendarObjectSize))
+	if err != nil {
+		webdav.WriteError(c.Writer, http.StatusForbidden, webdav.CalDAV("valid-calendar-data"))
+		return
+	}
+	if err := validateImportRecord(record); err != nil {
+		c.String(http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+
+	existing, err := d.findObject(cc, userID, p.name)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		c.String(http.StatusInternalServerError, err.Error())
+		return
+	}
+	ifMatch := c.GetHeader("If-Match")
+	if existing == nil {
+		if ifMatch != "" {
+			c.String(http.StatusPreconditionFailed, "resource does not exist")
+			return
+		}
+		d.createObject(c, cc, userID, p.name, record)
+		return
+	}
+	if c.GetHeader("If-None-Match") == "*" || (ifMatch != "" && !utils.ETagMatches(ifMatch, taskETag(existing.task), false)) {
+		c.Header("ETag", taskETag(existing.task))
+		c.String(http.StatusPreconditionFailed, "resource was changed")
+		return
+	}
+	d.updateObject(c, userID, existing, record)
+}
+
+func (d *caldavController) createObject(c *gin.Context, cc calendarCollection, userID uuid.UUID, name string, record *taskio.Record) {
+	if record.SourceID != "" {
+		var conflicts int64
+		if err := d.db.Model(&models.CalendarObject{}).Where("user_id = ? AND uid = ?", userID, record.SourceID).Count(&conflicts).Error; err != nil {
+			c.String(http.StatusInternalServerError, err.Error())
+			return
+		}
+		if conflicts > 0 {
+			webdav.WriteError(c.Writer, http.StatusForbidden, webdav.CalDAV("no-uid-conflict"))
+			return
+		}
+	}
+
+	task := models.Task{
+		UserID:      userID,
+		ProjectID:   cc.projectID(),
+		Title:       record.Title,
+		Description: record.Description,
+		Status:      record.Status,
+		Priority:    record.Priority,
+		DueDate:     record.DueDate,
+	}
+	err := d.db.Transaction(func(tx *gorm.DB) error {
+		if err := createTaskTx(tx, userID, &task); err != nil {
+			return err
+		}
+		if len(record.Labels) > 0 {
+			labels, err := models.FindOrCreateLabels(tx, userID, record.Labels)
+			if err != nil {
+				return err
+			}
+			if err := tx.Model(&task).Association("Labels").Append(labels); err != nil {
+				return err
+			}
+		}
+		object := models.CalendarObject{TaskID: task.TaskID, UserID: userID, Name: name, UID: record.SourceID}
+		if object.UID == "" {
+			object.UID = taskio.CalendarUID(&task)
+		}
+		return tx.Create(&object).Error
+	})
+	if err != nil {
+		c.String(http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	events.Publish(events.NewTaskCreated(userID, &task))
+	reindexTask(nil, &task)
+	c.Header("ETag", taskETag(task))
+	c.Status(http.StatusCreated)
+}
+
+func (d *caldavController) updateObject(c *gin.Context, userID uuid.UUID, existing *calendarTask, record *taskio.Record) {
+	before := existing.task
+	task := existing.task
+	task.Title = record.Title
+	task.Description = record.Description
+	task.Status = record.Status
+	task.Priority = record.Priority
+	task.DueDate = record.DueDate
+
+	err := d.db.Transaction(func(tx *gorm.DB) error {
+		if len(models.DiffTask(userID, &before, &task)) > 0 {
+			if err := saveTaskTx(tx, userID, &before, &task); err != nil {
+				return err
+			}
+		}
+		return replaceTaskLabelsTx(tx, userID, &task, record.Labels)
+	})
+	if errors.Is(err, models.ErrVersionConflict) {
+		c.String(http.StatusPreconditionFailed, err.Error())
+		return
+	}
+	if err != nil {
+		c.String(http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	events.Publish(events.NewTaskChanged(userID, &before, &task)...)
+	reindexTask(&before, &task)
+	c.Header("ETag", taskETag(task))
+	c.Status(http.StatusNoContent)
+}
+
+func (d *caldavController) DeleteObject(c *gin.Context) {
+	p, userID, ok := d.resolve(c)
+	if !ok {
+		return
+	}
+	if p.kind != caldavObject {
+		c.String(http.StatusForbidden, "calendars are deleted by deleting their project")
+		return
+	}
+	cc, ok := d.loadCollection(c, p)
+	if !ok {
+		return
+	}
+	task, ok := d.loadObject(c, cc, userID, p.name)
+	if !ok {
+		return
+	}
+	if ifMatch := c.GetHeader("If-Match"); ifMatch != "" && !utils.ETagMatches(ifMatch, taskETag(task.task), false) {
+		c.String(http.StatusPreconditionFailed, "resource was changed")
+		return
+	}
+
+	err := d.db.Transaction(func(tx *gorm.DB) error {
+		if err := deleteTaskTx(tx, userID, &task.task); err != nil {
+			return err
+		}
+		// The name may be reused by the client for a new to-do; restoring
+		// the task from the trash serves it under its task ID instead.
+		return tx.Where("task_id = ?", task.task.TaskID).Delete(&models.CalendarObject{}).Error
+	})
+	if errors.Is(err, models.ErrVersionConflict) {
+		c.String(http.StatusPreconditionFailed, err.Error())
+		return
+	}
+	if err != nil {
+		c.String(http.StatusInternalServerError, err.Error())
+		return
+	}
+	events.Publish(events.NewTaskDeleted(userID, &task.task))
+	c.Status(http.StatusNoContent)
+}
+
+func (d *caldavController) loadCollection(c *gin.Context, p caldavPath) (calendarCollection, bool) {
+	cc, err := d.collection(p)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.String(http.StatusNotFound, "calendar not found")
+		return cc, false
+	}
+	if err != nil {
+		c.String(http.StatusInternalServerError, err.Error())
+		return cc, false
+	}
+	return cc, true
+}
+
+func (d *caldavController) loadObject(c *gin.Context, cc calendarCollection, userID uuid.UUID, name string) (*calendarTask, bool) {
+	task, err := d.findObject(cc, userID, name)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.String(http.StatusNotFound, "calendar object not found")
+		return nil, false
+	}
+	if err != nil {
+		c.String(http.StatusInternalServerError, err.Error())
+		return nil, false
+	}
+	return task, true
+}
+
+func (d *caldavController) rootProps(userID uuid.UUID) []webdav.Property {
+	return []webdav.Property{
+		{Name: webdav.DAV("resourcetype"), Value: "<d:collection/>"},
+		{Name: webdav.DAV("current-user-principal"), Value: webdav.Href(principalHref(userID))},
+	}
+}
+
+func (d *caldavController) principalProps(userID uuid.UUID) []webdav.Property {
+	var user models.User
+	displayName := userID.String()
+	if err := d.db.Select("username").First(&user, "user_id = ?", userID).Error; err == nil && user.Username != "" {
+		displayName = user.Username
+	}
+	return []webdav.Property{
+		{Name: webdav.DAV("resourcetype"), Value: "<d:collection/><d:principal/>"},
+		{Name: webdav.DAV("displayname"), Value: webdav.Text(displayName)},
+		{Name: webdav.DAV("current-user-principal"), Value: webdav.Href(principalHref(userID))},
+		{Name: webdav.DAV("principal-URL"), Value: webdav.Href(principalHref(userID))},
+		{Name: webdav.CalDAV("calendar-home-set"), Value: webdav.Href(calendarHomeHref(userID))},
+	}
+}
+
+func (d *caldavController) homeProps(userID uuid.UUID) []webdav.Property {
+	return []webdav.Property{
+		{Name: webdav.DAV("resourcetype"), Value: "<d:collection/>"},
+		{Name: webdav.DAV("displayname"), Value: "Calendars"},
+		{Name: webdav.DAV("current-user-principal"), Value: webdav.Href(principalHref(userID))},
+	}
+}
+
+func (d *caldavController) calendarProps(cc calendarCollection, userID uuid.UUID) ([]webdav.Property, error) {
+	ctag, err := d.ctag(cc, userID)
+	if err != nil {
+		return nil, err
+	}
+	props := []webdav.Property{
+		{Name: webdav.DAV("resourcetype"), Value: "<d:collection/><c:calendar/>"},
+		{Name: webdav.DAV("displayname"), Value: webdav.Text(cc.displayName())},
+		{Name: webdav.DAV("current-user-principal"), Value: webdav.Href(principalHref(userID))},
+		{Name: webdav.DAV("owner"), Value: webdav.Href(principalHref(userID))},
+		{Name: webdav.DAV("current-user-privilege-set"), Value: "<d:privilege><d:read/></d:privilege><d:privilege><d:write/></d:privilege>" +
+			"<d:privilege><d:write-content/></d:privilege><d:privilege><d:bind/></d:privilege><d:privilege><d:unbind/></d:privilege>"},
+		{Name: webdav.DAV("supported-report-set"), Value: "<d:supported-report><d:report><c:calendar-query/></d:report></d:supported-report>" +
+			"<d:supported-report><d:report><c:calendar-multiget/></d:report></d:supported-report>"},
+		{Name: webdav.CalDAV("supported-calendar-component-set"), Value: `<c:comp name="VTODO"/>`},
+		{Name: webdav.CS("getctag"), Value: webdav.Text(ctag)},
+	}
+	if cc.project != nil && cc.project.Description != "" {
+		props = append(props, webdav.Property{Name: webdav.CalDAV("calendar-description"), Value: webdav.Text(cc.project.Description)})
+	}
+	return props, nil
+}
+
+// objectProps lists the properties of a to-do. calendar-data is only
+// rendered when the request asks for it.
+func objectProps(task *calendarTask, request *webdav.Request) []webdav.Property {
+	props := []webdav.Property{
+		{Name: webdav.DAV("resourcetype")},
+		{Name: webdav.DAV("getetag"), Value: webdav.Text(taskETag(task.task))},
+		{Name: webdav.DAV("getcontenttype"), Value: calendarObjectContentType},
+		{Name: webdav.DAV("getlastmodified"), Value: task.task.UpdatedAt.UTC().Format(http.TimeFormat)},
+	}
+	if slices.Contains(request.PropNames, webdav.CalDAV("calendar-data")) {
+		data, err := task.data()
+		if err != nil {
+			log.Printf("Error rendering task %s as iCalendar: %v", task.task.TaskID, err)
+		} else {
+			props = append(props, webdav.Property{Name: webdav.CalDAV("calendar-data"), Value: webdav.Text(data)})
+		}
+	}
+	return props
+}
@@ -0,0 +1,219 @@
+package controllers
+
+import (
+	"ai-task-manager/models"
+	"ai-task-manager/repository"
+	"ai-task-manager/taskio"
+	"ai-task-manager/utils"
+	"ai-task-manager/validations"
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gofrs/uuid"
+	"gorm.io/gorm"
+)
+
+// calendarFeedParams are the query parameters a feed URL may carry; they are
+// the task listing filters plus the component to emit.
+var calendarFeedParams = []string{"project", "labels", "status", "assignee", "q", "dueAfter", "dueBefore", "component"}
+
+type CalendarController interface {
+	CreateFeedToken(c *gin.Context)
+	GetFeedToken(c *gin.Context)
+	RevokeFeedToken(c *gin.Context)
+	GetFeed(c *gin.Context)
+}
+
+type calendarController struct {
+	db    *gorm.DB
+	tasks repository.TaskRepository
+}
+
+func NewCalendarController(db *gorm.DB) CalendarController {
+	return &calendarController{
+		db:    db,
+		tasks: repository.NewTaskRepository(db),
+	}
+}
+
+type feedTokenResponse struct {
+	models.CalendarToken
+	Token string `json:"token"`
+	URL   string `json:"url"`
+}
+
+// CreateFeedToken issues the caller's secret calendar feed URL, replacing
+// and thereby revoking any previous one. Filters given as query parameters
+// (project, labels, status, assignee, q, dueAfter, dueBefore and
+// component=vtodo|vevent) are validated and carried into the URL.
+func (cc *calendarController) CreateFeedToken(c *gin.Context) {
+	uuidUserID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	filters := url.Values{}
+	for _, name := range calendarFeedParams {
+		if value := c.Query(name); value != "" {
+			filters.Set(name, value)
+		}
+	}
+	if _, fieldErrors := parseFeedQuery(filters, uuidUserID); len(fieldErrors) > 0 {
+		utils.FieldErrorsResponse(c, http.StatusBadRequest, "Invalid query parameters", fieldErrors)
+		return
+	}
+
+	token, err := utils.GenerateToken()
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Error creating feed token", err.Error())
+		return
+	}
+	record := models.CalendarToken{UserID: uuidUserID, TokenHash: utils.HashToken(token)}
+	err = cc.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", uuidUserID).Delete(&models.CalendarToken{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(&record).Error
+	})
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Error creating feed token", err.Error())
+		return
+	}
+
+	feedURL := requestBaseURL(c) + "/api/v1/calendar/feed/" + token + ".ics"
+	if len(filters) > 0 {
+		feedURL += "?" + filters.Encode()
+	}
+	utils.SuccessResponse(c, http.StatusCreated, "Calendar feed token created successfully", feedTokenResponse{
+		CalendarToken: record,
+		Token:         token,
+		URL:           feedURL,
+	})
+}
+
+// GetFeedToken reports whether the caller has a feed token. The token itself
+// cannot be shown again; create a new one to get a fresh URL.
+func (cc *calendarController) GetFeedToken(c *gin.Context) {
+	uuidUserID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	var record models.CalendarToken
+	if err := cc.db.First(&record, "user_id = ?", uuidUserID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.ErrorResponse(c, http.StatusNotFound, "Calendar feed token not found", err.Error())
+			return
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Error fetching feed token", err.Error())
+		return
+	}
+	utils.SuccessResponse(c, http.StatusOK, "Calendar feed token fetched successfully", record)
+}
+
+// RevokeFeedToken disables the caller's feed URL.
+func (cc *calendarController) RevokeFeedToken(c *gin.Context) {
+	uuidUserID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	result := cc.db.Where("user_id = ?", uuidUserID).Delete(&models.CalendarToken{})
+	if result.Error != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Error revoking feed token", result.Error.Error())
+		return
+	}
+	if result.RowsAffected == 0 {
+		utils.ErrorResponse(c, http.StatusNotFound, "Calendar feed token not found", "no feed token to revoke")
+		return
+	}
+	utils.SuccessResponse(c, http.StatusOK, "Calendar feed token revoked successfully", nil)
+}
+
+// GetFeed serves the iCalendar feed of the token owner's tasks that have a
+// due date. The token in the path replaces the JWT, so calendar apps can
+// subscribe to the URL.
+func (cc *calendarController) GetFeed(c *gin.Context) {
+	token := strings.TrimSuffix(c.Param("token"), ".ics")
+
+	var record models.CalendarToken
+	if err := cc.db.First(&record, "token_hash = ?", utils.HashToken(token)).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.ErrorResponse(c, http.StatusNotFound, "Calendar feed not found", "the feed URL is invalid or was revoked")
+			return
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Error fetching calendar feed", err.Error())
+		return
+	}
+
+	feed, fieldErrors := parseFeedQuery(c.Request.URL.Query(), record.UserID)
+	if len(fieldErrors) > 0 {
+		utils.FieldErrorsResponse(c, http.StatusBadRequest, "Invalid query parameters", fieldErrors)
+		return
+	}
+
+	now := time.Now()
+	if err := cc.db.Model(&record).Update("last_used_at", now).Error; err != nil {
+		log.Printf("Error recording calendar feed use: %v", err)
+	}
+
+	c.Header("Content-Type", taskio.ContentType(taskio.FormatICS))
+	c.Header("Cache-Control", "private, no-cache")
+	c.Status(http.StatusOK)
+
+	writer := taskio.NewCalendarWriter(c.Writer, feed.component, "Tasks")
+	err := repository.EachTask(c.Request.Context(), cc.tasks, feed.query, writer.Write)
+	if err == nil {
+		err = writer.Close()
+	}
+	if err != nil {
+		log.Printf("Error writing calendar feed for user %s: %v", record.UserID, err)
+	}
+}
+
+type feedQuery struct {
+	query     validations.TaskQuery
+	component string
+}
+
+// parseFeedQuery reads the feed filters. Feeds only ever list the owner's
+// tasks with a due date.
+func parseFeedQuery(values url.Values, userID uuid.UUID) (feedQuery, []validations.FieldError) {
+	filters := url.Values{}
+	for _, name := range calendarFeedParams {
+		if value := values.Get(name); value != "" && name != "component" {
+			filters.Set(name, value)
+		}
+	}
+	query, fieldErrors := validations.ParseTaskQuery(filters, userID)
+	query.OwnerID = &userID
+	query.HasDueDate = true
+
+	feed := feedQuery{query: query, component: taskio.ComponentTodo}
+	switch strings.ToLower(values.Get("component")) {
+	case "", "vtodo":
+	case "vevent":
+		feed.component = taskio.ComponentEvent
+	default:
+		fieldErrors = append(fieldErrors, validations.FieldError{Field: "component", Message: "must be vtodo or vevent"})
+	}
+	return feed, fieldErrors
+}
+
+// requestBaseURL rebuilds the scheme and host the client used, honouring a
+// reverse proxy's X-Forwarded-Proto.
+func requestBaseURL(c *gin.Context) string {
+	scheme := "http"
+	if c.Request.TLS != nil {
+		scheme = "https"
+	}
+	if proto := c.GetHeader("X-Forwarded-Proto"); proto == "http" || proto == "https" {
+		scheme = proto
+	}
+	return scheme + "://" + c.Request.Host
+}
@@ -0,0 +1,277 @@
+package controllers
+
+import (
+	"ai-task-manager/events"
+	"ai-task-manager/models"
+	"ai-task-manager/utils"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultCommentPageSize = 20
+	maxCommentPageSize     = 100
+)
+
+type CommentController interface {
+	AddComment(c *gin.Context)
+	GetComments(c *gin.Context)
+	UpdateComment(c *gin.Context)
+	DeleteComment(c *gin.Context)
+}
+
+type commentController struct {
+	db *gorm.DB
+}
+
+func NewCommentController(db *gorm.DB) CommentController {
+	return &commentController{
+		db: db,
+	}
+}
+
+type commentInput struct {
+	Body string `json:"body" binding:"required"`
+}
+
+// AddComment comments on a task the caller owns or is assigned and notifies
+// the users it mentions.
+func (cc *commentController) AddComment(c *gin.Context) {
+	uuidUserID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	uuidTaskID, err := utils.IsUUID(c.Param("taskID"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "Error: convert taskID into UUID", err.Error())
+		return
+	}
+
+	task, ok := findVisibleTask(c, cc.db, uuidUserID, uuidTaskID)
+	if !ok {
+		return
+	}
+
+	var input commentInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid input", err.Error())
+		return
+	}
+
+	comment := models.Comment{
+		TaskID: task.TaskID,
+		UserID: uuidUserID,
+		Body:   input.Body,
+	}
+	var notifications []models.Notification
+	err = cc.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&comment).Error; err != nil {
+			return err
+		}
+		notifications, err = notifyMentions(tx, &comment, &task, utils.ParseMentions(comment.Body))
+		return err
+	})
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Error creating comment", err.Error())
+		return
+	}
+
+	events.Publish(events.NewCommentEvent(events.CommentAdded, uuidUserID, comment, &task))
+	publishNotifications(notifications)
+
+	utils.SuccessResponse(c, http.StatusCreated, "Comment added successfully", comment)
+}
+
+// GetComments pages through the comments on a task the caller owns or is
+// assigned, oldest first.
+func (cc *commentController) GetComments(c *gin.Context) {
+	uuidUserID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	uuidTaskID, err := utils.IsUUID(c.Param("taskID"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "Error: convert taskID into UUID", err.Error())
+		return
+	}
+	if _, ok := findVisibleTask(c, cc.db, uuidUserID, uuidTaskID); !ok {
+		return
+	}
+
+	page, limit := utils.ParsePagination(c.Query("page"), c.Query("limit"), defaultCommentPageSize, maxCommentPageSize)
+
+	var total int64
+	query := cc.db.Model(&models.Comment{}).Where("task_id = ?", uuidTaskID)
+	if err := query.Count(&total).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Error counting comments", err.Error())
+		return
+	}
+
+	var comments []models.Comment
+	if err := query.Order("created_at ASC, comment_id ASC").
+		Offset((page - 1) * limit).
+		Limit(limit).
+		Find(&comments).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Error retrieving comments", err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Comments retrieved successfully", gin.H{
+		"comments": comments,
+		"page":     page,
+		"limit":    limit,
+		"total":    total,
+	})
+}
+
+func (cc *commentController) UpdateComment(c *gin.Context) {
+	comment, ok := cc.findOwnComment(c)
+	if !ok {
+		return
+	}
+
+	var input commentInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid input", err.Error())
+		return
+	}
+
+	previousMentions := make(map[string]bool)
+	for _, username := range utils.ParseMentions(comment.Body) {
+		previousMentions[username] = true
+	}
+	var newMentions []string
+	for _, username := range utils.ParseMentions(input.Body) {
+		if !previousMentions[username] {
+			newMentions = append(newMentions, username)
+		}
+	}
+
+	var task models.Task
+	if err := cc.db.First(&task, "task_id = ?", comment.TaskID).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "Task not found", err.Error())
+		return
+	}
+
+	comment.Body = input.Body
+	var notifications []models.Notification
+	err := cc.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&comment).Error; err != nil {
+			return err
+		}
+		var err error
+		notifications, err = notifyMentions(tx, &comment, &task, newMentions)
+		return err
+	})
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Error updating comment", err.Error())
+		return
+	}
+
+	events.Publish(events.NewCommentEvent(events.CommentUpdated, comment.UserID, comment, &task))
+	publishNotifications(notifications)
+
+	utils.SuccessResponse(c, http.StatusOK, "Comment updated successfully", comment)
+}
+
+func (cc *commentController) DeleteComment(c *gin.Context) {
+	comment, ok := cc.findOwnComment(c)
+	if !ok {
+		return
+	}
+
+	if err := cc.db.Delete(&comment).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Error deleting comment", err.Error())
+		return
+	}
+
+	var task models.Task
+	if err := cc.db.First(&task, "task_id = ?", comment.TaskID).Error; err == nil {
+		events.Publish(events.NewCommentEvent(events.CommentDeleted, comment.UserID, comment, &task))
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Comment deleted successfully", nil)
+}
+
+// findOwnComment loads the comment named by the commentID path parameter and
+// makes sure it was written by the requesting user. It writes the error
+// response itself and reports whether the handler may continue.
+func (cc *commentController) findOwnComment(c *gin.Context) (models.Comment, bool) {
+	var comment models.Comment
+
+	uuidUserID, ok := currentUserID(c)
+	if !ok {
+		return comment, false
+	}
+
+	uuidCommentID, err := utils.IsUUID(c.Param("commentID"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "Error: convert commentID into UUID", err.Error())
+		return comment, false
+	}
+
+	if err := cc.db.First(&comment, "comment_id = ?", uuidCommentID).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "Comment not found", err.Error())
+		return comment, false
+	}
+
+	if comment.UserID != uuidUserID {
+		utils.ErrorResponse(c, http.StatusForbidden, "Only the author can modify this comment", utils.ErrUnauthorized)
+		return comment, false
+	}
+
+	return comment, true
+}
+
+// notifyMentions resolves the mentioned usernames to users and stores a
+// notification for each of them, skipping the comment author and anyone who
+// cannot see the task, who would otherwise learn its title.
+func notifyMentions(tx *gorm.DB, comment *models.Comment, task *models.Task, usernames []string) ([]models.Notification, error) {
+	if len(usernames) == 0 {
+		return nil, nil
+	}
+
+	var users []models.User
+	if err := tx.Where("username IN ?", usernames).Find(&users).Error; err != nil {
+		return nil, err
+	}
+
+	var author models.User
+	if err := tx.First(&author, "user_id = ?", comment.UserID).Error; err != nil {
+		return nil, err
+	}
+
+	var notifications []models.Notification
+	for _, user := range users {
+		if user.UserID == comment.UserID || !task.VisibleTo(user.UserID) {
+			continue
+		}
+		notifications = append(notifications, models.Notification{
+			UserID:    user.UserID,
+			ActorID:   comment.UserID,
+			Type:      models.NotificationTypeMention,
+			TaskID:    task.TaskID,
+			CommentID: comment.CommentID,
+			Message:   fmt.Sprintf("%s mentioned you on \"%s\"", author.Username, task.Title),
+		})
+	}
+	if len(notifications) == 0 {
+		return nil, nil
+	}
+
+	if err := tx.Create(&notifications).Error; err != nil {
+		return nil, err
+	}
+	return notifications, nil
+}
+
+func publishNotifications(notifications []models.Notification) {
+	for _, notification := range notifications {
+		events.Publish(events.NewNotificationCreated(notification))
+	}
+}
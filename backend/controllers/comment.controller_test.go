@@ -0,0 +1,44 @@
+package controllers
+
+import (
+	"ai-task-manager/database/dbtest"
+	"ai-task-manager/models"
+	"database/sql/driver"
+	"testing"
+
+	"github.com/gofrs/uuid"
+)
+
+func TestMentionsOnlyNotifyUsersWhoCanSeeTheTask(t *testing.T) {
+	owner, assignee, stranger := uuid.Must(uuid.NewV4()), uuid.Must(uuid.NewV4()), uuid.Must(uuid.NewV4())
+	task := testTask(owner, assignee)
+	users := map[uuid.UUID]string{owner: "olivia", assignee: "arun", stranger: "sam"}
+	db, fake := dbtest.New(t)
+	fake.On(`FROM "Users"`, func(args []any) dbtest.Result {
+		result := dbtest.Result{Columns: []string{"user_id", "username"}}
+		for userID, username := range users {
+			for _, arg := range args {
+				if arg == username || arg == userID {
+					result.Rows = append(result.Rows, []driver.Value{userID.String(), username})
+				}
+			}
+		}
+		return result
+	})
+
+	comment := &models.Comment{CommentID: uuid.Must(uuid.NewV4()), TaskID: task.TaskID, UserID: owner}
+	notifications, err := notifyMentions(db, comment, &task, []string{"arun", "sam"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(notifications) != 1 || notifications[0].UserID != assignee {
+		t.Fatalf("notified %v, want only the assignee %s", notifications, assignee)
+	}
+	for _, insert := range fake.Statements(`^INSERT INTO "Notifications"`) {
+		for _, arg := range insert.Args {
+			if arg == stranger {
+				t.Errorf("the stranger was notified: %s", insert.SQL)
+			}
+		}
+	}
+}
@@ -0,0 +1,230 @@
+package controllers
+
+import (
+	"ai-task-manager/config"
+	"ai-task-manager/events"
+	"ai-task-manager/utils"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-contrib/sse"
+	"github.com/gin-gonic/gin"
+	"github.com/gofrs/uuid"
+)
+
+// maxStreamTopics bounds the topics of one event stream, as for a WebSocket
+// connection.
+const maxStreamTopics = 100
+
+type EventController interface {
+	GetEventSchemas(c *gin.Context)
+	GetEventSchema(c *gin.Context)
+	Stream(c *gin.Context)
+}
+
+type eventController struct {
+	bus    *events.Bus
+	stream streamOptions
+}
+
+// streamOptions tune the event streams: the events buffered for a slow
+// stream, the interval between keep-alive comments and the events replayed
+// on resume.
+type streamOptions struct {
+	queueSize   int
+	keepAlive   time.Duration
+	replayLimit int
+}
+
+func NewEventController(bus *events.Bus) EventController {
+	cfg := config.GetConfig()
+	return &eventController{
+		bus: bus,
+		stream: streamOptions{
+			queueSize:   cfg.WebSocketSendQueueSize,
+			keepAlive:   cfg.EventStreamKeepAlive,
+			replayLimit: cfg.EventReplayLimit,
+		},
+	}
+}
+
+// GetEventSchemas returns the JSON Schema of every event delivered over the
+// WebSocket, keyed by event type.
+func (e *eventController) GetEventSchemas(c *gin.Context) {
+	schemas := make(map[string]any, len(events.Types))
+	for _, eventType := range events.Types {
+		schemas[eventType], _ = events.Schema(eventType)
+	}
+	utils.SuccessResponse(c, http.StatusOK, "Event schemas retrieved successfully", gin.H{
+		"version": events.Version,
+		"types":   events.Types,
+		"schemas": schemas,
+	})
+}
+
+// GetEventSchema serves the bare JSON Schema of one event type, so it can be
+// fed to a validator directly.
+func (e *eventController) GetEventSchema(c *gin.Context) {
+	eventType := c.Param("type")
+	schema, ok := events.Schema(eventType)
+	if !ok {
+		utils.ErrorResponse(c, http.StatusNotFound, "Event type not found", fmt.Sprintf("unknown event type %q", eventType))
+		return
+	}
+	c.Header("Content-Type", "application/schema+json")
+	c.JSON(http.StatusOK, schema)
+}
+
+// Stream sends the caller's events as Server-Sent Events, for clients behind
+// proxies that break WebSockets. The events and their envelope are the same
+// as over the WebSocket; ?topics= takes a comma-separated list of its topics
+// and defaults to all. Each event's id is its seq, so a browser resumes with
+// Last-Event-ID by itself; ?lastEventId= does the same for the first request.
+// A stream that falls too far behind is closed and resumes on reconnect.
+func (e *eventController) Stream(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	topics := []events.Topic{}
+	for _, name := range strings.Split(c.Query("topics"), ",") {
+		if strings.TrimSpace(name) == "" {
+			continue
+		}
+		topic, err := events.ParseTopic(name)
+		if err != nil {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Invalid topic", err.Error())
+			return
+		}
+		if !slices.Contains(topics, topic) {
+			topics = append(topics, topic)
+		}
+	}
+	if len(topics) > maxStreamTopics {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Too many topics", fmt.Sprintf("a stream can subscribe to at most %d topics", maxStreamTopics))
+		return
+	}
+	if len(topics) == 0 {
+		all, _ := events.ParseTopic(events.TopicAll)
+		topics = append(topics, all)
+	}
+
+	var lastEventID int64
+	if value := c.GetHeader("Last-Event-ID"); value != "" || c.Query("lastEventId") != "" {
+		if value == "" {
+			value = c.Query("lastEventId")
+		}
+		id, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || id < 0 {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Invalid Last-Event-ID", "Last-Event-ID must be the seq of an event")
+			return
+		}
+		lastEventID = id
+	}
+
+	// Subscribing before replaying means nothing published in between is
+	// missed; live events wait in the queue until the replay is written.
+	queue := make(chan events.Event, e.stream.queueSize)
+	overflow := make(chan struct{})
+	var overflowOnce sync.Once
+	unsubscribe := e.bus.Subscribe(func(event events.Event) {
+		if !slices.Contains(event.Audience, userID) || !events.MatchesAny(topics, event, userID) {
+			return
+		}
+		select {
+		case queue <- event:
+		default:
+			overflowOnce.Do(func() { close(overflow) })
+		}
+	})
+	defer unsubscribe()
+
+	// Set here, as the headers are flushed before the first event is
+	// rendered.
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+
+	var replayed map[int64]bool
+	if lastEventID > 0 {
+		replayed = e.replay(c, userID, topics, lastEventID, e.stream.replayLimit)
+	}
+	c.Writer.Flush()
+
+	keepAlive := time.NewTicker(e.stream.keepAlive)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case <-overflow:
+			return
+		case event := <-queue:
+			if replayed[event.Seq] {
+				continue
+			}
+			writeStreamEvent(c, event)
+			c.Writer.Flush()
+		case <-keepAlive.C:
+			if _, err := c.Writer.WriteString(": keep-alive\n\n"); err != nil {
+				return
+			}
+			c.Writer.Flush()
+		}
+	}
+}
+
+// replay writes the events after lastEventID, followed by a replayed event,
+// or a resync_required event when they cannot all be replayed. It returns
+// the seqs of the events it went through, which live events may arrive after
+// out of order, or nil after a resync.
+func (e *eventController) replay(c *gin.Context, userID uuid.UUID, topics []events.Topic, lastEventID int64, limit int) map[int64]bool {
+	missed, err := events.Replay(e.bus.Log(), userID, lastEventID, limit)
+	if err != nil {
+		if !errors.Is(err, events.ErrResyncRequired) {
+			log.Printf("Error replaying events for user %s: %v", userID, err)
+		}
+		c.Render(-1, sse.Event{
+			Event: "resync_required",
+			Data:  gin.H{"lastEventId": lastEventID, "message": events.ErrResyncRequired.Error()},
+		})
+		return nil
+	}
+
+	last, count := lastEventID, 0
+	replayed := make(map[int64]bool, len(missed))
+	for _, event := range missed {
+		last = event.Seq
+		replayed[event.Seq] = true
+		if events.MatchesAny(topics, event, userID) {
+			writeStreamEvent(c, event)
+			count++
+		}
+	}
+	c.Render(-1, sse.Event{
+		Event: "replayed",
+		Data:  gin.H{"count": count, "lastEventId": last},
+	})
+	return replayed
+}
+
+// writeStreamEvent writes an event named after its type, so clients can
+// listen for the types they handle.
+func writeStreamEvent(c *gin.Context, event events.Event) {
+	message := sse.Event{Event: event.Type, Data: event}
+	if event.Seq != 0 {
+		message.Id = strconv.FormatInt(event.Seq, 10)
+	}
+	c.Render(-1, message)
+}
@@ -0,0 +1,53 @@
+package controllers
+
+import (
+	"ai-task-manager/events"
+	"bufio"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gofrs/uuid"
+)
+
+func TestStreamIsAnEventStream(t *testing.T) {
+	userID := uuid.Must(uuid.NewV4())
+	bus := events.NewBus()
+	controller := &eventController{
+		bus:    bus,
+		stream: streamOptions{queueSize: 8, keepAlive: time.Minute, replayLimit: 10},
+	}
+	router := gin.New()
+	router.GET("/stream", func(c *gin.Context) {
+		c.Set("user", map[string]interface{}{"userID": userID.String()})
+	}, controller.Stream)
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	response, err := http.Get(server.URL + "/stream")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer response.Body.Close()
+	if contentType := response.Header.Get("Content-Type"); !strings.HasPrefix(contentType, "text/event-stream") {
+		t.Errorf("Content-Type = %q, want text/event-stream", contentType)
+	}
+
+	event := events.New(events.UserUpdated, userID, nil, userID)
+	event.Seq = 7
+	bus.Publish(event)
+
+	lines := bufio.NewScanner(response.Body)
+	var got []string
+	for len(got) < 2 && lines.Scan() {
+		if line := lines.Text(); line != "" {
+			got = append(got, line)
+		}
+	}
+	if len(got) < 2 || got[0] != "id:7" || got[1] != "event:"+events.UserUpdated {
+		t.Errorf("stream began with %q, want the event's id and type", got)
+	}
+}
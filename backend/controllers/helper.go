@@ -0,0 +1,129 @@
+package controllers
+
+import (
+	"ai-task-manager/models"
+	"ai-task-manager/utils"
+	"ai-task-manager/validations"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"slices"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gofrs/uuid"
+	"gorm.io/gorm"
+)
+
+// currentUserID is a convenience for handlers that only need the caller's UUID.
+func currentUserID(c *gin.Context) (uuid.UUID, bool) {
+	userID, err := utils.GetUserIdFromHeader(c)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "Error: get userID from header", err.Error())
+		return uuid.Nil, false
+	}
+	uuidUserID, err := utils.IsUUID(userID)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "Error: convert userID into UUID", err.Error())
+		return uuid.Nil, false
+	}
+	return uuidUserID, true
+}
+
+// findVisibleTask loads the task with taskID if userID owns or is assigned
+// it. Other users get the same 404 as for a missing task, so task IDs do not
+// leak. It writes the error response and returns false on failure.
+func findVisibleTask(c *gin.Context, db *gorm.DB, userID, taskID uuid.UUID) (models.Task, bool) {
+	var task models.Task
+	if err := db.First(&task, "task_id = ?", taskID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.ErrorResponse(c, http.StatusNotFound, "Task not found", err.Error())
+			return task, false
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Error fetching task", err.Error())
+		return task, false
+	}
+	if !task.VisibleTo(userID) {
+		utils.ErrorResponse(c, http.StatusNotFound, "Task not found", gorm.ErrRecordNotFound.Error())
+		return task, false
+	}
+	return task, true
+}
+
+// patchResource applies the request body to current as a JSON Merge Patch
+// (application/merge-patch+json or application/json) or a JSON Patch
+// (application/json-patch+json) and decodes the result into target. Patches
+// that touch a read-only field or add an unknown one are rejected. It writes
+// the error response and returns false on failure.
+func patchResource(c *gin.Context, current, target any, readOnly []string) bool {
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid input", err.Error())
+		return false
+	}
+	original, err := json.Marshal(current)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Error encoding resource", err.Error())
+		return false
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(c.ContentType())
+	var patched []byte
+	switch mediaType {
+	case utils.MergePatchContentType, "application/json":
+		patched, err = utils.MergePatch(original, body)
+	case utils.JSONPatchContentType:
+		patched, err = utils.ApplyJSONPatch(original, body)
+	default:
+		utils.ErrorResponse(c, http.StatusUnsupportedMediaType, "Unsupported patch format",
+			fmt.Sprintf("use %s or %s", utils.MergePatchContentType, utils.JSONPatchContentType))
+		return false
+	}
+	if errors.Is(err, utils.ErrInvalidPatch) {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid patch document", err.Error())
+		return false
+	}
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusUnprocessableEntity, "Patch could not be applied", err.Error())
+		return false
+	}
+
+	var before, after map[string]json.RawMessage
+	if err := json.Unmarshal(original, &before); err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Error encoding resource", err.Error())
+		return false
+	}
+	if err := json.Unmarshal(patched, &after); err != nil {
+		utils.ErrorResponse(c, http.StatusUnprocessableEntity, "Patch must produce an object", err.Error())
+		return false
+	}
+
+	var fieldErrors []validations.FieldError
+	for _, field := range readOnly {
+		if !utils.JSONEqual(before[field], after[field]) {
+			fieldErrors = append(fieldErrors, validations.FieldError{Field: field, Message: "is read-only"})
+		}
+	}
+	added := make([]string, 0)
+	for field := range after {
+		if _, ok := before[field]; !ok && !slices.Contains(readOnly, field) {
+			added = append(added, field)
+		}
+	}
+	slices.Sort(added)
+	for _, field := range added {
+		fieldErrors = append(fieldErrors, validations.FieldError{Field: field, Message: "is not a known field"})
+	}
+	if len(fieldErrors) > 0 {
+		utils.FieldErrorsResponse(c, http.StatusUnprocessableEntity, "Patch changes fields that cannot be changed", fieldErrors)
+		return false
+	}
+
+	if err := json.Unmarshal(patched, target); err != nil {
+		utils.ErrorResponse(c, http.StatusUnprocessableEntity, "Patch produced an invalid value", err.Error())
+		return false
+	}
+	return true
+}
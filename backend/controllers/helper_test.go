@@ -0,0 +1,87 @@
+package controllers
+
+import (
+	"ai-task-manager/database/dbtest"
+	"ai-task-manager/models"
+	"database/sql/driver"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gofrs/uuid"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+// serveAs runs handler for a request made by userID, as the auth middleware
+// would have authenticated it.
+func serveAs(userID uuid.UUID, route, method, path, body string, handler gin.HandlerFunc, headers ...string) *httptest.ResponseRecorder {
+	router := gin.New()
+	router.Handle(method, route, func(c *gin.Context) {
+		c.Set("user", map[string]interface{}{"userID": userID.String()})
+	}, handler)
+
+	var reader io.Reader
+	if body != "" {
+		reader = strings.NewReader(body)
+	}
+	request := httptest.NewRequest(method, path, reader)
+	request.Header.Set("Content-Type", "application/json")
+	for i := 0; i+1 < len(headers); i += 2 {
+		request.Header.Set(headers[i], headers[i+1])
+	}
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, request)
+	return recorder
+}
+
+// onTask answers the queries loading tasks with task, whatever they filter
+// on, unless they scope it to the tasks of a user who can't see it.
+func onTask(db *dbtest.DB, task models.Task) {
+	db.On(`FROM "Tasks"`, func(args []any) dbtest.Result {
+		for _, arg := range args {
+			if userID, ok := arg.(uuid.UUID); ok && userID != task.TaskID && !task.VisibleTo(userID) {
+				return dbtest.Result{}
+			}
+		}
+		return dbtest.Result{
+			Columns: []string{"task_id", "user_id", "assigned_to", "title", "description", "status", "version"},
+			Rows: [][]driver.Value{{
+				task.TaskID.String(), task.UserID.String(), task.AssignedTo.String(),
+				task.Title, task.Description, task.Status, task.Version,
+			}},
+		}
+	})
+}
+
+// testTask returns a task owned by owner and assigned to assignee.
+func testTask(owner, assignee uuid.UUID) models.Task {
+	return models.Task{
+		TaskID:      uuid.Must(uuid.NewV4()),
+		UserID:      owner,
+		AssignedTo:  assignee,
+		Title:       "Quarterly report",
+		Description: "Figures for the third quarter",
+		Status:      "pending",
+		Version:     1,
+	}
+}
+
+func expectStatus(t *testing.T, recorder *httptest.ResponseRecorder, want int) {
+	t.Helper()
+	if recorder.Code != want {
+		t.Errorf("status = %d, want %d: %s", recorder.Code, want, recorder.Body.String())
+	}
+}
+
+// expectNoWrites fails when anything was changed in the database.
+func expectNoWrites(t *testing.T, db *dbtest.DB) {
+	t.Helper()
+	if writes := db.Statements(`^(INSERT|UPDATE|DELETE)`); len(writes) > 0 {
+		t.Errorf("the database was written to: %s", writes[0].SQL)
+	}
+}
@@ -0,0 +1,96 @@
+package controllers
+
+import (
+	"ai-task-manager/models"
+	"ai-task-manager/utils"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultNotificationPageSize = 20
+	maxNotificationPageSize     = 100
+)
+
+type NotificationController interface {
+	GetNotifications(c *gin.Context)
+	MarkNotificationRead(c *gin.Context)
+}
+
+type notificationController struct {
+	db *gorm.DB
+}
+
+func NewNotificationController(db *gorm.DB) NotificationController {
+	return &notificationController{
+		db: db,
+	}
+}
+
+func (n *notificationController) GetNotifications(c *gin.Context) {
+	uuidUserID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	page, limit := utils.ParsePagination(c.Query("page"), c.Query("limit"), defaultNotificationPageSize, maxNotificationPageSize)
+
+	query := n.db.Model(&models.Notification{}).Where("user_id = ?", uuidUserID)
+	if c.Query("unread") == "true" {
+		query = query.Where("read_at IS NULL")
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Error counting notifications", err.Error())
+		return
+	}
+
+	var notifications []models.Notification
+	if err := query.Order("created_at DESC").
+		Offset((page - 1) * limit).
+		Limit(limit).
+		Find(&notifications).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Error retrieving notifications", err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Notifications retrieved successfully", gin.H{
+		"notifications": notifications,
+		"page":          page,
+		"limit":         limit,
+		"total":         total,
+	})
+}
+
+func (n *notificationController) MarkNotificationRead(c *gin.Context) {
+	uuidUserID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	uuidNotificationID, err := utils.IsUUID(c.Param("notificationID"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "Error: convert notificationID into UUID", err.Error())
+		return
+	}
+
+	var notification models.Notification
+	if err := n.db.First(&notification, "notification_id = ? AND user_id = ?", uuidNotificationID, uuidUserID).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "Notification not found", err.Error())
+		return
+	}
+
+	if notification.ReadAt == nil {
+		now := time.Now()
+		if err := n.db.Model(&notification).Update("read_at", now).Error; err != nil {
+			utils.ErrorResponse(c, http.StatusInternalServerError, "Error updating notification", err.Error())
+			return
+		}
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Notification marked as read", notification)
+}
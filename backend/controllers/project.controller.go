@@ -0,0 +1,190 @@
+package controllers
+
+import (
+	"ai-task-manager/models"
+	"ai-task-manager/utils"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gofrs/uuid"
+	"gorm.io/gorm"
+)
+
+var errProjectNotFound = errors.New("project not found")
+
+type ProjectController interface {
+	CreateProject(c *gin.Context)
+	GetProjects(c *gin.Context)
+	GetProject(c *gin.Context)
+	UpdateProject(c *gin.Context)
+	DeleteProject(c *gin.Context)
+}
+
+type projectController struct {
+	db *gorm.DB
+}
+
+func NewProjectController(db *gorm.DB) ProjectController {
+	return &projectController{
+		db: db,
+	}
+}
+
+type projectInput struct {
+	Name        string `json:"name" binding:"required"`
+	Description string `json:"description"`
+}
+
+func (p *projectController) CreateProject(c *gin.Context) {
+	uuidUserID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	var input projectInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid input", err.Error())
+		return
+	}
+
+	project := models.Project{
+		UserID:      uuidUserID,
+		Name:        input.Name,
+		Description: input.Description,
+	}
+	err := p.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&project).Error; err != nil {
+			return err
+		}
+		return models.RecordAudit(tx, uuidUserID, models.AuditEntityProject, project.ProjectID, "create", nil)
+	})
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Error creating project", err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusCreated, "Project created successfully", project)
+}
+
+func (p *projectController) GetProjects(c *gin.Context) {
+	uuidUserID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	var projects []models.Project
+	if err := p.db.Where("user_id = ?", uuidUserID).Order("name").Find(&projects).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Error retrieving projects", err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Projects retrieved successfully", projects)
+}
+
+func (p *projectController) GetProject(c *gin.Context) {
+	project, _, ok := p.findOwnProject(c)
+	if !ok {
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Project retrieved successfully", project)
+}
+
+func (p *projectController) UpdateProject(c *gin.Context) {
+	project, actorID, ok := p.findOwnProject(c)
+	if !ok {
+		return
+	}
+
+	var input projectInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid input", err.Error())
+		return
+	}
+
+	var changes []models.FieldChange
+	if project.Name != input.Name {
+		changes = append(changes, models.FieldChange{Field: "name", From: project.Name, To: input.Name})
+	}
+	if project.Description != input.Description {
+		changes = append(changes, models.FieldChange{Field: "description", From: project.Description, To: input.Description})
+	}
+	project.Name = input.Name
+	project.Description = input.Description
+
+	err := p.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&project).Error; err != nil {
+			return err
+		}
+		return models.RecordAudit(tx, actorID, models.AuditEntityProject, project.ProjectID, "update", changes)
+	})
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Error updating project", err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Project updated successfully", project)
+}
+
+// DeleteProject deletes the project and moves its tasks out of it.
+func (p *projectController) DeleteProject(c *gin.Context) {
+	project, actorID, ok := p.findOwnProject(c)
+	if !ok {
+		return
+	}
+
+	err := p.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Task{}).Where("project_id = ?", project.ProjectID).Update("project_id", nil).Error; err != nil {
+			return err
+		}
+		if err := tx.Delete(&project).Error; err != nil {
+			return err
+		}
+		return models.RecordAudit(tx, actorID, models.AuditEntityProject, project.ProjectID, "delete", nil)
+	})
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Error deleting project", err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Project deleted successfully", nil)
+}
+
+func (p *projectController) findOwnProject(c *gin.Context) (models.Project, uuid.UUID, bool) {
+	var project models.Project
+
+	uuidUserID, ok := currentUserID(c)
+	if !ok {
+		return project, uuid.Nil, false
+	}
+
+	uuidProjectID, err := utils.IsUUID(c.Param("projectID"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "Error: convert projectID into UUID", err.Error())
+		return project, uuid.Nil, false
+	}
+
+	if err := p.db.First(&project, "project_id = ? AND user_id = ?", uuidProjectID, uuidUserID).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "Project not found", err.Error())
+		return project, uuid.Nil, false
+	}
+
+	return project, uuidUserID, true
+}
+
+// ensureProjectOwner checks that projectID, when set, names a project owned
+// by userID.
+func ensureProjectOwner(db *gorm.DB, userID uuid.UUID, projectID *uuid.UUID) error {
+	if projectID == nil {
+		return nil
+	}
+	var count int64
+	if err := db.Model(&models.Project{}).Where("project_id = ? AND user_id = ?", *projectID, userID).Count(&count).Error; err != nil {
+		return err
+	}
+	if count == 0 {
+		return errProjectNotFound
+	}
+	return nil
+}
@@ -1,32 +1,68 @@
 package controllers
 
 import (
+	"ai-task-manager/config"
+	"ai-task-manager/embeddings"
+	"ai-task-manager/events"
 	"ai-task-manager/models"
+	"ai-task-manager/repository"
 	"ai-task-manager/utils"
-	"ai-task-manager/websocket"
+	"ai-task-manager/validations"
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
 	"net/http"
+	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
+	"github.com/gofrs/uuid"
 	"gorm.io/gorm"
 )
 
+const (
+	defaultTrashPageSize  = 20
+	maxTrashPageSize      = 100
+	defaultSearchPageSize = 20
+	maxSearchPageSize     = 100
+
+	maxDuplicateResults        = 5
+	defaultSimilarResults      = 10
+	maxSimilarResults          = 50
+	defaultSimilarityThreshold = 0.5
+)
+
 type TaskController interface {
 	CreateTask(c *gin.Context)
 	GetTask(c *gin.Context)
 	GetAllTasks(c *gin.Context)
 	UpdateTask(c *gin.Context)
+	PatchTask(c *gin.Context)
 	DeleteTask(c *gin.Context)
 	ChangeStatusTask(c *gin.Context)
+	GetTrash(c *gin.Context)
+	RestoreTask(c *gin.Context)
+	PermanentDeleteTask(c *gin.Context)
+	SetTaskLabels(c *gin.Context)
+	SearchTasks(c *gin.Context)
+	FindSimilarTasks(c *gin.Context)
+	BulkTasks(c *gin.Context)
+	ExportTasks(c *gin.Context)
+	ImportTasks(c *gin.Context)
+	MigrateTasks(c *gin.Context)
 }
 
 type taskController struct {
-	db *gorm.DB
+	db    *gorm.DB
+	tasks repository.TaskRepository
 }
 
 func NewTaskController(db *gorm.DB) TaskController {
 	return &taskController{
-		db: db,
+		db:    db,
+		tasks: repository.NewTaskRepository(db),
 	}
 }
 
@@ -49,16 +85,136 @@ func (t *taskController) CreateTask(c *gin.Context) {
 		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid input", err.Error())
 		return
 	}
+	// Labels are managed through SetTaskLabels.
+	task.Labels = nil
+	if err := ensureProjectOwner(t.db, uuidUserID, task.ProjectID); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid project", err.Error())
+		return
+	}
 
-	if err := t.db.Create(&task).Error; err != nil {
+	err = t.db.Transaction(func(tx *gorm.DB) error {
+		return createTaskTx(tx, uuidUserID, &task)
+	})
+	if err != nil {
 		utils.ErrorResponse(c, http.StatusInternalServerError, "Error creating task", err.Error())
 		return
 	}
 
-	taskJSON, _ := json.Marshal(task)
-	websocket.Manager.BroadcastMessage(taskJSON)
+	events.Publish(events.NewTaskCreated(uuidUserID, &task))
+
+	response := createTaskResponse{Task: task}
+	response.PossibleDuplicates = t.findDuplicates(c.Request.Context(), &task)
+	message := "Task created successfully"
+	if len(response.PossibleDuplicates) > 0 {
+		message = "Task created successfully, but it looks like an existing task"
+	}
+
+	utils.SuccessResponse(c, http.StatusCreated, message, response)
+}
 
-	utils.SuccessResponse(c, http.StatusCreated, "Task created successfully", task)
+type createTaskResponse struct {
+	models.Task
+	PossibleDuplicates []embeddings.SimilarTask `json:"possibleDuplicates,omitempty"`
+}
+
+// findDuplicates embeds a newly created task and returns the tasks its owner
+// sees that are similar enough to be likely duplicates. Failures only cost
+// the warning, so they are logged and the embedding is retried in the
+// background.
+func (t *taskController) findDuplicates(ctx context.Context, task *models.Task) []embeddings.SimilarTask {
+	if embeddings.Default == nil {
+		return nil
+	}
+
+	vector, err := embeddings.Default.IndexTask(ctx, task)
+	if err != nil {
+		log.Printf("Error embedding task %s: %v", task.TaskID, err)
+		embeddings.Default.Enqueue(task.TaskID)
+		return nil
+	}
+
+	duplicates, err := embeddings.Default.FindSimilar(ctx, task.UserID, vector, task.TaskID, config.GetConfig().DuplicateThreshold, maxDuplicateResults)
+	if err != nil {
+		log.Printf("Error finding duplicates for task %s: %v", task.TaskID, err)
+		return nil
+	}
+	return duplicates
+}
+
+// FindSimilarTasks ranks the tasks the caller owns or is assigned by semantic
+// similarity to one of them (?taskID=) or to free text (?text=).
+func (t *taskController) FindSimilarTasks(c *gin.Context) {
+	uuidUserID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	if embeddings.Default == nil {
+		utils.ErrorResponse(c, http.StatusServiceUnavailable, "Similarity search is not configured", nil)
+		return
+	}
+
+	var fieldErrors []validations.FieldError
+	threshold := defaultSimilarityThreshold
+	if value := c.Query("threshold"); value != "" {
+		parsed, err := strconv.ParseFloat(value, 64)
+		if err != nil || parsed < -1 || parsed > 1 {
+			fieldErrors = append(fieldErrors, validations.FieldError{Field: "threshold", Message: "must be a number between -1 and 1"})
+		}
+		threshold = parsed
+	}
+	limit := defaultSimilarResults
+	if value := c.Query("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 || parsed > maxSimilarResults {
+			fieldErrors = append(fieldErrors, validations.FieldError{Field: "limit", Message: fmt.Sprintf("must be an integer between 1 and %d", maxSimilarResults)})
+		}
+		limit = parsed
+	}
+	taskID, text := c.Query("taskID"), strings.TrimSpace(c.Query("text"))
+	if (taskID == "") == (text == "") {
+		fieldErrors = append(fieldErrors, validations.FieldError{Field: "taskID", Message: "exactly one of taskID or text is required"})
+	}
+	if len(fieldErrors) > 0 {
+		utils.FieldErrorsResponse(c, http.StatusBadRequest, "Invalid query parameters", fieldErrors)
+		return
+	}
+
+	ctx := c.Request.Context()
+	excludeTaskID := uuid.Nil
+	var vector []float32
+	if taskID != "" {
+		uuidTaskID, err := utils.IsUUID(taskID)
+		if err != nil {
+			utils.ErrorResponse(c, http.StatusNotFound, "Error: convert taskID into UUID", err.Error())
+			return
+		}
+		var task models.Task
+		if err := t.db.Scopes(models.VisibleTasks(uuidUserID)).First(&task, "task_id = ?", uuidTaskID).Error; err != nil {
+			utils.ErrorResponse(c, http.StatusNotFound, "Task not found", err.Error())
+			return
+		}
+		vector, err = embeddings.Default.IndexTask(ctx, &task)
+		if err != nil {
+			utils.ErrorResponse(c, http.StatusBadGateway, "Error computing embedding", err.Error())
+			return
+		}
+		excludeTaskID = task.TaskID
+	} else {
+		var err error
+		vector, err = embeddings.Default.Embedder().Embed(ctx, text)
+		if err != nil {
+			utils.ErrorResponse(c, http.StatusBadGateway, "Error computing embedding", err.Error())
+			return
+		}
+	}
+
+	similar, err := embeddings.Default.FindSimilar(ctx, uuidUserID, vector, excludeTaskID, threshold, limit)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Error finding similar tasks", err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Similar tasks retrieved successfully", similar)
 }
 
 func (t *taskController) GetTask(c *gin.Context) {
@@ -74,17 +230,85 @@ func (t *taskController) GetTask(c *gin.Context) {
 		return
 	}
 
+	etag := taskETag(task)
+	c.Header("ETag", etag)
+	if match := c.GetHeader("If-None-Match"); match != "" && utils.ETagMatches(match, etag, true) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+
 	utils.SuccessResponse(c, http.StatusOK, "Task retrieved successfully", task)
 }
 
+// GetAllTasks lists the tasks the caller owns or is assigned, with cursor
+// pagination. See validations.ParseTaskQuery for the supported query
+// parameters.
 func (t *taskController) GetAllTasks(c *gin.Context) {
-	var tasks []models.Task
-	if err := t.db.Find(&tasks).Error; err != nil {
+	uuidUserID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	query, fieldErrors := validations.ParseTaskQuery(c.Request.URL.Query(), uuidUserID)
+	if len(fieldErrors) > 0 {
+		utils.FieldErrorsResponse(c, http.StatusBadRequest, "Invalid query parameters", fieldErrors)
+		return
+	}
+
+	page, err := t.tasks.List(c.Request.Context(), query)
+	if err != nil {
 		utils.ErrorResponse(c, http.StatusInternalServerError, "Error retrieving tasks", err.Error())
 		return
 	}
 
-	utils.SuccessResponse(c, http.StatusOK, "Tasks retrieved successfully", tasks)
+	var items any = page.Tasks
+	if len(query.Fields) > 0 {
+		items, err = selectTaskFields(page.Tasks, query.Fields)
+		if err != nil {
+			utils.ErrorResponse(c, http.StatusInternalServerError, "Error retrieving tasks", err.Error())
+			return
+		}
+	}
+
+	utils.PaginatedResponse(c, http.StatusOK, "Tasks retrieved successfully", items, utils.PageInfo{
+		Limit:      query.Limit,
+		NextCursor: page.NextCursor,
+		PrevCursor: page.PrevCursor,
+		HasNext:    page.HasNext,
+		HasPrev:    page.HasPrev,
+	}, page.Total)
+}
+
+// SearchTasks runs a full-text search over the tasks the caller owns or is
+// assigned. See validations.SearchQuery for the query syntax accepted in ?q=.
+func (t *taskController) SearchTasks(c *gin.Context) {
+	uuidUserID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	query, err := validations.ParseSearchQuery(c.Query("q"), uuidUserID)
+	if err != nil {
+		utils.FieldErrorsResponse(c, http.StatusBadRequest, "Invalid query parameters", []validations.FieldError{
+			{Field: "q", Message: err.Error()},
+		})
+		return
+	}
+
+	page, limit := utils.ParsePagination(c.Query("page"), c.Query("limit"), defaultSearchPageSize, maxSearchPageSize)
+
+	result, err := t.tasks.Search(c.Request.Context(), query, page, limit)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Error searching tasks", err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Tasks searched successfully", gin.H{
+		"results": result.Hits,
+		"page":    page,
+		"limit":   limit,
+		"total":   result.Total,
+	})
 }
 
 func (t *taskController) UpdateTask(c *gin.Context) {
@@ -99,6 +323,9 @@ func (t *taskController) UpdateTask(c *gin.Context) {
 		utils.ErrorResponse(c, http.StatusNotFound, "Task not found", err.Error())
 		return
 	}
+	if !checkIfMatch(c, task) {
+		return
+	}
 
 	var updatedData models.Task
 	if err := c.ShouldBindJSON(&updatedData); err != nil {
@@ -106,14 +333,95 @@ func (t *taskController) UpdateTask(c *gin.Context) {
 		return
 	}
 
-	if err := t.db.Model(&task).Updates(updatedData).Error; err != nil {
-		utils.ErrorResponse(c, http.StatusInternalServerError, "Error updating task", err.Error())
+	actorID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	if err := ensureProjectOwner(t.db, task.UserID, updatedData.ProjectID); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid project", err.Error())
 		return
 	}
+	before := task
+	err = t.db.Transaction(func(tx *gorm.DB) error {
+		if err := models.UpdateTaskVersioned(tx, &task, updatedData); err != nil {
+			return err
+		}
+		return models.RecordTaskEvents(tx, models.DiffTask(actorID, &before, &task)...)
+	})
+	if err != nil {
+		respondTaskWriteError(c, "Error updating task", err)
+		return
+	}
+
+	events.Publish(events.NewTaskChanged(actorID, &before, &task)...)
+	reindexTask(&before, &task)
 
+	c.Header("ETag", taskETag(task))
 	utils.SuccessResponse(c, http.StatusOK, "Task updated successfully", task)
 }
 
+// readOnlyTaskFields cannot be changed through PatchTask. Labels have their
+// own endpoint and the version only moves with successful writes.
+var readOnlyTaskFields = []string{"taskID", "userID", "createdAt", "updatedAt", "deletedAt", "version", "labels"}
+
+// PatchTask applies a JSON Merge Patch or JSON Patch to a task. Unlike
+// UpdateTask, fields can be cleared, and validation runs on the patched task.
+func (t *taskController) PatchTask(c *gin.Context) {
+	uuidTaskID, err := utils.IsUUID(c.Param("taskID"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "Error: convert taskID into UUID", err.Error())
+		return
+	}
+	actorID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	task, ok := findVisibleTask(c, t.db, actorID, uuidTaskID)
+	if !ok {
+		return
+	}
+	if !checkIfMatch(c, task) {
+		return
+	}
+
+	var patched models.Task
+	if !patchResource(c, task, &patched, readOnlyTaskFields) {
+		return
+	}
+	// The assignee can work on the task but not hand it on.
+	if patched.AssignedTo != task.AssignedTo && actorID != task.UserID {
+		utils.ErrorResponse(c, http.StatusForbidden, "Only the task owner can reassign the task", utils.ErrUnauthorized)
+		return
+	}
+	if err := validations.ValidateTask(validations.Task{
+		Title:       patched.Title,
+		Description: patched.Description,
+		Status:      patched.Status,
+		Priority:    patched.Priority,
+	}); err != nil {
+		utils.ErrorResponse(c, http.StatusUnprocessableEntity, "Invalid task", err.Error())
+		return
+	}
+	if err := ensureProjectOwner(t.db, task.UserID, patched.ProjectID); err != nil {
+		utils.ErrorResponse(c, http.StatusUnprocessableEntity, "Invalid project", err.Error())
+		return
+	}
+
+	err = t.db.Transaction(func(tx *gorm.DB) error {
+		return saveTaskTx(tx, actorID, &task, &patched)
+	})
+	if err != nil {
+		respondTaskWriteError(c, "Error updating task", err)
+		return
+	}
+
+	events.Publish(events.NewTaskChanged(actorID, &task, &patched)...)
+	reindexTask(&task, &patched)
+
+	c.Header("ETag", taskETag(patched))
+	utils.SuccessResponse(c, http.StatusOK, "Task updated successfully", patched)
+}
+
 func (t *taskController) DeleteTask(c *gin.Context) {
 	taskID := c.Param("taskID")
 	uuidTaskID, err := utils.IsUUID(taskID)
@@ -121,10 +429,26 @@ func (t *taskController) DeleteTask(c *gin.Context) {
 		utils.ErrorResponse(c, http.StatusNotFound, "Error: convert taskID into UUID", err.Error())
 		return
 	}
-	if err := t.db.Delete(&models.Task{}, "id = ?", uuidTaskID).Error; err != nil {
-		utils.ErrorResponse(c, http.StatusInternalServerError, "Error deleting task", err.Error())
+	actorID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	var task models.Task
+	if err := t.db.First(&task, "task_id = ?", uuidTaskID).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "Task not found", err.Error())
+		return
+	}
+	if !checkIfMatch(c, task) {
 		return
 	}
+	err = t.db.Transaction(func(tx *gorm.DB) error {
+		return deleteTaskTx(tx, actorID, &task)
+	})
+	if err != nil {
+		respondTaskWriteError(c, "Error deleting task", err)
+		return
+	}
+	events.Publish(events.NewTaskDeleted(actorID, &task))
 	utils.SuccessResponse(c, http.StatusOK, "Task deleted successfully", nil)
 }
 
@@ -154,17 +478,254 @@ func (t *taskController) ChangeStatusTask(c *gin.Context) {
 		return
 	}
 
+	actorID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
 	var task models.Task
-	if err := t.db.Where("id = ?", uuidTaskID).First(&task).Error; err != nil {
+	if err := t.db.Where("task_id = ?", uuidTaskID).First(&task).Error; err != nil {
 		utils.ErrorResponse(c, http.StatusNotFound, "Task not found", err.Error())
 		return
 	}
 
-	task.Status = statusUpdate.Status
-	if err := t.db.Save(&task).Error; err != nil {
-		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to update task status", err.Error())
+	if !checkIfMatch(c, task) {
 		return
 	}
 
+	before := task
+	err = t.db.Transaction(func(tx *gorm.DB) error {
+		if err := models.UpdateTaskVersioned(tx, &task, models.Task{Status: statusUpdate.Status}); err != nil {
+			return err
+		}
+		return models.RecordTaskEvents(tx, models.DiffTask(actorID, &before, &task)...)
+	})
+	if err != nil {
+		respondTaskWriteError(c, "Failed to update task status", err)
+		return
+	}
+	events.Publish(events.NewTaskChanged(actorID, &before, &task)...)
+
+	c.Header("ETag", taskETag(task))
 	utils.SuccessResponse(c, http.StatusOK, "Task status updated successfully", task)
 }
+
+func (t *taskController) GetTrash(c *gin.Context) {
+	uuidUserID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	page, limit := utils.ParsePagination(c.Query("page"), c.Query("limit"), defaultTrashPageSize, maxTrashPageSize)
+
+	query := t.db.Unscoped().Model(&models.Task{}).
+		Where("user_id = ? AND deleted_at IS NOT NULL", uuidUserID)
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Error counting deleted tasks", err.Error())
+		return
+	}
+
+	var tasks []models.Task
+	if err := query.Order("deleted_at DESC").
+		Offset((page - 1) * limit).
+		Limit(limit).
+		Find(&tasks).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Error retrieving deleted tasks", err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Deleted tasks retrieved successfully", gin.H{
+		"tasks": tasks,
+		"page":  page,
+		"limit": limit,
+		"total": total,
+	})
+}
+
+func (t *taskController) RestoreTask(c *gin.Context) {
+	task, actorID, ok := t.findOwnTaskUnscoped(c)
+	if !ok {
+		return
+	}
+	if !task.DeletedAt.Valid {
+		utils.ErrorResponse(c, http.StatusConflict, "Task is not deleted", nil)
+		return
+	}
+
+	err := t.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Model(&task).Update("deleted_at", nil).Error; err != nil {
+			return err
+		}
+		return models.RecordTaskEvents(tx, models.TaskEvent{
+			TaskID:  task.TaskID,
+			ActorID: actorID,
+			Action:  models.TaskActionRestore,
+		})
+	})
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Error restoring task", err.Error())
+		return
+	}
+	task.DeletedAt = gorm.DeletedAt{}
+	events.Publish(events.NewTaskRestored(actorID, &task))
+
+	utils.SuccessResponse(c, http.StatusOK, "Task restored successfully", task)
+}
+
+func (t *taskController) PermanentDeleteTask(c *gin.Context) {
+	task, actorID, ok := t.findOwnTaskUnscoped(c)
+	if !ok {
+		return
+	}
+	if !checkIfMatch(c, task) {
+		return
+	}
+
+	if err := models.PurgeTask(t.db, actorID, &task); err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Error permanently deleting task", err.Error())
+		return
+	}
+	events.Publish(events.NewTaskPurged(actorID, &task))
+
+	utils.SuccessResponse(c, http.StatusOK, "Task permanently deleted", nil)
+}
+
+// findOwnTaskUnscoped loads the task named by the taskID path parameter,
+// including soft-deleted ones, and checks that the caller owns it.
+func (t *taskController) findOwnTaskUnscoped(c *gin.Context) (models.Task, uuid.UUID, bool) {
+	var task models.Task
+
+	uuidUserID, ok := currentUserID(c)
+	if !ok {
+		return task, uuid.Nil, false
+	}
+
+	uuidTaskID, err := utils.IsUUID(c.Param("taskID"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "Error: convert taskID into UUID", err.Error())
+		return task, uuid.Nil, false
+	}
+
+	if err := t.db.Unscoped().First(&task, "task_id = ?", uuidTaskID).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "Task not found", err.Error())
+		return task, uuid.Nil, false
+	}
+
+	if task.UserID != uuidUserID {
+		utils.ErrorResponse(c, http.StatusForbidden, "Only the task owner can do this", utils.ErrUnauthorized)
+		return task, uuid.Nil, false
+	}
+
+	return task, uuidUserID, true
+}
+
+func (t *taskController) SetTaskLabels(c *gin.Context) {
+	actorID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	uuidTaskID, err := utils.IsUUID(c.Param("taskID"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "Error: convert taskID into UUID", err.Error())
+		return
+	}
+
+	var input struct {
+		Labels []string `json:"labels"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid input", err.Error())
+		return
+	}
+
+	task, ok := findVisibleTask(c, t.db.Preload("Labels"), actorID, uuidTaskID)
+	if !ok {
+		return
+	}
+
+	if !checkIfMatch(c, task) {
+		return
+	}
+
+	before := task
+	err = t.db.Transaction(func(tx *gorm.DB) error {
+		return replaceTaskLabelsTx(tx, actorID, &task, input.Labels)
+	})
+	if errors.Is(err, models.ErrVersionConflict) {
+		respondTaskWriteError(c, "Error updating task labels", err)
+		return
+	}
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Error updating task labels", err.Error())
+		return
+	}
+	events.Publish(events.NewTaskChanged(actorID, &before, &task)...)
+
+	c.Header("ETag", taskETag(task))
+	utils.SuccessResponse(c, http.StatusOK, "Task labels updated successfully", task)
+}
+
+// selectTaskFields projects tasks onto the requested JSON fields. taskID is
+// always included so clients can address the result.
+func selectTaskFields(tasks []models.Task, fields []string) ([]map[string]any, error) {
+	projected := make([]map[string]any, 0, len(tasks))
+	for _, task := range tasks {
+		raw, err := json.Marshal(task)
+		if err != nil {
+			return nil, err
+		}
+		var full map[string]any
+		if err := json.Unmarshal(raw, &full); err != nil {
+			return nil, err
+		}
+
+		item := map[string]any{"taskID": full["taskID"]}
+		for _, field := range fields {
+			if field == "labels" && full[field] == nil {
+				item[field] = []models.Label{}
+				continue
+			}
+			item[field] = full[field]
+		}
+		projected = append(projected, item)
+	}
+	return projected, nil
+}
+
+func taskETag(task models.Task) string {
+	return utils.ETag(task.TaskID.String(), task.Version)
+}
+
+// checkIfMatch enforces the If-Match precondition on a write to task. The
+// header is optional unless REQUIRE_IF_MATCH is set. It writes the error
+// response and returns false when the write must not go ahead.
+func checkIfMatch(c *gin.Context, task models.Task) bool {
+	match := c.GetHeader("If-Match")
+	if match == "" {
+		if config.GetConfig().RequireIfMatch {
+			utils.ErrorResponse(c, http.StatusPreconditionRequired, "If-Match header is required", "send the task's current ETag in If-Match")
+			return false
+		}
+		return true
+	}
+
+	etag := taskETag(task)
+	if !utils.ETagMatches(match, etag, false) {
+		c.Header("ETag", etag)
+		utils.ErrorResponse(c, http.StatusPreconditionFailed, "Task was modified by someone else", models.ErrVersionConflict.Error())
+		return false
+	}
+	return true
+}
+
+// respondTaskWriteError maps a lost update race to 412 and anything else to 500.
+func respondTaskWriteError(c *gin.Context, message string, err error) {
+	if errors.Is(err, models.ErrVersionConflict) {
+		utils.ErrorResponse(c, http.StatusPreconditionFailed, "Task was modified by someone else", err.Error())
+		return
+	}
+	utils.ErrorResponse(c, http.StatusInternalServerError, message, err.Error())
+}
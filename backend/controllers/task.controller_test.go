@@ -0,0 +1,47 @@
+package controllers
+
+import (
+	"ai-task-manager/database/dbtest"
+	"net/http"
+	"testing"
+
+	"github.com/gofrs/uuid"
+)
+
+func TestPatchTaskByAStrangerIsNotFound(t *testing.T) {
+	owner, stranger := uuid.Must(uuid.NewV4()), uuid.Must(uuid.NewV4())
+	task := testTask(owner, uuid.Nil)
+	db, fake := dbtest.New(t)
+	onTask(fake, task)
+
+	recorder := serveAs(stranger, "/update-task/:taskID", http.MethodPatch, "/update-task/"+task.TaskID.String(),
+		`{"title":"Mine now"}`, NewTaskController(db).PatchTask,
+		"Content-Type", "application/merge-patch+json", "If-Match", taskETag(task))
+	expectStatus(t, recorder, http.StatusNotFound)
+	expectNoWrites(t, fake)
+}
+
+func TestPatchTaskReassignmentIsOwnerOnly(t *testing.T) {
+	owner, assignee, other := uuid.Must(uuid.NewV4()), uuid.Must(uuid.NewV4()), uuid.Must(uuid.NewV4())
+	task := testTask(owner, assignee)
+	db, fake := dbtest.New(t)
+	onTask(fake, task)
+
+	recorder := serveAs(assignee, "/update-task/:taskID", http.MethodPatch, "/update-task/"+task.TaskID.String(),
+		`{"assignedTo":"`+other.String()+`"}`, NewTaskController(db).PatchTask,
+		"Content-Type", "application/merge-patch+json", "If-Match", taskETag(task))
+	expectStatus(t, recorder, http.StatusForbidden)
+	expectNoWrites(t, fake)
+}
+
+func TestSetTaskLabelsByAStrangerIsNotFound(t *testing.T) {
+	owner, stranger := uuid.Must(uuid.NewV4()), uuid.Must(uuid.NewV4())
+	task := testTask(owner, uuid.Nil)
+	db, fake := dbtest.New(t)
+	onTask(fake, task)
+
+	recorder := serveAs(stranger, "/set-task-labels/:taskID", http.MethodPut, "/set-task-labels/"+task.TaskID.String(),
+		`{"labels":["urgent"]}`, NewTaskController(db).SetTaskLabels, "If-Match", taskETag(task))
+	expectStatus(t, recorder, http.StatusNotFound)
+	expectNoWrites(t, fake)
+}
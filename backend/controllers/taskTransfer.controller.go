@@ -0,0 +1,532 @@
+package controllers
+
+import (
+	"ai-task-manager/config"
+	"ai-task-manager/embeddings"
+	"ai-task-manager/events"
+	"ai-task-manager/models"
+	"ai-task-manager/repository"
+	"ai-task-manager/taskio"
+	"ai-task-manager/utils"
+	"ai-task-manager/validations"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gofrs/uuid"
+	"gorm.io/gorm"
+)
+
+// maxImportErrors caps the row errors reported for one import.
+const maxImportErrors = 1000
+
+type importRowError struct {
+	Line     int    `json:"line,omitempty"`
+	SourceID string `json:"sourceID,omitempty"`
+	Error    string `json:"error"`
+}
+
+type importReport struct {
+	Format          string                 `json:"format,omitempty"`
+	Source          string                 `json:"source,omitempty"`
+	DryRun          bool                   `json:"dryRun"`
+	Rows            int                    `json:"rows"`
+	Imported        int                    `json:"imported"`
+	ProjectsCreated int                    `json:"projectsCreated,omitempty"`
+	Failed          int                    `json:"failed"`
+	Errors          []importRowError       `json:"errors"`
+	ErrorsTruncated bool                   `json:"errorsTruncated,omitempty"`
+	Unmapped        []taskio.UnmappedField `json:"unmapped,omitempty"`
+}
+
+func (r *importReport) fail(line int, sourceID string, err error) {
+	r.Failed++
+	if len(r.Errors) >= maxImportErrors {
+		r.ErrorsTruncated = true
+		return
+	}
+	r.Errors = append(r.Errors, importRowError{Line: line, SourceID: sourceID, Error: err.Error()})
+}
+
+// ExportTasks streams the caller's tasks as CSV, JSON Lines, a Markdown
+// checklist or iCalendar to-dos (?format=). It accepts the same filters and
+// sort as GetAllTasks.
+func (t *taskController) ExportTasks(c *gin.Context) {
+	uuidUserID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	format, err := taskio.ParseFormat(c.DefaultQuery("format", taskio.FormatCSV))
+	if err != nil {
+		utils.FieldErrorsResponse(c, http.StatusBadRequest, "Invalid query parameters", []validations.FieldError{
+			{Field: "format", Message: err.Error()},
+		})
+		return
+	}
+	query, fieldErrors := validations.ParseTaskQuery(c.Request.URL.Query(), uuidUserID)
+	if len(fieldErrors) > 0 {
+		utils.FieldErrorsResponse(c, http.StatusBadRequest, "Invalid query parameters", fieldErrors)
+		return
+	}
+	query.OwnerID = &uuidUserID
+
+	fileName := fmt.Sprintf("tasks-%s.%s", time.Now().UTC().Format("20060102"), taskio.Extension(format))
+	c.Header("Content-Type", taskio.ContentType(format))
+	c.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": fileName}))
+	c.Status(http.StatusOK)
+
+	// The status line is already sent, so a failure part-way can only be
+	// logged; the client sees a truncated file.
+	writer, err := taskio.NewWriter(format, c.Writer)
+	if err == nil {
+		err = repository.EachTask(c.Request.Context(), t.tasks, query, writer.Write)
+	}
+	if err == nil {
+		err = writer.Close()
+	}
+	if err != nil {
+		log.Printf("Error exporting tasks for user %s: %v", uuidUserID, err)
+	}
+}
+
+// ImportTasks creates tasks from a CSV, JSON Lines, Markdown checklist or
+// iCalendar request body. The format comes from ?format= or the
+// Content-Type; only the VTODO items of a calendar are imported. The file is
+// read as a stream and imported in one transaction: if any row fails
+// validation nothing is created and every row error is reported. With
+// ?dryRun=true rows are only validated.
+func (t *taskController) ImportTasks(c *gin.Context) {
+	uuidUserID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	var fieldErrors []validations.FieldError
+	format := c.Query("format")
+	if format != "" {
+		parsed, err := taskio.ParseFormat(format)
+		if err != nil {
+			fieldErrors = append(fieldErrors, validations.FieldError{Field: "format", Message: err.Error()})
+		}
+		format = parsed
+	} else if detected, ok := taskio.FormatFromContentType(c.ContentType()); ok {
+		format = detected
+	} else {
+		fieldErrors = append(fieldErrors, validations.FieldError{Field: "format", Message: "is required when the Content-Type does not identify the format"})
+	}
+	dryRun := false
+	if value := c.Query("dryRun"); value != "" {
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			fieldErrors = append(fieldErrors, validations.FieldError{Field: "dryRun", Message: "must be true or false"})
+		}
+		dryRun = parsed
+	}
+	var projectID *uuid.UUID
+	if value := c.Query("project"); value != "" {
+		parsed, err := uuid.FromString(value)
+		if err != nil {
+			fieldErrors = append(fieldErrors, validations.FieldError{Field: "project", Message: "must be a UUID"})
+		}
+		projectID = &parsed
+	}
+	if len(fieldErrors) > 0 {
+		utils.FieldErrorsResponse(c, http.StatusBadRequest, "Invalid query parameters", fieldErrors)
+		return
+	}
+	if err := ensureProjectOwner(t.db, uuidUserID, projectID); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid project", err.Error())
+		return
+	}
+
+	body := http.MaxBytesReader(c.Writer, c.Request.Body, config.GetConfig().ImportMaxSize)
+	reader, err := taskio.NewReader(format, body)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid format", err.Error())
+		return
+	}
+
+	report := &importReport{Format: format, DryRun: dryRun, Errors: []importRowError{}}
+	importer := newTaskImporter(uuidUserID, projectID)
+	t.runImport(c, report, importer, reader.Read)
+}
+
+// MigrateTasks imports an export file from Todoist, Trello or Jira
+// (?source=), sent as the raw request body or as the "file" field of a
+// multipart form. Projects, labels, checklists, comments, due dates and
+// statuses are mapped onto ours; projects are matched by name and created
+// when missing, unless ?project= puts every task in one project. The report
+// lists the source fields that could not be mapped. Like ImportTasks it is
+// all-or-nothing and supports ?dryRun=true.
+func (t *taskController) MigrateTasks(c *gin.Context) {
+	uuidUserID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	var fieldErrors []validations.FieldError
+	source := strings.ToLower(strings.TrimSpace(c.Query("source")))
+	switch source {
+	case taskio.SourceTodoist, taskio.SourceTrello, taskio.SourceJira:
+	case "":
+		fieldErrors = append(fieldErrors, validations.FieldError{Field: "source", Message: "is required"})
+	default:
+		fieldErrors = append(fieldErrors, validations.FieldError{Field: "source", Message: "must be one of todoist, trello, jira"})
+	}
+	dryRun := false
+	if value := c.Query("dryRun"); value != "" {
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			fieldErrors = append(fieldErrors, validations.FieldError{Field: "dryRun", Message: "must be true or false"})
+		}
+		dryRun = parsed
+	}
+	var projectID *uuid.UUID
+	if value := c.Query("project"); value != "" {
+		parsed, err := uuid.FromString(value)
+		if err != nil {
+			fieldErrors = append(fieldErrors, validations.FieldError{Field: "project", Message: "must be a UUID"})
+		}
+		projectID = &parsed
+	}
+	if len(fieldErrors) > 0 {
+		utils.FieldErrorsResponse(c, http.StatusBadRequest, "Invalid query parameters", fieldErrors)
+		return
+	}
+	if err := ensureProjectOwner(t.db, uuidUserID, projectID); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid project", err.Error())
+		return
+	}
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, config.GetConfig().ImportMaxSize)
+	file, fileName, err := migrationFile(c)
+	if err != nil {
+		respondImportReadError(c, err)
+		return
+	}
+	// Exports such as Todoist's per-project CSV don't name their project;
+	// the file name does.
+	defaultProject := c.Query("projectName")
+	if defaultProject == "" && fileName != "" {
+		defaultProject = strings.TrimSuffix(filepath.Base(fileName), filepath.Ext(fileName))
+	}
+
+	migration, err := taskio.ParseMigration(source, file, strings.TrimSpace(defaultProject))
+	if err != nil {
+		respondImportReadError(c, err)
+		return
+	}
+
+	report := &importReport{Source: source, DryRun: dryRun, Errors: []importRowError{}, Unmapped: migration.Unmapped}
+	importer := newTaskImporter(uuidUserID, projectID)
+	next := 0
+	t.runImport(c, report, importer, func() (*taskio.Record, error) {
+		if next == len(migration.Records) {
+			return nil, io.EOF
+		}
+		next++
+		return &migration.Records[next-1], nil
+	})
+}
+
+// migrationFile returns the uploaded file: the "file" part of a multipart
+// form, or otherwise the request body.
+func migrationFile(c *gin.Context) (io.Reader, string, error) {
+	if !strings.HasPrefix(c.ContentType(), "multipart/") {
+		return c.Request.Body, "", nil
+	}
+	form, err := c.Request.MultipartReader()
+	if err != nil {
+		return nil, "", err
+	}
+	for {
+		part, err := form.NextPart()
+		if err == io.EOF {
+			return nil, "", errors.New(`multipart form has no "file" field`)
+		}
+		if err != nil {
+			return nil, "", err
+		}
+		if part.FormName() == "file" {
+			return part, part.FileName(), nil
+		}
+	}
+}
+
+func respondImportReadError(c *gin.Context, err error) {
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		utils.ErrorResponse(c, http.StatusRequestEntityTooLarge, "Import file too large", fmt.Sprintf("maximum size is %d bytes", maxBytesErr.Limit))
+		return
+	}
+	utils.ErrorResponse(c, http.StatusBadRequest, "Error reading import file", err.Error())
+}
+
+// runImport validates and creates the records returned by next in one
+// transaction and writes the report. Nothing is saved if any record fails.
+func (t *taskController) runImport(c *gin.Context, report *importReport, importer *taskImporter, next func() (*taskio.Record, error)) {
+	tx := t.db.Begin()
+	if tx.Error != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Error importing tasks", tx.Error.Error())
+		return
+	}
+	defer tx.Rollback()
+
+	for {
+		record, err := next()
+		if err == io.EOF {
+			break
+		}
+		var rowErr *taskio.RowError
+		if errors.As(err, &rowErr) {
+			report.Rows++
+			report.fail(rowErr.Line, "", rowErr.Err)
+			continue
+		}
+		if err != nil {
+			respondImportReadError(c, err)
+			return
+		}
+
+		report.Rows++
+		if err := importer.validate(record); err != nil {
+			report.fail(record.Line, record.SourceID, err)
+			continue
+		}
+		// Once a row has failed the import will be rolled back, so the
+		// remaining rows are only validated.
+		if report.DryRun || report.Failed > 0 {
+			continue
+		}
+		if err := importer.create(tx, record); err != nil {
+			utils.ErrorResponse(c, http.StatusInternalServerError, "Error importing tasks", fmt.Sprintf("%s: %v", recordPosition(record), err))
+			return
+		}
+	}
+
+	if report.DryRun {
+		utils.SuccessResponse(c, http.StatusOK, "Import validated, nothing was saved", report)
+		return
+	}
+	if report.Failed > 0 {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"success": false,
+			"message": "Import failed validation, nothing was saved",
+			"error":   fmt.Sprintf("%d of %d rows are invalid", report.Failed, report.Rows),
+			"data":    report,
+		})
+		return
+	}
+	if err := tx.Commit().Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Error importing tasks", err.Error())
+		return
+	}
+
+	report.Imported = len(importer.created)
+	report.ProjectsCreated = importer.projectsCreated
+	if embeddings.Default != nil {
+		for _, taskID := range importer.created {
+			embeddings.Default.Enqueue(taskID)
+		}
+	}
+	if report.Imported > 0 {
+		events.Publish(events.NewTasksImported(importer.userID, report.Imported, report.ProjectsCreated))
+	}
+
+	utils.SuccessResponse(c, http.StatusCreated, fmt.Sprintf("%d tasks imported successfully", report.Imported), report)
+}
+
+func recordPosition(record *taskio.Record) string {
+	if record.Line > 0 {
+		return fmt.Sprintf("line %d", record.Line)
+	}
+	return fmt.Sprintf("record %s", record.SourceID)
+}
+
+// validateImportRecord checks the task fields of a record, defaulting the
+// status and trimming label names.
+func validateImportRecord(record *taskio.Record) error {
+	if record.Status == "" {
+		record.Status = "pending"
+	}
+	if err := validations.ValidateTask(validations.Task{
+		Title:       record.Title,
+		Description: record.Description,
+		Status:      record.Status,
+		Priority:    record.Priority,
+	}); err != nil {
+		return err
+	}
+	for n, label := range record.Labels {
+		if err := validations.ValidateLabelName(label); err != nil {
+			return err
+		}
+		record.Labels[n] = strings.TrimSpace(label)
+	}
+	return nil
+}
+
+func (i *taskImporter) validate(record *taskio.Record) error {
+	if err := validateImportRecord(record); err != nil {
+		return err
+	}
+	if record.Project != "" && i.projectID == nil {
+		if err := validations.ValidateProject(validations.Project{Name: record.Project}); err != nil {
+			return err
+		}
+	}
+	for _, comment := range record.Comments {
+		if err := validations.ValidateComment(validations.Comment{Body: i.commentBody(comment)}); err != nil {
+			return fmt.Errorf("comment: %w", err)
+		}
+	}
+	return nil
+}
+
+// taskImporter creates imported tasks, caching the labels and projects it
+// has resolved. A fixed projectID overrides the project names of records.
+type taskImporter struct {
+	userID          uuid.UUID
+	projectID       *uuid.UUID
+	labels          map[string]models.Label
+	projects        map[string]uuid.UUID
+	projectsCreated int
+	created         []uuid.UUID
+}
+
+func newTaskImporter(userID uuid.UUID, projectID *uuid.UUID) *taskImporter {
+	return &taskImporter{
+		userID:    userID,
+		projectID: projectID,
+		labels:    make(map[string]models.Label),
+		projects:  make(map[string]uuid.UUID),
+	}
+}
+
+func (i *taskImporter) create(tx *gorm.DB, record *taskio.Record) error {
+	projectID := i.projectID
+	if projectID == nil && record.Project != "" {
+		id, err := i.resolveProject(tx, record.Project)
+		if err != nil {
+			return err
+		}
+		projectID = &id
+	}
+
+	task := models.Task{
+		UserID:      i.userID,
+		ProjectID:   projectID,
+		Title:       record.Title,
+		Description: record.Description,
+		Status:      record.Status,
+		DueDate:     record.DueDate,
+		Priority:    record.Priority,
+	}
+	if err := tx.Omit("Labels").Create(&task).Error; err != nil {
+		return err
+	}
+
+	if len(record.Labels) > 0 {
+		labels, err := i.resolveLabels(tx, record.Labels)
+		if err != nil {
+			return err
+		}
+		if err := tx.Model(&task).Association("Labels").Append(labels); err != nil {
+			return err
+		}
+	}
+
+	if err := models.RecordTaskEvents(tx, models.TaskEvent{
+		TaskID:  task.TaskID,
+		ActorID: i.userID,
+		Action:  models.TaskActionCreate,
+	}); err != nil {
+		return err
+	}
+
+	for _, source := range record.Comments {
+		comment := models.Comment{
+			TaskID:    task.TaskID,
+			UserID:    i.userID,
+			Body:      i.commentBody(source),
+			CreatedAt: source.CreatedAt,
+		}
+		if err := tx.Create(&comment).Error; err != nil {
+			return err
+		}
+	}
+	i.created = append(i.created, task.TaskID)
+	return nil
+}
+
+// commentBody credits the original author of a migrated comment, since the
+// comment is stored under the importing user.
+func (i *taskImporter) commentBody(comment taskio.Comment) string {
+	body := strings.TrimSpace(comment.Body)
+	if comment.Author == "" {
+		return body
+	}
+	return fmt.Sprintf("*Originally posted by %s*\n\n%s", comment.Author, body)
+}
+
+// resolveProject returns the caller's project with the given name, creating
+// it when there is none.
+func (i *taskImporter) resolveProject(tx *gorm.DB, name string) (uuid.UUID, error) {
+	name = strings.TrimSpace(name)
+	if id, ok := i.projects[name]; ok {
+		return id, nil
+	}
+
+	var project models.Project
+	err := tx.Where("user_id = ? AND name = ?", i.userID, name).Order("created_at").First(&project).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		project = models.Project{UserID: i.userID, Name: name}
+		if err = tx.Create(&project).Error; err == nil {
+			err = models.RecordAudit(tx, i.userID, models.AuditEntityProject, project.ProjectID, "create", nil)
+			i.projectsCreated++
+		}
+	}
+	if err != nil {
+		return uuid.Nil, err
+	}
+	i.projects[name] = project.ProjectID
+	return project.ProjectID, nil
+}
+
+func (i *taskImporter) resolveLabels(tx *gorm.DB, names []string) ([]models.Label, error) {
+	var missing []string
+	for _, name := range names {
+		if _, ok := i.labels[name]; !ok {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		found, err := models.FindOrCreateLabels(tx, i.userID, missing)
+		if err != nil {
+			return nil, err
+		}
+		for _, label := range found {
+			i.labels[label.Name] = label
+		}
+	}
+
+	labels := make([]models.Label, 0, len(names))
+	seen := make(map[string]bool, len(names))
+	for _, name := range names {
+		label, ok := i.labels[name]
+		if ok && !seen[name] {
+			seen[name] = true
+			labels = append(labels, label)
+		}
+	}
+	return labels, nil
+}
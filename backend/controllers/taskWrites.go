@@ -0,0 +1,83 @@
+package controllers
+
+import (
+	"ai-task-manager/embeddings"
+	"ai-task-manager/models"
+
+	"github.com/gofrs/uuid"
+	"gorm.io/gorm"
+)
+
+// The functions below are the single-task write paths shared by the REST
+// handlers and CalDAV, so every client leaves the same history, audit log
+// entries and events. They run inside the caller's transaction; events are
+// published and tasks reindexed after it commits.
+
+func createTaskTx(tx *gorm.DB, actorID uuid.UUID, task *models.Task) error {
+	if err := tx.Omit("Labels").Create(task).Error; err != nil {
+		return err
+	}
+	return models.RecordTaskEvents(tx, models.TaskEvent{
+		TaskID:  task.TaskID,
+		ActorID: actorID,
+		Action:  models.TaskActionCreate,
+	})
+}
+
+// saveTaskTx writes every field of after, provided the task is still at
+// after.Version, and records what changed since before.
+func saveTaskTx(tx *gorm.DB, actorID uuid.UUID, before, after *models.Task) error {
+	if err := models.SaveTaskVersioned(tx, after); err != nil {
+		return err
+	}
+	return models.RecordTaskEvents(tx, models.DiffTask(actorID, before, after)...)
+}
+
+func deleteTaskTx(tx *gorm.DB, actorID uuid.UUID, task *models.Task) error {
+	if err := models.DeleteTaskVersioned(tx, task); err != nil {
+		return err
+	}
+	return models.RecordTaskEvents(tx, models.TaskEvent{
+		TaskID:  task.TaskID,
+		ActorID: actorID,
+		Action:  models.TaskActionDelete,
+	})
+}
+
+// replaceTaskLabelsTx sets the task's labels by name, creating missing labels
+// for the task owner. task.Labels must hold the current labels; a change
+// bumps the task version.
+func replaceTaskLabelsTx(tx *gorm.DB, actorID uuid.UUID, task *models.Task, names []string) error {
+	previous := models.LabelNames(task.Labels)
+	labels, err := models.FindOrCreateLabels(tx, task.UserID, names)
+	if err != nil {
+		return err
+	}
+	if err := tx.Model(task).Association("Labels").Replace(labels); err != nil {
+		return err
+	}
+	task.Labels = labels
+	current := models.LabelNames(labels)
+	if current == previous {
+		return nil
+	}
+	// Labels are part of the task's representation, so they bump its version.
+	if err := models.UpdateTaskVersioned(tx, task, models.Task{}); err != nil {
+		return err
+	}
+	return models.RecordTaskEvents(tx, models.TaskEvent{
+		TaskID:   task.TaskID,
+		ActorID:  actorID,
+		Action:   models.TaskActionUpdate,
+		Field:    "labels",
+		OldValue: previous,
+		NewValue: current,
+	})
+}
+
+// reindexTask queues a new embedding when the text of the task changed.
+func reindexTask(before, after *models.Task) {
+	if embeddings.Default != nil && (before == nil || before.Title != after.Title || before.Description != after.Description) {
+		embeddings.Default.Enqueue(after.TaskID)
+	}
+}
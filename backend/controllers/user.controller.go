@@ -2,10 +2,13 @@ package controllers
 
 import (
 	"ai-task-manager/config"
+	"ai-task-manager/events"
 	"ai-task-manager/models"
 	"ai-task-manager/utils"
+	"ai-task-manager/validations"
 	"fmt"
 	"net/http"
+	"slices"
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt"
@@ -40,6 +43,7 @@ type UserController interface {
 	SignOut(c *gin.Context)
 	GetUserProfile(c *gin.Context)
 	UpdateUserProfile(c *gin.Context)
+	ChangePassword(c *gin.Context)
 	DeleteUser(c *gin.Context)
 	GetAllUsers(c *gin.Context)
 }
@@ -64,7 +68,17 @@ func (u *userController) SignUp(c *gin.Context) {
 		utils.ErrorResponse(c, http.StatusBadRequest, "User already exists", "")
 		return
 	}
-	if err := u.db.Create(&user).Error; err != nil {
+	user.Role = models.RoleUser
+	if slices.Contains(config.GetConfig().AdminEmails, user.Email) {
+		user.Role = models.RoleAdmin
+	}
+	err := u.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&user).Error; err != nil {
+			return err
+		}
+		return models.RecordAudit(tx, user.UserID, models.AuditEntityUser, user.UserID, "create", nil)
+	})
+	if err != nil {
 		utils.ErrorResponse(c, http.StatusBadRequest, "Error creating user", err.Error())
 		return
 	}
@@ -129,6 +143,13 @@ func (u *userController) GetUserProfile(c *gin.Context) {
 	utils.SuccessResponse(c, http.StatusOK, "User profile retrieved successfully", user)
 }
 
+// readOnlyUserFields cannot be changed through UpdateUserProfile. Roles are
+// only granted through ADMIN_EMAILS, never through the profile, and the
+// password is changed through ChangePassword.
+var readOnlyUserFields = []string{"userID", "role", "password", "createdAt", "updatedAt", "deletedAt"}
+
+// UpdateUserProfile applies a JSON Merge Patch or JSON Patch to the caller's
+// own profile. Validation runs on the patched profile.
 func (u *userController) UpdateUserProfile(c *gin.Context) {
 	userID := c.Param("userID")
 	uuidUserID, err := utils.IsUUID(userID)
@@ -136,26 +157,104 @@ func (u *userController) UpdateUserProfile(c *gin.Context) {
 		utils.ErrorResponse(c, http.StatusNotFound, "Error: convert userID into UUID", err.Error())
 		return
 	}
+	actorID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	if actorID != uuidUserID {
+		utils.ErrorResponse(c, http.StatusForbidden, "You can only update your own profile", utils.ErrUnauthorized)
+		return
+	}
 	var user models.User
 	if err := u.db.First(&user, "user_id = ?", uuidUserID).Error; err != nil {
 		utils.ErrorResponse(c, http.StatusNotFound, "User not found", err.Error())
 		return
 	}
 
-	var updatedData models.User
-	if err := c.ShouldBindJSON(&updatedData); err != nil {
-		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid input", err.Error())
+	var patched models.User
+	if !patchResource(c, user, &patched, readOnlyUserFields) {
+		return
+	}
+	if err := validations.ValidateUserUpdate(validations.User{
+		UserID:   patched.UserID,
+		Email:    patched.Email,
+		Username: patched.Username,
+		Password: patched.Password,
+	}, false); err != nil {
+		utils.ErrorResponse(c, http.StatusUnprocessableEntity, "Invalid user", err.Error())
 		return
 	}
 
-	if err := u.db.Model(&user).Updates(updatedData).Error; err != nil {
+	before := user
+	user.Email = patched.Email
+	user.Username = patched.Username
+	err = u.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&user).Select("email", "username").Updates(&user).Error; err != nil {
+			return err
+		}
+		return models.RecordAudit(tx, actorID, models.AuditEntityUser, user.UserID, "update", diffUser(&before, &user))
+	})
+	if err != nil {
 		utils.ErrorResponse(c, http.StatusInternalServerError, "Error updating user", err.Error())
 		return
 	}
+	events.Publish(events.NewUserUpdated(actorID, &user))
 
 	utils.SuccessResponse(c, http.StatusOK, "User profile updated successfully", user)
 }
 
+// ChangePassword sets a new password for the caller after checking their
+// current one.
+func (u *userController) ChangePassword(c *gin.Context) {
+	actorID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	var input struct {
+		CurrentPassword string `json:"currentPassword" binding:"required"`
+		NewPassword     string `json:"newPassword" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid input", err.Error())
+		return
+	}
+
+	var user models.User
+	if err := u.db.First(&user, "user_id = ?", actorID).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "User not found", err.Error())
+		return
+	}
+	if err := utils.CompareHashAndPassword(user.Password, input.CurrentPassword); err != nil {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "Current password is incorrect", "")
+		return
+	}
+	if err := validations.ValidateUserUpdate(validations.User{
+		UserID:   user.UserID,
+		Email:    user.Email,
+		Username: user.Username,
+		Password: input.NewPassword,
+	}, true); err != nil {
+		utils.ErrorResponse(c, http.StatusUnprocessableEntity, "Invalid password", err.Error())
+		return
+	}
+
+	before := user
+	user.Password = input.NewPassword
+	err := u.db.Transaction(func(tx *gorm.DB) error {
+		// BeforeSave hashes the new password.
+		if err := tx.Model(&user).Select("password").Updates(&user).Error; err != nil {
+			return err
+		}
+		return models.RecordAudit(tx, actorID, models.AuditEntityUser, user.UserID, "update", diffUser(&before, &user))
+	})
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Error changing password", err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Password changed successfully", nil)
+}
+
 func (u *userController) DeleteUser(c *gin.Context) {
 	userID := c.Param("userID")
 	uuidUserID, err := utils.IsUUID(userID)
@@ -163,7 +262,17 @@ func (u *userController) DeleteUser(c *gin.Context) {
 		utils.ErrorResponse(c, http.StatusNotFound, "Error: convert userID into UUID", err.Error())
 		return
 	}
-	if err := u.db.Delete(&models.User{}, "user_id = ?", uuidUserID).Error; err != nil {
+	actorID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	err = u.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(&models.User{}, "user_id = ?", uuidUserID).Error; err != nil {
+			return err
+		}
+		return models.RecordAudit(tx, actorID, models.AuditEntityUser, uuidUserID, "delete", nil)
+	})
+	if err != nil {
 		utils.ErrorResponse(c, http.StatusInternalServerError, "Error deleting user", err.Error())
 		return
 	}
@@ -180,3 +289,18 @@ func (u *userController) GetAllUsers(c *gin.Context) {
 
 	utils.SuccessResponse(c, http.StatusOK, "Users retrieved successfully", users)
 }
+
+func diffUser(before, after *models.User) []models.FieldChange {
+	var changes []models.FieldChange
+	if before.Email != after.Email {
+		changes = append(changes, models.FieldChange{Field: "email", From: before.Email, To: after.Email})
+	}
+	if before.Username != after.Username {
+		changes = append(changes, models.FieldChange{Field: "username", From: before.Username, To: after.Username})
+	}
+	if before.Password != after.Password {
+		// Never copy password hashes into the audit log.
+		changes = append(changes, models.FieldChange{Field: "password", From: "[redacted]", To: "[redacted]"})
+	}
+	return changes
+}
@@ -0,0 +1,323 @@
+package controllers
+
+import (
+	"ai-task-manager/models"
+	"ai-task-manager/utils"
+	"ai-task-manager/validations"
+	"ai-task-manager/webhooks"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gofrs/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	maxWebhooksPerUser         = 20
+	defaultDeliveryPageSize    = 20
+	maxDeliveryPageSize        = 100
+	webhookDeliveryStatusQuery = "status"
+)
+
+type WebhookController interface {
+	CreateWebhook(c *gin.Context)
+	GetWebhooks(c *gin.Context)
+	GetWebhook(c *gin.Context)
+	UpdateWebhook(c *gin.Context)
+	DeleteWebhook(c *gin.Context)
+	SendTestEvent(c *gin.Context)
+	GetDeliveries(c *gin.Context)
+}
+
+type webhookController struct {
+	db         *gorm.DB
+	dispatcher *webhooks.Dispatcher
+}
+
+func NewWebhookController(db *gorm.DB, dispatcher *webhooks.Dispatcher) WebhookController {
+	return &webhookController{
+		db:         db,
+		dispatcher: dispatcher,
+	}
+}
+
+// webhookInput creates or replaces a webhook. An empty secret is generated
+// on create and left unchanged on update.
+type webhookInput struct {
+	URL        string     `json:"url" binding:"required"`
+	EventTypes []string   `json:"eventTypes" binding:"required"`
+	ProjectID  *uuid.UUID `json:"projectID"`
+	Secret     string     `json:"secret"`
+	Active     *bool      `json:"active"`
+}
+
+// webhookResponse shows the secret only when it was just set.
+type webhookResponse struct {
+	models.Webhook
+	EventTypes []string `json:"eventTypes"`
+	Secret     string   `json:"secret,omitempty"`
+}
+
+func newWebhookResponse(webhook models.Webhook) webhookResponse {
+	return webhookResponse{Webhook: webhook, EventTypes: webhook.Types()}
+}
+
+// CreateWebhook subscribes a URL to the caller's events, optionally limited
+// to one of their projects. The response carries the signing secret, which
+// cannot be read again.
+func (w *webhookController) CreateWebhook(c *gin.Context) {
+	uuidUserID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	var input webhookInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid input", err.Error())
+		return
+	}
+	if !w.validateInput(c, uuidUserID, &input) {
+		return
+	}
+
+	var count int64
+	if err := w.db.Model(&models.Webhook{}).Where("user_id = ?", uuidUserID).Count(&count).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Error counting webhooks", err.Error())
+		return
+	}
+	if count >= maxWebhooksPerUser {
+		utils.ErrorResponse(c, http.StatusConflict, "Too many webhooks", "delete a webhook before creating another")
+		return
+	}
+
+	secret := input.Secret
+	if secret == "" {
+		var err error
+		if secret, err = utils.GenerateToken(); err != nil {
+			utils.ErrorResponse(c, http.StatusInternalServerError, "Error creating webhook secret", err.Error())
+			return
+		}
+	}
+	webhook := models.Webhook{
+		UserID:     uuidUserID,
+		ProjectID:  input.ProjectID,
+		URL:        input.URL,
+		EventTypes: strings.Join(input.EventTypes, ","),
+		Secret:     secret,
+		Active:     input.Active == nil || *input.Active,
+	}
+	if err := w.db.Create(&webhook).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Error creating webhook", err.Error())
+		return
+	}
+
+	response := newWebhookResponse(webhook)
+	response.Secret = secret
+	utils.SuccessResponse(c, http.StatusCreated, "Webhook created successfully", response)
+}
+
+func (w *webhookController) GetWebhooks(c *gin.Context) {
+	uuidUserID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	var hooks []models.Webhook
+	if err := w.db.Where("user_id = ?", uuidUserID).Order("created_at").Find(&hooks).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Error retrieving webhooks", err.Error())
+		return
+	}
+
+	response := make([]webhookResponse, len(hooks))
+	for i, webhook := range hooks {
+		response[i] = newWebhookResponse(webhook)
+	}
+	utils.SuccessResponse(c, http.StatusOK, "Webhooks retrieved successfully", response)
+}
+
+func (w *webhookController) GetWebhook(c *gin.Context) {
+	webhook, _, ok := w.findOwnWebhook(c)
+	if !ok {
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Webhook retrieved successfully", newWebhookResponse(webhook))
+}
+
+// UpdateWebhook replaces the webhook's settings. A new secret, if given,
+// takes effect for the next attempt, including retries of earlier events.
+func (w *webhookController) UpdateWebhook(c *gin.Context) {
+	webhook, uuidUserID, ok := w.findOwnWebhook(c)
+	if !ok {
+		return
+	}
+
+	var input webhookInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid input", err.Error())
+		return
+	}
+	if !w.validateInput(c, uuidUserID, &input) {
+		return
+	}
+
+	webhook.URL = input.URL
+	webhook.EventTypes = strings.Join(input.EventTypes, ",")
+	webhook.ProjectID = input.ProjectID
+	if input.Secret != "" {
+		webhook.Secret = input.Secret
+	}
+	if input.Active != nil {
+		webhook.Active = *input.Active
+	}
+	if err := w.db.Save(&webhook).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Error updating webhook", err.Error())
+		return
+	}
+
+	response := newWebhookResponse(webhook)
+	response.Secret = input.Secret
+	utils.SuccessResponse(c, http.StatusOK, "Webhook updated successfully", response)
+}
+
+// DeleteWebhook deletes the webhook with its delivery log, abandoning any
+// pending retries.
+func (w *webhookController) DeleteWebhook(c *gin.Context) {
+	webhook, _, ok := w.findOwnWebhook(c)
+	if !ok {
+		return
+	}
+
+	err := w.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("webhook_id = ?", webhook.WebhookID).Delete(&models.WebhookDelivery{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&webhook).Error
+	})
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Error deleting webhook", err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Webhook deleted successfully", nil)
+}
+
+// SendTestEvent posts a webhook.test event to the webhook right away, even
+// when it is inactive, and returns the logged delivery. A failed test is not
+// retried.
+func (w *webhookController) SendTestEvent(c *gin.Context) {
+	webhook, _, ok := w.findOwnWebhook(c)
+	if !ok {
+		return
+	}
+
+	delivery, err := w.dispatcher.SendTest(c.Request.Context(), &webhook)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Error sending test event", err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Test event sent", delivery)
+}
+
+// GetDeliveries pages through the webhook's delivery log, newest first,
+// optionally filtered by status (pending, succeeded or failed).
+func (w *webhookController) GetDeliveries(c *gin.Context) {
+	webhook, _, ok := w.findOwnWebhook(c)
+	if !ok {
+		return
+	}
+
+	page, limit := utils.ParsePagination(c.Query("page"), c.Query("limit"), defaultDeliveryPageSize, maxDeliveryPageSize)
+
+	query := w.db.Model(&models.WebhookDelivery{}).Where("webhook_id = ?", webhook.WebhookID)
+	if status := c.Query(webhookDeliveryStatusQuery); status != "" {
+		switch status {
+		case models.WebhookDeliveryPending, models.WebhookDeliverySucceeded, models.WebhookDeliveryFailed:
+			query = query.Where("status = ?", status)
+		default:
+			utils.FieldErrorsResponse(c, http.StatusBadRequest, "Invalid query parameters", map[string]string{
+				webhookDeliveryStatusQuery: "must be pending, succeeded or failed",
+			})
+			return
+		}
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Error counting webhook deliveries", err.Error())
+		return
+	}
+
+	var deliveries []models.WebhookDelivery
+	if err := query.Order("created_at DESC").
+		Offset((page - 1) * limit).
+		Limit(limit).
+		Find(&deliveries).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Error retrieving webhook deliveries", err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Webhook deliveries retrieved successfully", gin.H{
+		"deliveries": deliveries,
+		"page":       page,
+		"limit":      limit,
+		"total":      total,
+	})
+}
+
+// validateInput checks the webhook settings and that the project, if any,
+// belongs to userID. It writes the error response and returns false on
+// failure.
+func (w *webhookController) validateInput(c *gin.Context, userID uuid.UUID, input *webhookInput) bool {
+	input.URL = strings.TrimSpace(input.URL)
+	for i, eventType := range input.EventTypes {
+		input.EventTypes[i] = strings.TrimSpace(eventType)
+	}
+	if err := validations.ValidateWebhook(validations.Webhook{
+		URL:        input.URL,
+		EventTypes: input.EventTypes,
+		Secret:     input.Secret,
+	}, webhooks.EventTypes()); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid input", err.Error())
+		return false
+	}
+
+	if err := ensureProjectOwner(w.db, userID, input.ProjectID); err != nil {
+		if errors.Is(err, errProjectNotFound) {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Invalid input", err.Error())
+			return false
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Error checking project", err.Error())
+		return false
+	}
+	return true
+}
+
+func (w *webhookController) findOwnWebhook(c *gin.Context) (models.Webhook, uuid.UUID, bool) {
+	var webhook models.Webhook
+
+	uuidUserID, ok := currentUserID(c)
+	if !ok {
+		return webhook, uuid.Nil, false
+	}
+
+	uuidWebhookID, err := utils.IsUUID(c.Param("webhookID"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "Error: convert webhookID into UUID", err.Error())
+		return webhook, uuid.Nil, false
+	}
+
+	if err := w.db.First(&webhook, "webhook_id = ? AND user_id = ?", uuidWebhookID, uuidUserID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.ErrorResponse(c, http.StatusNotFound, "Webhook not found", err.Error())
+			return webhook, uuid.Nil, false
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Error fetching webhook", err.Error())
+		return webhook, uuid.Nil, false
+	}
+
+	return webhook, uuidUserID, true
+}
@@ -0,0 +1,107 @@
+package controllers
+
+import (
+	"ai-task-manager/models"
+	"ai-task-manager/utils"
+	"ai-task-manager/websocket"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gofrs/uuid"
+	"gorm.io/gorm"
+)
+
+type WebSocketController interface {
+	CreateTicket(c *gin.Context)
+	GetStats(c *gin.Context)
+	GetTaskViewers(c *gin.Context)
+}
+
+type webSocketController struct {
+	db      *gorm.DB
+	manager *websocket.WebSocketManager
+}
+
+func NewWebSocketController(db *gorm.DB, manager *websocket.WebSocketManager) WebSocketController {
+	return &webSocketController{db: db, manager: manager}
+}
+
+type taskViewer struct {
+	websocket.Viewer
+	Username string `json:"username"`
+}
+
+// CreateTicket issues a single-use ticket for opening a WebSocket with
+// /ws?ticket=..., for browser clients that hold their token in memory rather
+// than in the access_token cookie.
+func (w *webSocketController) CreateTicket(c *gin.Context) {
+	uuidUserID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	ticket, expiresAt, err := w.manager.IssueTicket(uuidUserID)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Error creating WebSocket ticket", err.Error())
+		return
+	}
+	utils.SuccessResponse(c, http.StatusCreated, "WebSocket ticket created successfully", gin.H{
+		"ticket":    ticket,
+		"expiresAt": expiresAt.UTC().Format(time.RFC3339),
+	})
+}
+
+// GetStats reports open connections and send queue depths, for spotting slow
+// clients.
+func (w *webSocketController) GetStats(c *gin.Context) {
+	utils.SuccessResponse(c, http.StatusOK, "WebSocket stats retrieved successfully", w.manager.Stats())
+}
+
+// GetTaskViewers lists the users currently viewing a task over a WebSocket,
+// on any instance, earliest first.
+func (w *webSocketController) GetTaskViewers(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	uuidTaskID, err := utils.IsUUID(c.Param("taskID"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "Error: convert taskID into UUID", err.Error())
+		return
+	}
+	task, ok := findVisibleTask(c, w.db, userID, uuidTaskID)
+	if !ok {
+		return
+	}
+
+	viewers, err := w.manager.Viewers(task.TaskID)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Error retrieving viewers", err.Error())
+		return
+	}
+	userIDs := make([]uuid.UUID, len(viewers))
+	for i, viewer := range viewers {
+		userIDs[i] = viewer.UserID
+	}
+	var users []models.User
+	if len(userIDs) > 0 {
+		if err := w.db.Select("user_id", "username").Where("user_id IN ?", userIDs).Find(&users).Error; err != nil {
+			utils.ErrorResponse(c, http.StatusInternalServerError, "Error retrieving viewers", err.Error())
+			return
+		}
+	}
+	usernames := make(map[uuid.UUID]string, len(users))
+	for _, user := range users {
+		usernames[user.UserID] = user.Username
+	}
+
+	result := make([]taskViewer, len(viewers))
+	for i, viewer := range viewers {
+		result[i] = taskViewer{Viewer: viewer, Username: usernames[viewer.UserID]}
+	}
+	utils.SuccessResponse(c, http.StatusOK, "Task viewers retrieved successfully", gin.H{
+		"taskID":  task.TaskID,
+		"viewers": result,
+	})
+}
@@ -0,0 +1,196 @@
+// Package dbtest opens a *gorm.DB on the postgres dialect whose statements
+// are answered by the test, so that handlers can be tested without a
+// database server. Statements nothing answers return no rows.
+package dbtest
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"regexp"
+	"sync"
+	"testing"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// Result answers a statement. RowsAffected is what an Exec reports; a query
+// reports its rows.
+type Result struct {
+	Columns      []string
+	Rows         [][]driver.Value
+	RowsAffected int64
+	Err          error
+}
+
+// Responder answers a statement given its arguments, which are passed as the
+// caller gave them, uuid.UUID values included.
+type Responder func(args []any) Result
+
+// Statement is a statement the database was sent.
+type Statement struct {
+	SQL  string
+	Args []any
+}
+
+type handler struct {
+	pattern *regexp.Regexp
+	respond Responder
+}
+
+// DB records the statements it is sent and answers them.
+type DB struct {
+	mu         sync.Mutex
+	handlers   []handler
+	statements []Statement
+}
+
+// New returns a gorm connection to a new fake database.
+func New(t testing.TB) (*gorm.DB, *DB) {
+	t.Helper()
+	fake := &DB{}
+	sqlDB := sql.OpenDB(connector{fake})
+	t.Cleanup(func() { sqlDB.Close() })
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: sqlDB}), &gorm.Config{
+		Logger: logger.Discard,
+	})
+	if err != nil {
+		t.Fatalf("opening the fake database: %v", err)
+	}
+	return db, fake
+}
+
+// On answers the statements matching pattern, a regular expression. The
+// handler registered last wins.
+func (d *DB) On(pattern string, respond Responder) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.handlers = append(d.handlers, handler{pattern: regexp.MustCompile(pattern), respond: respond})
+}
+
+// Statements returns the statements sent so far that match pattern.
+func (d *DB) Statements(pattern string) []Statement {
+	re := regexp.MustCompile(pattern)
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	var matched []Statement
+	for _, statement := range d.statements {
+		if re.MatchString(statement.SQL) {
+			matched = append(matched, statement)
+		}
+	}
+	return matched
+}
+
+func (d *DB) answer(query string, named []driver.NamedValue) Result {
+	args := make([]any, len(named))
+	for i, arg := range named {
+		args[i] = arg.Value
+	}
+	d.mu.Lock()
+	d.statements = append(d.statements, Statement{SQL: query, Args: args})
+	var respond Responder
+	for i := len(d.handlers) - 1; i >= 0; i-- {
+		if d.handlers[i].pattern.MatchString(query) {
+			respond = d.handlers[i].respond
+			break
+		}
+	}
+	d.mu.Unlock()
+
+	if respond == nil {
+		return Result{}
+	}
+	return respond(args)
+}
+
+type connector struct {
+	db *DB
+}
+
+func (c connector) Connect(context.Context) (driver.Conn, error) {
+	return conn(c), nil
+}
+
+func (c connector) Driver() driver.Driver {
+	return fakeDriver{}
+}
+
+type fakeDriver struct{}
+
+func (fakeDriver) Open(string) (driver.Conn, error) {
+	return nil, errors.New("dbtest: open through dbtest.New")
+}
+
+type conn struct {
+	db *DB
+}
+
+func (c conn) Prepare(string) (driver.Stmt, error) {
+	return nil, errors.New("dbtest: prepared statements are not supported")
+}
+
+func (c conn) Close() error {
+	return nil
+}
+
+func (c conn) Begin() (driver.Tx, error) {
+	return tx{}, nil
+}
+
+// CheckNamedValue passes arguments through unconverted.
+func (c conn) CheckNamedValue(*driver.NamedValue) error {
+	return nil
+}
+
+func (c conn) QueryContext(_ context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	result := c.db.answer(query, args)
+	if result.Err != nil {
+		return nil, result.Err
+	}
+	return &rows{columns: result.Columns, values: result.Rows}, nil
+}
+
+func (c conn) ExecContext(_ context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	result := c.db.answer(query, args)
+	if result.Err != nil {
+		return nil, result.Err
+	}
+	return driver.RowsAffected(result.RowsAffected), nil
+}
+
+type tx struct{}
+
+func (tx) Commit() error {
+	return nil
+}
+
+func (tx) Rollback() error {
+	return nil
+}
+
+type rows struct {
+	columns []string
+	values  [][]driver.Value
+}
+
+func (r *rows) Columns() []string {
+	return r.columns
+}
+
+func (r *rows) Close() error {
+	return nil
+}
+
+func (r *rows) Next(dest []driver.Value) error {
+	if len(r.values) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.values[0])
+	r.values = r.values[1:]
+	return nil
+}
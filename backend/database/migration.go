@@ -12,7 +12,7 @@ func MigrateModels(db *gorm.DB) error {
 		return errors.New("db instance is nil; ensure it is properly initialized")
 	}
 
-	if err := db.Migrator().DropTable(&models.Task{}, &models.User{}); err != nil {
+	if err := db.Migrator().DropTable(&models.WebSocketTicket{}, &models.TaskViewer{}, &models.EventLogEntry{}, &models.WebhookCursor{}, &models.WebhookDelivery{}, &models.Webhook{}, &models.CalendarObject{}, &models.CalendarToken{}, &models.IdempotencyKey{}, &models.TaskEmbedding{}, "task_labels", &models.Label{}, &models.AuditLog{}, &models.TaskEvent{}, &models.OrphanedObject{}, &models.Attachment{}, &models.Notification{}, &models.Comment{}, &models.Task{}, &models.Project{}, &models.User{}); err != nil {
 		panic("Failed to drop tables: " + err.Error())
 	}
 
@@ -20,10 +20,79 @@ func MigrateModels(db *gorm.DB) error {
 		panic("Failed to migrate User table: " + err.Error())
 	}
 
+	if err := db.AutoMigrate(&models.Project{}); err != nil {
+		panic("Failed to migrate Project table: " + err.Error())
+	}
+
+	if err := db.AutoMigrate(&models.Label{}); err != nil {
+		panic("Failed to migrate Label table: " + err.Error())
+	}
+
 	if err := db.AutoMigrate(&models.Task{}); err != nil {
 		panic("Failed to migrate Task table: " + err.Error())
 	}
 
+	if err := db.AutoMigrate(&models.Comment{}, &models.Notification{}); err != nil {
+		panic("Failed to migrate Comment and Notification tables: " + err.Error())
+	}
+
+	if err := db.AutoMigrate(&models.Attachment{}, &models.OrphanedObject{}); err != nil {
+		panic("Failed to migrate Attachment and OrphanedObject tables: " + err.Error())
+	}
+
+	if err := db.AutoMigrate(&models.TaskEvent{}, &models.AuditLog{}); err != nil {
+		panic("Failed to migrate TaskEvent and AuditLog tables: " + err.Error())
+	}
+
+	if err := db.AutoMigrate(&models.TaskEmbedding{}); err != nil {
+		panic("Failed to migrate TaskEmbedding table: " + err.Error())
+	}
+
+	if err := db.AutoMigrate(&models.IdempotencyKey{}); err != nil {
+		panic("Failed to migrate IdempotencyKey table: " + err.Error())
+	}
+
+	if err := db.AutoMigrate(&models.CalendarToken{}); err != nil {
+		panic("Failed to migrate CalendarToken table: " + err.Error())
+	}
+
+	if err := db.AutoMigrate(&models.CalendarObject{}); err != nil {
+		panic("Failed to migrate CalendarObject table: " + err.Error())
+	}
+
+	if err := db.AutoMigrate(&models.EventLogEntry{}); err != nil {
+		panic("Failed to migrate EventLogEntry table: " + err.Error())
+	}
+
+	if err := db.AutoMigrate(&models.TaskViewer{}); err != nil {
+		panic("Failed to migrate TaskViewer table: " + err.Error())
+	}
+
+	if err := db.AutoMigrate(&models.WebSocketTicket{}); err != nil {
+		panic("Failed to migrate WebSocketTicket table: " + err.Error())
+	}
+
+	if err := db.AutoMigrate(&models.Webhook{}, &models.WebhookDelivery{}, &models.WebhookCursor{}); err != nil {
+		panic("Failed to migrate Webhook tables: " + err.Error())
+	}
+
+	if err := migrateTaskSearch(db); err != nil {
+		panic("Failed to migrate task search index: " + err.Error())
+	}
+
 	println("Database migration completed successfully")
 	return nil
 }
+
+// migrateTaskSearch adds the generated full-text search column over title
+// and description (title weighted higher) and its GIN index.
+func migrateTaskSearch(db *gorm.DB) error {
+	if err := db.Exec(`ALTER TABLE "Tasks" ADD COLUMN IF NOT EXISTS search_vector tsvector
+		GENERATED ALWAYS AS (
+			setweight(to_tsvector('english', coalesce(title, '')), 'A') ||
+			setweight(to_tsvector('english', coalesce(description, '')), 'B')
+		) STORED`).Error; err != nil {
+		return err
+	}
+	return db.Exec(`CREATE INDEX IF NOT EXISTS idx_tasks_search_vector ON "Tasks" USING GIN (search_vector)`).Error
+}
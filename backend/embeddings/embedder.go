@@ -0,0 +1,52 @@
+package embeddings
+
+import (
+	"ai-task-manager/config"
+	"context"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// Embedder turns text into a fixed-size vector whose cosine similarity
+// reflects how related two texts are.
+type Embedder interface {
+	Embed(ctx context.Context, text string) ([]float32, error)
+	// Model identifies the vector space; vectors from different models must
+	// never be compared.
+	Model() string
+}
+
+func NewFromConfig(cfg *config.Config) (Embedder, error) {
+	switch cfg.EmbeddingProvider {
+	case "", "openai":
+		return NewOpenAIEmbedder(cfg.OpenAIAPIKey, cfg.EmbeddingModel), nil
+	case "hash":
+		return NewHashEmbedder(DefaultHashDimensions), nil
+	default:
+		return nil, fmt.Errorf("unknown embedding provider: %s", cfg.EmbeddingProvider)
+	}
+}
+
+// TaskText is the text embedded for a task.
+func TaskText(title, description string) string {
+	return strings.TrimSpace(title + "\n" + description)
+}
+
+// Cosine returns the cosine similarity of a and b, or 0 if their lengths
+// differ or either is all zeros.
+func Cosine(a, b []float32) float64 {
+	if len(a) != len(b) || len(a) == 0 {
+		return 0
+	}
+	var dot, normA, normB float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		normA += float64(a[i]) * float64(a[i])
+		normB += float64(b[i]) * float64(b[i])
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
@@ -0,0 +1,61 @@
+package embeddings
+
+import (
+	"ai-task-manager/validations"
+	"context"
+	"fmt"
+	"hash/fnv"
+	"math"
+)
+
+const DefaultHashDimensions = 256
+
+// hashEmbedder is a deterministic, offline embedder using the hashing trick
+// over words and word bigrams. It captures lexical rather than semantic
+// similarity, which is enough to spot near-duplicate tasks.
+type hashEmbedder struct {
+	dimensions int
+}
+
+func NewHashEmbedder(dimensions int) Embedder {
+	return &hashEmbedder{dimensions: dimensions}
+}
+
+func (h *hashEmbedder) Model() string {
+	return fmt.Sprintf("hash-%d", h.dimensions)
+}
+
+func (h *hashEmbedder) Embed(ctx context.Context, text string) ([]float32, error) {
+	vector := make([]float32, h.dimensions)
+	words := validations.SearchWords(text)
+	for i, word := range words {
+		h.add(vector, word, 1)
+		if i > 0 {
+			h.add(vector, words[i-1]+" "+word, 0.5)
+		}
+	}
+
+	var norm float64
+	for _, value := range vector {
+		norm += float64(value) * float64(value)
+	}
+	if norm > 0 {
+		scale := float32(1 / math.Sqrt(norm))
+		for i := range vector {
+			vector[i] *= scale
+		}
+	}
+	return vector, nil
+}
+
+func (h *hashEmbedder) add(vector []float32, feature string, weight float32) {
+	hasher := fnv.New64a()
+	hasher.Write([]byte(feature))
+	sum := hasher.Sum64()
+	index := int(sum % uint64(h.dimensions))
+	// The top bit of the hash picks the sign so collisions tend to cancel out.
+	if sum&(1<<63) != 0 {
+		weight = -weight
+	}
+	vector[index] += weight
+}
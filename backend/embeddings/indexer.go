@@ -0,0 +1,156 @@
+package embeddings
+
+import (
+	"ai-task-manager/models"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	indexQueueSize = 256
+	embedTimeout   = 30 * time.Second
+)
+
+// SimilarTask is a task whose embedding is close to a query vector.
+type SimilarTask struct {
+	Task  models.Task `json:"task"`
+	Score float64     `json:"score"`
+}
+
+// Indexer keeps task embeddings up to date. Tasks are embedded
+// synchronously with IndexTask or in the background with Enqueue.
+type Indexer struct {
+	db       *gorm.DB
+	embedder Embedder
+	queue    chan uuid.UUID
+}
+
+// Default is the indexer configured at startup.
+var Default *Indexer
+
+func NewIndexer(db *gorm.DB, embedder Embedder) *Indexer {
+	return &Indexer{
+		db:       db,
+		embedder: embedder,
+		queue:    make(chan uuid.UUID, indexQueueSize),
+	}
+}
+
+func (i *Indexer) Embedder() Embedder {
+	return i.embedder
+}
+
+// Enqueue schedules a task for re-embedding without blocking the caller. If
+// the queue is full the request is dropped; the next change will retry.
+func (i *Indexer) Enqueue(taskID uuid.UUID) {
+	select {
+	case i.queue <- taskID:
+	default:
+		log.Printf("Embedding queue full, skipping task %s", taskID)
+	}
+}
+
+// Run processes queued tasks until ctx is cancelled.
+func (i *Indexer) Run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case taskID := <-i.queue:
+			var task models.Task
+			if err := i.db.WithContext(ctx).First(&task, "task_id = ?", taskID).Error; err != nil {
+				continue
+			}
+			embedCtx, cancel := context.WithTimeout(ctx, embedTimeout)
+			if _, err := i.IndexTask(embedCtx, &task); err != nil {
+				log.Printf("Error embedding task %s: %v", taskID, err)
+			}
+			cancel()
+		}
+	}
+}
+
+// IndexTask embeds the task unless its stored embedding is already current
+// and returns the vector.
+func (i *Indexer) IndexTask(ctx context.Context, task *models.Task) (models.Vector, error) {
+	text := TaskText(task.Title, task.Description)
+	hash := contentHash(text)
+
+	var existing models.TaskEmbedding
+	err := i.db.WithContext(ctx).First(&existing, "task_id = ?", task.TaskID).Error
+	if err == nil && existing.ContentHash == hash && existing.Model == i.embedder.Model() {
+		return existing.Vector, nil
+	}
+
+	vector, err := i.embedder.Embed(ctx, text)
+	if err != nil {
+		return nil, err
+	}
+
+	embedding := models.TaskEmbedding{
+		TaskID:      task.TaskID,
+		Model:       i.embedder.Model(),
+		ContentHash: hash,
+		Vector:      vector,
+	}
+	if err := i.db.WithContext(ctx).Clauses(clause.OnConflict{UpdateAll: true}).Create(&embedding).Error; err != nil {
+		return nil, err
+	}
+	return embedding.Vector, nil
+}
+
+// FindSimilar compares vector against the embeddings of the tasks userID
+// owns or is assigned and returns up to limit matches scoring at least
+// threshold, best first.
+func (i *Indexer) FindSimilar(ctx context.Context, userID uuid.UUID, vector []float32, excludeTaskID uuid.UUID, threshold float64, limit int) ([]SimilarTask, error) {
+	visibleTasks := i.db.Model(&models.Task{}).Select("task_id").Scopes(models.VisibleTasks(userID))
+
+	var stored []models.TaskEmbedding
+	if err := i.db.WithContext(ctx).
+		Where("model = ? AND task_id <> ? AND task_id IN (?)", i.embedder.Model(), excludeTaskID, visibleTasks).
+		Find(&stored).Error; err != nil {
+		return nil, err
+	}
+
+	scores := make(map[uuid.UUID]float64)
+	var ids []uuid.UUID
+	for _, embedding := range stored {
+		if score := Cosine(vector, embedding.Vector); score >= threshold {
+			scores[embedding.TaskID] = score
+			ids = append(ids, embedding.TaskID)
+		}
+	}
+	if len(ids) == 0 {
+		return []SimilarTask{}, nil
+	}
+
+	var tasks []models.Task
+	if err := i.db.WithContext(ctx).Scopes(models.VisibleTasks(userID)).Where("task_id IN ?", ids).Find(&tasks).Error; err != nil {
+		return nil, err
+	}
+
+	similar := make([]SimilarTask, 0, len(tasks))
+	for _, task := range tasks {
+		similar = append(similar, SimilarTask{Task: task, Score: scores[task.TaskID]})
+	}
+	sort.Slice(similar, func(a, b int) bool {
+		return similar[a].Score > similar[b].Score
+	})
+	if len(similar) > limit {
+		similar = similar[:limit]
+	}
+	return similar, nil
+}
+
+func contentHash(text string) string {
+	sum := sha256.Sum256([]byte(text))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,41 @@
+package embeddings
+
+import (
+	"context"
+	"errors"
+
+	openai "github.com/sashabaranov/go-openai"
+)
+
+type openAIEmbedder struct {
+	client *openai.Client
+	model  openai.EmbeddingModel
+}
+
+func NewOpenAIEmbedder(apiKey, model string) Embedder {
+	if model == "" {
+		model = string(openai.SmallEmbedding3)
+	}
+	return &openAIEmbedder{
+		client: openai.NewClient(apiKey),
+		model:  openai.EmbeddingModel(model),
+	}
+}
+
+func (o *openAIEmbedder) Model() string {
+	return string(o.model)
+}
+
+func (o *openAIEmbedder) Embed(ctx context.Context, text string) ([]float32, error) {
+	resp, err := o.client.CreateEmbeddings(ctx, openai.EmbeddingRequest{
+		Input: []string{text},
+		Model: o.model,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(resp.Data) == 0 {
+		return nil, errors.New("embedding response contained no data")
+	}
+	return resp.Data[0].Embedding, nil
+}
@@ -0,0 +1,59 @@
+package events
+
+import (
+	"ai-task-manager/config"
+	"ai-task-manager/database"
+	"context"
+	"errors"
+	"fmt"
+)
+
+// Backend carries events between the instances of the application. Events
+// published on an instance are always delivered to its own subscribers
+// directly; the backend only brings in the events of the other instances.
+type Backend interface {
+	// Broadcast sends events published on this instance to the others.
+	Broadcast(events []Event) error
+	// Signal sends events signalled on this instance to the others. They are
+	// not in the log, so they travel whole.
+	Signal(events []Event) error
+	// Listen delivers the events published or signalled on other instances
+	// until ctx is cancelled.
+	Listen(ctx context.Context, deliver func(Event)) error
+}
+
+// InProcess is the backend of a single instance: there is nobody to tell.
+type InProcess struct{}
+
+func (InProcess) Broadcast([]Event) error {
+	return nil
+}
+
+func (InProcess) Signal([]Event) error {
+	return nil
+}
+
+func (InProcess) Listen(ctx context.Context, deliver func(Event)) error {
+	<-ctx.Done()
+	return nil
+}
+
+// NewBackendFromConfig returns the backend chosen by EVENT_BACKEND. The
+// postgres backend sends sequence numbers only and reads the events back from
+// l, so it needs the database log.
+func NewBackendFromConfig(cfg *config.Config, l Log) (Backend, error) {
+	switch cfg.EventBackend {
+	case "", "inprocess":
+		return InProcess{}, nil
+	case "postgres":
+		if database.PGXPool == nil {
+			return nil, errors.New("the postgres event backend needs a database connection")
+		}
+		if l == nil {
+			return nil, errors.New("the postgres event backend needs an event log")
+		}
+		return NewPostgresBackend(database.PGXPool, cfg.EventChannel, l), nil
+	default:
+		return nil, fmt.Errorf("unknown event backend: %s", cfg.EventBackend)
+	}
+}
@@ -0,0 +1,243 @@
+// Package events defines the domain events the application publishes when
+// tasks, comments and users change, and the bus that carries them to
+// subscribers such as the WebSocket manager.
+package events
+
+import (
+	"ai-task-manager/models"
+	"context"
+	"log"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+// Version is the envelope version. It changes only when the envelope or a
+// payload changes incompatibly.
+const Version = 1
+
+// Event is the envelope every event is delivered in. Seq is set by the
+// database once the event has been logged; clients pass the highest one they
+// saw to catch up after reconnecting. Events published concurrently may be
+// delivered out of sequence order.
+type Event struct {
+	Version    int        `json:"version"`
+	Seq        int64      `json:"seq,omitempty"`
+	ID         uuid.UUID  `json:"id"`
+	Type       string     `json:"type"`
+	OccurredAt time.Time  `json:"occurredAt"`
+	Actor      *uuid.UUID `json:"actor"`
+	Payload    any        `json:"payload"`
+
+	// Audience lists the users entitled to see the event.
+	Audience []uuid.UUID `json:"-"`
+	// Scope says what the event is about, for matching subscriptions.
+	Scope Scope `json:"-"`
+}
+
+// Scope lists the tasks, projects and assignees an event concerns. Personal
+// events, such as notifications, concern their recipient rather than a task.
+type Scope struct {
+	TaskIDs    []uuid.UUID `json:"taskIDs,omitempty"`
+	ProjectIDs []uuid.UUID `json:"projectIDs,omitempty"`
+	Assignees  []uuid.UUID `json:"assignees,omitempty"`
+	Personal   bool        `json:"personal,omitempty"`
+}
+
+// scopeOf describes events about the given versions of tasks.
+func scopeOf(tasks ...*models.Task) Scope {
+	var scope Scope
+	for _, task := range tasks {
+		scope.TaskIDs = appendUnique(scope.TaskIDs, task.TaskID)
+		if task.ProjectID != nil {
+			scope.ProjectIDs = appendUnique(scope.ProjectIDs, *task.ProjectID)
+		}
+		scope.Assignees = appendUnique(scope.Assignees, task.AssignedTo)
+	}
+	return scope
+}
+
+func appendUnique(ids []uuid.UUID, id uuid.UUID) []uuid.UUID {
+	if id == uuid.Nil || slices.Contains(ids, id) {
+		return ids
+	}
+	return append(ids, id)
+}
+
+// New wraps a payload in an envelope. A nil actor is used for changes made
+// by the system, such as the trash purger.
+func New(eventType string, actor uuid.UUID, payload any, audience ...uuid.UUID) Event {
+	event := Event{
+		Version:    Version,
+		ID:         uuid.Must(uuid.NewV4()),
+		Type:       eventType,
+		OccurredAt: time.Now().UTC(),
+		Payload:    payload,
+	}
+	if actor != uuid.Nil {
+		event.Actor = &actor
+	}
+	seen := make(map[uuid.UUID]bool, len(audience))
+	for _, userID := range audience {
+		if userID != uuid.Nil && !seen[userID] {
+			seen[userID] = true
+			event.Audience = append(event.Audience, userID)
+		}
+	}
+	return event
+}
+
+// Handler receives published events. Handlers run synchronously on the
+// publishing goroutine and must not block.
+type Handler func(Event)
+
+// Bus fans published events out to its subscribers, recording them in its
+// log first when it has one. Its backend shares them with the other
+// instances of the application. Publishers do not wait for each other, so
+// subscribers must not rely on events arriving in sequence order.
+type Bus struct {
+	mu       sync.RWMutex
+	handlers map[int]Handler
+	next     int
+	eventLog Log
+	backend  Backend
+}
+
+func NewBus() *Bus {
+	return &Bus{handlers: make(map[int]Handler), backend: InProcess{}}
+}
+
+// Default is the bus the application publishes to.
+var Default = NewBus()
+
+// UseLog makes the bus record every event in l before delivering it.
+func (b *Bus) UseLog(l Log) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.eventLog = l
+}
+
+// Log returns the log the bus records events in, or nil.
+func (b *Bus) Log() Log {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return b.eventLog
+}
+
+// UseBackend replaces the backend. Call Listen afterwards to receive the
+// events of the other instances.
+func (b *Bus) UseBackend(backend Backend) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.backend = backend
+}
+
+// Listen delivers the events published on other instances to the
+// subscribers until ctx is cancelled.
+func (b *Bus) Listen(ctx context.Context) error {
+	b.mu.RLock()
+	backend := b.backend
+	b.mu.RUnlock()
+
+	return backend.Listen(ctx, func(event Event) {
+		for _, handler := range b.currentHandlers() {
+			deliver(handler, event)
+		}
+	})
+}
+
+func (b *Bus) currentHandlers() []Handler {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	handlers := make([]Handler, 0, len(b.handlers))
+	for _, handler := range b.handlers {
+		handlers = append(handlers, handler)
+	}
+	return handlers
+}
+
+// Subscribe registers a handler and returns a function that removes it.
+func (b *Bus) Subscribe(handler Handler) func() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	id := b.next
+	b.next++
+	b.handlers[id] = handler
+	return func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		delete(b.handlers, id)
+	}
+}
+
+// Publish logs events, delivers them to every subscriber and broadcasts them
+// to the other instances. Publish after the change has been committed, so
+// subscribers never see a change that was rolled back. Events that cannot be
+// logged are still delivered locally, without a sequence.
+func (b *Bus) Publish(events ...Event) {
+	handlers := b.currentHandlers()
+	b.mu.RLock()
+	eventLog, backend := b.eventLog, b.backend
+	b.mu.RUnlock()
+
+	if eventLog != nil {
+		if err := eventLog.Append(events); err != nil {
+			log.Printf("Error logging %d events: %v", len(events), err)
+		}
+	}
+
+	for _, event := range events {
+		for _, handler := range handlers {
+			deliver(handler, event)
+		}
+	}
+
+	if err := backend.Broadcast(events); err != nil {
+		log.Printf("Error broadcasting %d events: %v", len(events), err)
+	}
+}
+
+// Signal delivers transient events, such as presence, to every subscriber
+// and to the other instances. Signals are not logged: they have no sequence,
+// are never replayed and are lost by instances that are not listening.
+func (b *Bus) Signal(events ...Event) {
+	handlers := b.currentHandlers()
+	b.mu.RLock()
+	backend := b.backend
+	b.mu.RUnlock()
+
+	for _, event := range events {
+		for _, handler := range handlers {
+			deliver(handler, event)
+		}
+	}
+
+	if err := backend.Signal(events); err != nil {
+		log.Printf("Error signalling %d events: %v", len(events), err)
+	}
+}
+
+// deliver keeps one failing subscriber from affecting the others or the
+// request that published the event.
+func deliver(handler Handler, event Event) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("Event handler panicked on %s %s: %v", event.Type, event.ID, r)
+		}
+	}()
+	handler(event)
+}
+
+// Publish publishes events on the default bus.
+func Publish(events ...Event) {
+	Default.Publish(events...)
+}
+
+// Signal signals events on the default bus.
+func Signal(events ...Event) {
+	Default.Signal(events...)
+}
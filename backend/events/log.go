@@ -0,0 +1,153 @@
+package events
+
+import (
+	"ai-task-manager/models"
+	"encoding/json"
+	"errors"
+
+	"github.com/gofrs/uuid"
+	"gorm.io/gorm"
+)
+
+// ErrResyncRequired means some of the events a client asked to replay are no
+// longer retained, so it has to refetch its state instead.
+var ErrResyncRequired = errors.New("missed events are no longer retained, resync required")
+
+// Log keeps published events in sequence order for a bounded time so that
+// clients can catch up after a disconnect.
+type Log interface {
+	// Append stores events and sets their Seq. Concurrent appends may be
+	// given sequences in any order.
+	Append(events []Event) error
+	// Since returns up to limit events after seq that userID may see, oldest
+	// first.
+	Since(userID uuid.UUID, seq int64, limit int) ([]Event, error)
+	// Fetch returns the events with the given sequence numbers that are
+	// still retained, in sequence order.
+	Fetch(seqs []int64) ([]Event, error)
+	// Bounds returns the oldest and the latest sequence retained, or zeros
+	// when nothing was ever logged.
+	Bounds() (oldest, latest int64, err error)
+}
+
+// Replay returns the events published after seq that userID may see. It
+// fails with ErrResyncRequired when some of them have been pruned, when seq
+// is ahead of the log, or when there are more than limit of them.
+func Replay(l Log, userID uuid.UUID, seq int64, limit int) ([]Event, error) {
+	if l == nil {
+		return nil, ErrResyncRequired
+	}
+	missed, err := l.Since(userID, seq, limit+1)
+	if err != nil {
+		return nil, err
+	}
+	// Bounds is read after the events so that a prune in between is noticed.
+	oldest, latest, err := l.Bounds()
+	if err != nil {
+		return nil, err
+	}
+	if seq > latest || seq < oldest-1 || len(missed) > limit {
+		return nil, ErrResyncRequired
+	}
+	return missed, nil
+}
+
+type dbLog struct {
+	db *gorm.DB
+}
+
+// NewDBLog returns a Log stored in the event_log table. Pruning it is left to
+// jobs.StartEventLogPruner.
+func NewDBLog(db *gorm.DB) Log {
+	return &dbLog{db: db}
+}
+
+func (l *dbLog) Append(events []Event) error {
+	if len(events) == 0 {
+		return nil
+	}
+	entries := make([]models.EventLogEntry, len(events))
+	for i, event := range events {
+		audience, err := json.Marshal(event.Audience)
+		if err != nil {
+			return err
+		}
+		scope, err := json.Marshal(event.Scope)
+		if err != nil {
+			return err
+		}
+		payload, err := json.Marshal(event.Payload)
+		if err != nil {
+			return err
+		}
+		entries[i] = models.EventLogEntry{
+			EventID:    event.ID,
+			Version:    event.Version,
+			Type:       event.Type,
+			ActorID:    event.Actor,
+			OccurredAt: event.OccurredAt,
+			Audience:   string(audience),
+			Scope:      string(scope),
+			Payload:    string(payload),
+		}
+	}
+	if err := l.db.Create(&entries).Error; err != nil {
+		return err
+	}
+	for i := range events {
+		events[i].Seq = entries[i].Seq
+	}
+	return nil
+}
+
+func (l *dbLog) Since(userID uuid.UUID, seq int64, limit int) ([]Event, error) {
+	var entries []models.EventLogEntry
+	if err := l.db.Where("seq > ? AND jsonb_exists(audience, ?)", seq, userID.String()).
+		Order("seq").Limit(limit).Find(&entries).Error; err != nil {
+		return nil, err
+	}
+	return FromLogEntries(entries)
+}
+
+func (l *dbLog) Fetch(seqs []int64) ([]Event, error) {
+	if len(seqs) == 0 {
+		return nil, nil
+	}
+	var entries []models.EventLogEntry
+	if err := l.db.Where("seq IN ?", seqs).Order("seq").Find(&entries).Error; err != nil {
+		return nil, err
+	}
+	return FromLogEntries(entries)
+}
+
+// FromLogEntries rebuilds logged events, for readers of the event log such
+// as the webhook dispatcher. Their payloads stay raw JSON.
+func FromLogEntries(entries []models.EventLogEntry) ([]Event, error) {
+	events := make([]Event, len(entries))
+	for i, entry := range entries {
+		event := Event{
+			Version:    entry.Version,
+			Seq:        entry.Seq,
+			ID:         entry.EventID,
+			Type:       entry.Type,
+			OccurredAt: entry.OccurredAt.UTC(),
+			Actor:      entry.ActorID,
+			Payload:    json.RawMessage(entry.Payload),
+		}
+		if err := json.Unmarshal([]byte(entry.Audience), &event.Audience); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal([]byte(entry.Scope), &event.Scope); err != nil {
+			return nil, err
+		}
+		events[i] = event
+	}
+	return events, nil
+}
+
+func (l *dbLog) Bounds() (oldest, latest int64, err error) {
+	err = l.db.Model(&models.EventLogEntry{}).
+		Select("COALESCE(MIN(seq), 0), COALESCE(MAX(seq), 0)").
+		Row().Scan(&oldest, &latest)
+	return oldest, latest, err
+}
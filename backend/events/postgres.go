@@ -0,0 +1,176 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// notifyPayloadLimit keeps notifications under Postgres' 8000 byte limit.
+const notifyPayloadLimit = 7000
+
+// PostgresBackend shares events between instances with LISTEN/NOTIFY. A
+// notification only carries the sequence numbers of the events, as
+// "<instance> <seq>,<seq>,...", and the listeners read the events back from
+// the log, so payload size never limits what an event may hold. Events that
+// could not be logged stay on the instance that published them. Signals are
+// not logged, so each one is sent whole, as "<instance> !<json>".
+type PostgresBackend struct {
+	pool     *pgxpool.Pool
+	channel  string
+	eventLog Log
+	instance string
+}
+
+func NewPostgresBackend(pool *pgxpool.Pool, channel string, l Log) *PostgresBackend {
+	return &PostgresBackend{
+		pool:     pool,
+		channel:  channel,
+		eventLog: l,
+		instance: uuid.Must(uuid.NewV4()).String(),
+	}
+}
+
+func (b *PostgresBackend) Broadcast(events []Event) error {
+	prefix := b.instance + " "
+	payload := prefix
+	flush := func() error {
+		if payload == prefix {
+			return nil
+		}
+		_, err := b.pool.Exec(context.Background(), "SELECT pg_notify($1, $2)", b.channel, payload)
+		payload = prefix
+		return err
+	}
+
+	for _, event := range events {
+		if event.Seq == 0 {
+			continue
+		}
+		seq := strconv.FormatInt(event.Seq, 10)
+		if len(payload)+len(seq)+1 > notifyPayloadLimit {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+		if payload != prefix {
+			payload += ","
+		}
+		payload += seq
+	}
+	return flush()
+}
+
+// signal is the form a signalled event travels in, keeping the fields the
+// envelope leaves out for clients.
+type signal struct {
+	Event
+	Payload  json.RawMessage `json:"payload"`
+	Audience []uuid.UUID     `json:"audience,omitempty"`
+	Scope    Scope           `json:"scope"`
+}
+
+func (b *PostgresBackend) Signal(events []Event) error {
+	for _, event := range events {
+		payload, err := json.Marshal(event.Payload)
+		if err != nil {
+			return err
+		}
+		encoded, err := json.Marshal(signal{Event: event, Payload: payload, Audience: event.Audience, Scope: event.Scope})
+		if err != nil {
+			return err
+		}
+		notification := b.instance + " !" + string(encoded)
+		if len(notification) > notifyPayloadLimit {
+			return fmt.Errorf("%s signal of %d bytes is too large to send", event.Type, len(notification))
+		}
+		if _, err := b.pool.Exec(context.Background(), "SELECT pg_notify($1, $2)", b.channel, notification); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Listen holds a connection of its own for LISTEN and reconnects with
+// backoff when it is lost. Events published while it was disconnected are
+// not delivered; clients catch up on them when they reconnect.
+func (b *PostgresBackend) Listen(ctx context.Context, deliver func(Event)) error {
+	backoff := time.Second
+	for {
+		listened, err := b.listen(ctx, deliver)
+		if ctx.Err() != nil {
+			return nil
+		}
+		if listened {
+			backoff = time.Second
+		}
+		log.Printf("Lost event channel %s, reconnecting in %s: %v", b.channel, backoff, err)
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, time.Minute)
+	}
+}
+
+// listen reports whether it got as far as listening before it failed.
+func (b *PostgresBackend) listen(ctx context.Context, deliver func(Event)) (bool, error) {
+	pooled, err := b.pool.Acquire(ctx)
+	if err != nil {
+		return false, err
+	}
+	// A listening connection must not go back to the pool.
+	conn := pooled.Hijack()
+	defer conn.Close(context.Background())
+
+	if _, err := conn.Exec(ctx, "LISTEN "+pgx.Identifier{b.channel}.Sanitize()); err != nil {
+		return false, err
+	}
+
+	for {
+		notification, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return true, err
+		}
+		instance, list, ok := strings.Cut(notification.Payload, " ")
+		if !ok || instance == b.instance {
+			continue
+		}
+		if encoded, ok := strings.CutPrefix(list, "!"); ok {
+			var s signal
+			if err := json.Unmarshal([]byte(encoded), &s); err != nil {
+				log.Printf("Error decoding a signal on %s: %v", b.channel, err)
+				continue
+			}
+			event := s.Event
+			event.Payload, event.Audience, event.Scope = s.Payload, s.Audience, s.Scope
+			deliver(event)
+			continue
+		}
+
+		var seqs []int64
+		for _, field := range strings.Split(list, ",") {
+			if seq, err := strconv.ParseInt(field, 10, 64); err == nil {
+				seqs = append(seqs, seq)
+			}
+		}
+		events, err := b.eventLog.Fetch(seqs)
+		if err != nil {
+			log.Printf("Error reading %d events from the event log: %v", len(seqs), err)
+			continue
+		}
+		for _, event := range events {
+			deliver(event)
+		}
+	}
+}
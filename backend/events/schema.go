@@ -0,0 +1,142 @@
+package events
+
+// The JSON Schemas below document the envelope and payload of each event
+// type for client authors. Keep them in step with the payload structs.
+
+const schemaDialect = "https://json-schema.org/draft/2020-12/schema"
+
+type schema = map[string]any
+
+var (
+	uuidSchema         = schema{"type": "string", "format": "uuid"}
+	nullableUUIDSchema = schema{"type": []string{"string", "null"}, "format": "uuid"}
+	dateTimeSchema     = schema{"type": "string", "format": "date-time"}
+	nullableDateTime   = schema{"type": []string{"string", "null"}, "format": "date-time"}
+	statusSchema       = schema{"type": "string", "enum": []string{"pending", "in_progress", "completed"}}
+)
+
+func object(properties schema, required ...string) schema {
+	return schema{"type": "object", "properties": properties, "required": required}
+}
+
+func ref(name string) schema {
+	return schema{"$ref": "#/$defs/" + name}
+}
+
+var definitions = schema{
+	"label": object(schema{
+		"labelID":   uuidSchema,
+		"userID":    uuidSchema,
+		"name":      schema{"type": "string"},
+		"createdAt": dateTimeSchema,
+	}, "labelID", "name"),
+	"task": object(schema{
+		"taskID":      uuidSchema,
+		"title":       schema{"type": "string"},
+		"description": schema{"type": "string"},
+		"status":      statusSchema,
+		"assignedTo":  uuidSchema,
+		"createdAt":   dateTimeSchema,
+		"updatedAt":   dateTimeSchema,
+		"deletedAt":   nullableDateTime,
+		"userID":      uuidSchema,
+		"projectID":   nullableUUIDSchema,
+		"dueDate":     nullableDateTime,
+		"priority":    schema{"type": "integer", "minimum": 0, "maximum": 9},
+		"labels":      schema{"type": "array", "items": ref("label")},
+		"version":     schema{"type": "integer", "minimum": 1},
+	}, "taskID", "title", "description", "status", "userID", "version"),
+	"comment": object(schema{
+		"commentID": uuidSchema,
+		"taskID":    uuidSchema,
+		"userID":    uuidSchema,
+		"body":      schema{"type": "string"},
+		"createdAt": dateTimeSchema,
+		"updatedAt": dateTimeSchema,
+		"deletedAt": nullableDateTime,
+	}, "commentID", "taskID", "userID", "body"),
+	"notification": object(schema{
+		"notificationID": uuidSchema,
+		"userID":         uuidSchema,
+		"actorID":        uuidSchema,
+		"type":           schema{"type": "string"},
+		"taskID":         uuidSchema,
+		"commentID":      uuidSchema,
+		"message":        schema{"type": "string"},
+		"readAt":         nullableDateTime,
+		"createdAt":      dateTimeSchema,
+	}, "notificationID", "userID", "type", "message"),
+	"user": object(schema{
+		"userID":    uuidSchema,
+		"email":     schema{"type": "string", "format": "email"},
+		"username":  schema{"type": "string"},
+		"role":      schema{"type": "string", "enum": []string{"user", "admin"}},
+		"updatedAt": dateTimeSchema,
+	}, "userID", "email", "username", "role"),
+	"fieldChange": object(schema{
+		"field": schema{"type": "string"},
+		"from":  schema{"type": "string"},
+		"to":    schema{"type": "string"},
+	}, "field", "from", "to"),
+}
+
+var payloadSchemas = map[string]schema{
+	TaskCreated: object(schema{"task": ref("task")}, "task"),
+	TaskUpdated: object(schema{
+		"task":    ref("task"),
+		"changes": schema{"type": "array", "items": ref("fieldChange"), "minItems": 1},
+	}, "task", "changes"),
+	TaskStatusChanged: object(schema{
+		"task": ref("task"),
+		"from": statusSchema,
+		"to":   statusSchema,
+	}, "task", "from", "to"),
+	TaskDeleted:  object(schema{"task": ref("task")}, "task"),
+	TaskRestored: object(schema{"task": ref("task")}, "task"),
+	TaskPurged:   object(schema{"taskID": uuidSchema}, "taskID"),
+	TasksImported: object(schema{
+		"count":           schema{"type": "integer", "minimum": 1},
+		"projectsCreated": schema{"type": "integer", "minimum": 0},
+	}, "count", "projectsCreated"),
+	TasksBulk: object(schema{
+		"results": schema{"type": "array", "minItems": 1, "items": object(schema{
+			"index": schema{"type": "integer", "minimum": 0},
+			"op":    schema{"type": "string", "enum": []string{"create", "update", "change_status", "delete", "move_project", "add_label"}},
+			"task":  ref("task"),
+		}, "index", "op", "task")},
+	}, "results"),
+	CommentAdded:        object(schema{"taskID": uuidSchema, "comment": ref("comment")}, "taskID", "comment"),
+	CommentUpdated:      object(schema{"taskID": uuidSchema, "comment": ref("comment")}, "taskID", "comment"),
+	CommentDeleted:      object(schema{"taskID": uuidSchema, "comment": ref("comment")}, "taskID", "comment"),
+	NotificationCreated: object(schema{"notification": ref("notification")}, "notification"),
+	UserUpdated:         object(schema{"user": ref("user")}, "user"),
+	PresenceJoined:      object(schema{"taskID": uuidSchema, "userID": uuidSchema}, "taskID", "userID"),
+	PresenceLeft:        object(schema{"taskID": uuidSchema, "userID": uuidSchema}, "taskID", "userID"),
+	PresenceTyping: object(schema{
+		"taskID":    uuidSchema,
+		"userID":    uuidSchema,
+		"expiresAt": dateTimeSchema,
+	}, "taskID", "userID", "expiresAt"),
+}
+
+// Schema returns the JSON Schema of the envelope for one event type.
+func Schema(eventType string) (map[string]any, bool) {
+	payload, ok := payloadSchemas[eventType]
+	if !ok {
+		return nil, false
+	}
+	envelope := object(schema{
+		"version":    schema{"const": Version},
+		"seq":        schema{"type": "integer", "minimum": 1},
+		"id":         uuidSchema,
+		"type":       schema{"const": eventType},
+		"occurredAt": dateTimeSchema,
+		"actor":      nullableUUIDSchema,
+		"payload":    payload,
+	}, "version", "id", "type", "occurredAt", "actor", "payload")
+	envelope["$schema"] = schemaDialect
+	envelope["$id"] = "urn:ai-task-manager:event:" + eventType
+	envelope["title"] = eventType
+	envelope["$defs"] = definitions
+	return envelope, true
+}
@@ -0,0 +1,84 @@
+package events
+
+import (
+	"errors"
+	"slices"
+	"strings"
+
+	"github.com/gofrs/uuid"
+)
+
+// Topics a client can subscribe to. Delivery is still limited to the event's
+// audience; topics only narrow what a client receives.
+const (
+	// TopicAll matches every event the user may see.
+	TopicAll = "all"
+	// TopicUser matches events about the user rather than a task, such as
+	// notifications and profile changes.
+	TopicUser = "user"
+	// TopicAssigned matches events about tasks assigned to the user,
+	// including tasks being assigned to or away from them.
+	TopicAssigned = "assigned"
+
+	topicTaskPrefix    = "task:"
+	topicProjectPrefix = "project:"
+)
+
+var ErrInvalidTopic = errors.New(`topic must be "all", "user", "assigned", "task:<taskID>" or "project:<projectID>"`)
+
+// Topic is a parsed subscription topic.
+type Topic struct {
+	name string
+	kind string
+	id   uuid.UUID
+}
+
+func ParseTopic(name string) (Topic, error) {
+	name = strings.TrimSpace(name)
+	switch name {
+	case TopicAll, TopicUser, TopicAssigned:
+		return Topic{name: name, kind: name}, nil
+	}
+	for _, prefix := range []string{topicTaskPrefix, topicProjectPrefix} {
+		if rest, ok := strings.CutPrefix(name, prefix); ok {
+			id, err := uuid.FromString(rest)
+			if err != nil || id == uuid.Nil {
+				return Topic{}, ErrInvalidTopic
+			}
+			return Topic{name: prefix + id.String(), kind: prefix, id: id}, nil
+		}
+	}
+	return Topic{}, ErrInvalidTopic
+}
+
+// String returns the canonical name of the topic.
+func (t Topic) String() string {
+	return t.name
+}
+
+// Matches reports whether event falls under the topic for userID.
+func (t Topic) Matches(event Event, userID uuid.UUID) bool {
+	switch t.kind {
+	case TopicAll:
+		return true
+	case TopicUser:
+		return event.Scope.Personal
+	case TopicAssigned:
+		return slices.Contains(event.Scope.Assignees, userID)
+	case topicTaskPrefix:
+		return slices.Contains(event.Scope.TaskIDs, t.id)
+	case topicProjectPrefix:
+		return slices.Contains(event.Scope.ProjectIDs, t.id)
+	}
+	return false
+}
+
+// MatchesAny reports whether event falls under any of the topics.
+func MatchesAny(topics []Topic, event Event, userID uuid.UUID) bool {
+	for _, topic := range topics {
+		if topic.Matches(event, userID) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,281 @@
+package events
+
+import (
+	"ai-task-manager/models"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+const (
+	TaskCreated       = "task.created"
+	TaskUpdated       = "task.updated"
+	TaskStatusChanged = "task.status_changed"
+	TaskDeleted       = "task.deleted"
+	TaskRestored      = "task.restored"
+	TaskPurged        = "task.purged"
+	TasksImported     = "tasks.imported"
+	TasksBulk         = "tasks.bulk"
+
+	CommentAdded   = "comment.added"
+	CommentUpdated = "comment.updated"
+	CommentDeleted = "comment.deleted"
+
+	NotificationCreated = "notification.created"
+
+	UserUpdated = "user.updated"
+
+	// Presence events are only sent over the WebSocket, to the other viewers
+	// of a task, whichever instance they are connected to. They are signalled
+	// rather than published, so they are never logged or replayed.
+	PresenceJoined = "presence.joined"
+	PresenceLeft   = "presence.left"
+	PresenceTyping = "presence.typing"
+)
+
+// Types lists every event type, in the order they are documented.
+var Types = []string{
+	TaskCreated, TaskUpdated, TaskStatusChanged, TaskDeleted, TaskRestored, TaskPurged, TasksImported, TasksBulk,
+	CommentAdded, CommentUpdated, CommentDeleted,
+	NotificationCreated,
+	UserUpdated,
+	PresenceJoined, PresenceLeft, PresenceTyping,
+}
+
+type TaskPayload struct {
+	Task *models.Task `json:"task"`
+}
+
+type TaskUpdatedPayload struct {
+	Task    *models.Task         `json:"task"`
+	Changes []models.FieldChange `json:"changes"`
+}
+
+type TaskStatusChangedPayload struct {
+	Task *models.Task `json:"task"`
+	From string       `json:"from"`
+	To   string       `json:"to"`
+}
+
+type TaskPurgedPayload struct {
+	TaskID uuid.UUID `json:"taskID"`
+}
+
+type TasksImportedPayload struct {
+	Count           int `json:"count"`
+	ProjectsCreated int `json:"projectsCreated"`
+}
+
+// TasksBulkPayload lists the successful operations of one bulk request that
+// concern the recipient. Task is the task after the operation.
+type TasksBulkPayload struct {
+	Results []BulkResult `json:"results"`
+}
+
+type BulkResult struct {
+	Index int          `json:"index"`
+	Op    string       `json:"op"`
+	Task  *models.Task `json:"task"`
+}
+
+type CommentPayload struct {
+	TaskID  uuid.UUID      `json:"taskID"`
+	Comment models.Comment `json:"comment"`
+}
+
+type NotificationPayload struct {
+	Notification models.Notification `json:"notification"`
+}
+
+type PresencePayload struct {
+	TaskID uuid.UUID `json:"taskID"`
+	UserID uuid.UUID `json:"userID"`
+}
+
+// PresenceTypingPayload says a viewer is typing a comment on the task.
+// Clients show it until ExpiresAt unless it is repeated.
+type PresenceTypingPayload struct {
+	TaskID    uuid.UUID `json:"taskID"`
+	UserID    uuid.UUID `json:"userID"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+// UserProfile is the public part of a user; events never carry the password
+// hash.
+type UserProfile struct {
+	UserID    uuid.UUID `json:"userID"`
+	Email     string    `json:"email"`
+	Username  string    `json:"username"`
+	Role      string    `json:"role"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+type UserPayload struct {
+	User UserProfile `json:"user"`
+}
+
+// TaskAudience lists the users entitled to hear about a task: its owner and
+// its assignee.
+func TaskAudience(task *models.Task) []uuid.UUID {
+	return []uuid.UUID{task.UserID, task.AssignedTo}
+}
+
+func NewTaskCreated(actor uuid.UUID, task *models.Task) Event {
+	event := New(TaskCreated, actor, TaskPayload{Task: task}, TaskAudience(task)...)
+	event.Scope = scopeOf(task)
+	return event
+}
+
+// NewTaskChanged describes the difference between two versions of a task. A
+// status change is reported as task.status_changed and any other change as
+// task.updated, so a write can produce none, one or both. Users who lost
+// access through the change, such as a previous assignee, are told too.
+func NewTaskChanged(actor uuid.UUID, before, after *models.Task) []Event {
+	audience := append(TaskAudience(before), TaskAudience(after)...)
+	scope := scopeOf(before, after)
+
+	var changes []models.FieldChange
+	for _, diff := range models.DiffTask(actor, before, after) {
+		if diff.Field != "status" {
+			changes = append(changes, models.FieldChange{Field: diff.Field, From: diff.OldValue, To: diff.NewValue})
+		}
+	}
+	if previous, current := models.LabelNames(before.Labels), models.LabelNames(after.Labels); previous != current {
+		changes = append(changes, models.FieldChange{Field: "labels", From: previous, To: current})
+	}
+
+	var events []Event
+	if before.Status != after.Status {
+		events = append(events, New(TaskStatusChanged, actor, TaskStatusChangedPayload{Task: after, From: before.Status, To: after.Status}, audience...))
+	}
+	if len(changes) > 0 {
+		events = append(events, New(TaskUpdated, actor, TaskUpdatedPayload{Task: after, Changes: changes}, audience...))
+	}
+	for i := range events {
+		events[i].Scope = scope
+	}
+	return events
+}
+
+func NewTaskDeleted(actor uuid.UUID, task *models.Task) Event {
+	event := New(TaskDeleted, actor, TaskPayload{Task: task}, TaskAudience(task)...)
+	event.Scope = scopeOf(task)
+	return event
+}
+
+func NewTaskRestored(actor uuid.UUID, task *models.Task) Event {
+	event := New(TaskRestored, actor, TaskPayload{Task: task}, TaskAudience(task)...)
+	event.Scope = scopeOf(task)
+	return event
+}
+
+func NewTaskPurged(actor uuid.UUID, task *models.Task) Event {
+	event := New(TaskPurged, actor, TaskPurgedPayload{TaskID: task.TaskID}, TaskAudience(task)...)
+	event.Scope = scopeOf(task)
+	return event
+}
+
+func NewTasksImported(actor uuid.UUID, count, projectsCreated int) Event {
+	event := New(TasksImported, actor, TasksImportedPayload{Count: count, ProjectsCreated: projectsCreated}, actor)
+	event.Scope.Personal = true
+	return event
+}
+
+// BulkChange is one successful operation of a bulk request. Before is nil
+// for a created task.
+type BulkChange struct {
+	Index  int
+	Op     string
+	Before *models.Task
+	After  *models.Task
+}
+
+// NewTasksBulk aggregates the changes of one bulk request into a single
+// tasks.bulk event per affected user, carrying only the changes to tasks
+// that user owns or is assigned, before or after the change.
+func NewTasksBulk(actor uuid.UUID, changes []BulkChange) []Event {
+	var users []uuid.UUID
+	concerned := make(map[uuid.UUID][]int)
+	for i, change := range changes {
+		audience := TaskAudience(change.After)
+		if change.Before != nil {
+			audience = append(audience, TaskAudience(change.Before)...)
+		}
+		for _, user := range audience {
+			if user == uuid.Nil || slices.Contains(concerned[user], i) {
+				continue
+			}
+			if len(concerned[user]) == 0 {
+				users = append(users, user)
+			}
+			concerned[user] = append(concerned[user], i)
+		}
+	}
+
+	events := make([]Event, 0, len(users))
+	for _, user := range users {
+		var payload TasksBulkPayload
+		var tasks []*models.Task
+		for _, i := range concerned[user] {
+			change := changes[i]
+			payload.Results = append(payload.Results, BulkResult{Index: change.Index, Op: change.Op, Task: change.After})
+			if change.Before != nil {
+				tasks = append(tasks, change.Before)
+			}
+			tasks = append(tasks, change.After)
+		}
+		event := New(TasksBulk, actor, payload, user)
+		event.Scope = scopeOf(tasks...)
+		events = append(events, event)
+	}
+	return events
+}
+
+// NewCommentEvent reports a comment change to the task's audience and the
+// comment's author.
+func NewCommentEvent(eventType string, actor uuid.UUID, comment models.Comment, task *models.Task) Event {
+	audience := append(TaskAudience(task), comment.UserID)
+	event := New(eventType, actor, CommentPayload{TaskID: comment.TaskID, Comment: comment}, audience...)
+	event.Scope = scopeOf(task)
+	return event
+}
+
+func NewNotificationCreated(notification models.Notification) Event {
+	event := New(NotificationCreated, notification.ActorID, NotificationPayload{Notification: notification}, notification.UserID)
+	event.Scope = Scope{TaskIDs: appendUnique(nil, notification.TaskID), Personal: true}
+	return event
+}
+
+func NewUserUpdated(actor uuid.UUID, user *models.User) Event {
+	profile := UserProfile{
+		UserID:    user.UserID,
+		Email:     user.Email,
+		Username:  user.Username,
+		Role:      user.Role,
+		UpdatedAt: user.UpdatedAt,
+	}
+	event := New(UserUpdated, actor, UserPayload{User: profile}, user.UserID)
+	event.Scope.Personal = true
+	return event
+}
+
+// NewPresence has no audience: it goes to whoever views the task, and
+// viewing a task is checked when it starts.
+func NewPresence(eventType string, taskID, userID uuid.UUID) Event {
+	event := New(eventType, userID, PresencePayload{TaskID: taskID, UserID: userID})
+	event.Scope.TaskIDs = []uuid.UUID{taskID}
+	return event
+}
+
+func NewPresenceTyping(taskID, userID uuid.UUID, expiresAt time.Time) Event {
+	event := New(PresenceTyping, userID, PresenceTypingPayload{TaskID: taskID, UserID: userID, ExpiresAt: expiresAt.UTC()})
+	event.Scope.TaskIDs = []uuid.UUID{taskID}
+	return event
+}
+
+// IsPresence reports whether the event is a presence event.
+func IsPresence(event Event) bool {
+	return strings.HasPrefix(event.Type, "presence.")
+}
@@ -0,0 +1,49 @@
+package jobs
+
+import (
+	"ai-task-manager/models"
+	"context"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// StartEventLogPruner bounds the event log every interval until ctx is
+// cancelled, keeping events for at most retention and at most maxEntries of
+// them.
+func StartEventLogPruner(ctx context.Context, db *gorm.DB, retention time.Duration, maxEntries int64, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := PruneEventLog(db, time.Now().Add(-retention), maxEntries); err != nil {
+			log.Printf("Error pruning event log: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// PruneEventLog deletes events logged before cutoff and those more than
+// maxEntries behind the latest. The latest event is always kept so that the
+// log still knows the current sequence.
+func PruneEventLog(db *gorm.DB, cutoff time.Time, maxEntries int64) error {
+	var latest int64
+	if err := db.Model(&models.EventLogEntry{}).Select("COALESCE(MAX(seq), 0)").Row().Scan(&latest); err != nil {
+		return err
+	}
+	result := db.Where("seq < ? AND (created_at < ? OR seq <= ?)", latest, cutoff, latest-maxEntries).
+		Delete(&models.EventLogEntry{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected > 0 {
+		log.Printf("Pruned %d events from the event log", result.RowsAffected)
+	}
+	return nil
+}
@@ -0,0 +1,32 @@
+package jobs
+
+import (
+	"ai-task-manager/models"
+	"context"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// StartIdempotencyKeyCleaner deletes expired idempotency keys every interval
+// until ctx is cancelled.
+func StartIdempotencyKeyCleaner(ctx context.Context, db *gorm.DB, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		result := db.Where("expires_at < ?", time.Now()).Delete(&models.IdempotencyKey{})
+		if result.Error != nil {
+			log.Printf("Error deleting expired idempotency keys: %v", result.Error)
+		} else if result.RowsAffected > 0 {
+			log.Printf("Deleted %d expired idempotency keys", result.RowsAffected)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
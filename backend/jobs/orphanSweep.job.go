@@ -0,0 +1,106 @@
+package jobs
+
+import (
+	"ai-task-manager/models"
+	"ai-task-manager/storage"
+	"context"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const sweepBatchSize = 100
+
+// StartOrphanSweeper deletes the stored objects that lost their last
+// attachment every interval until ctx is cancelled.
+func StartOrphanSweeper(ctx context.Context, db *gorm.DB, store storage.Storage, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := SweepOrphans(ctx, db, store); err != nil {
+			log.Printf("Error sweeping orphaned objects: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// SweepOrphans deletes the queued objects that are still unreferenced. An
+// object uploaded again since it was queued is kept. An object the store
+// fails to delete is logged, keeps the error and is skipped until the next
+// sweep, so it never holds up the others.
+func SweepOrphans(ctx context.Context, db *gorm.DB, store storage.Storage) error {
+	started := time.Now()
+	swept := 0
+	for {
+		var orphans []models.OrphanedObject
+		if err := db.Where("attempted_at IS NULL OR attempted_at < ?", started).
+			Order("created_at").Limit(sweepBatchSize).Find(&orphans).Error; err != nil {
+			return err
+		}
+		for _, orphan := range orphans {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			deleted, err := sweepOrphan(ctx, db, store, orphan)
+			if err != nil {
+				log.Printf("Error deleting orphaned object %s: %v", orphan.StorageKey, err)
+				if err := recordSweepFailure(db, orphan, err); err != nil {
+					return err
+				}
+				continue
+			}
+			if deleted {
+				swept++
+			}
+		}
+		if len(orphans) < sweepBatchSize {
+			break
+		}
+	}
+
+	if swept > 0 {
+		log.Printf("Deleted %d orphaned objects", swept)
+	}
+	return nil
+}
+
+// sweepOrphan deletes a queued object unless an attachment references it
+// again, and dequeues it either way. It reports whether the object was
+// deleted.
+func sweepOrphan(ctx context.Context, db *gorm.DB, store storage.Storage, orphan models.OrphanedObject) (bool, error) {
+	deleted := false
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := models.LockStorageKey(tx, orphan.StorageKey); err != nil {
+			return err
+		}
+		var references int64
+		if err := tx.Model(&models.Attachment{}).Where("storage_key = ?", orphan.StorageKey).Count(&references).Error; err != nil {
+			return err
+		}
+		if references == 0 {
+			if err := store.Delete(ctx, orphan.StorageKey); err != nil {
+				return err
+			}
+			deleted = true
+		}
+		return tx.Delete(&orphan).Error
+	})
+	return deleted, err
+}
+
+// recordSweepFailure keeps the outcome of a failed attempt on the queued
+// object, which also leaves it out of the rest of the sweep.
+func recordSweepFailure(db *gorm.DB, orphan models.OrphanedObject, cause error) error {
+	return db.Model(&models.OrphanedObject{}).Where("storage_key = ?", orphan.StorageKey).Updates(map[string]interface{}{
+		"attempts":     gorm.Expr("attempts + 1"),
+		"last_error":   cause.Error(),
+		"attempted_at": time.Now(),
+	}).Error
+}
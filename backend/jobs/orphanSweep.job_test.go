@@ -0,0 +1,152 @@
+package jobs
+
+import (
+	"ai-task-manager/database/dbtest"
+	"ai-task-manager/storage"
+	"context"
+	"database/sql/driver"
+	"errors"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// orphanQueue answers the sweeper's statements like the orphaned_objects and
+// Attachments tables would.
+type orphanQueue struct {
+	mu         sync.Mutex
+	keys       []string
+	attempts   map[string]int
+	references map[string]int64
+}
+
+func newOrphanQueue(fake *dbtest.DB, keys ...string) *orphanQueue {
+	q := &orphanQueue{keys: keys, attempts: make(map[string]int), references: make(map[string]int64)}
+	fake.On(`^SELECT \* FROM "orphaned_objects"`, func([]any) dbtest.Result {
+		q.mu.Lock()
+		defer q.mu.Unlock()
+		result := dbtest.Result{Columns: []string{"storage_key", "attempts"}}
+		for _, key := range q.keys {
+			if q.attempts[key] == 0 {
+				result.Rows = append(result.Rows, []driver.Value{key, int64(0)})
+			}
+		}
+		return result
+	})
+	fake.On(`^DELETE FROM "orphaned_objects"`, func(args []any) dbtest.Result {
+		q.mu.Lock()
+		defer q.mu.Unlock()
+		key := q.keyIn(args)
+		q.keys = slices.DeleteFunc(q.keys, func(queued string) bool { return queued == key })
+		return dbtest.Result{RowsAffected: 1}
+	})
+	fake.On(`^UPDATE "orphaned_objects"`, func(args []any) dbtest.Result {
+		q.mu.Lock()
+		defer q.mu.Unlock()
+		q.attempts[q.keyIn(args)]++
+		return dbtest.Result{RowsAffected: 1}
+	})
+	fake.On(`FROM "Attachments"`, func(args []any) dbtest.Result {
+		q.mu.Lock()
+		defer q.mu.Unlock()
+		return dbtest.Result{Columns: []string{"count"}, Rows: [][]driver.Value{{q.references[q.keyIn(args)]}}}
+	})
+	return q
+}
+
+func (q *orphanQueue) keyIn(args []any) string {
+	for _, arg := range args {
+		if key, ok := arg.(string); ok && slices.Contains(q.keys, key) {
+			return key
+		}
+	}
+	return ""
+}
+
+func (q *orphanQueue) queued() []string {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return slices.Clone(q.keys)
+}
+
+// failingStore fails to delete one key.
+type failingStore struct {
+	storage.Storage
+	key string
+}
+
+func (s failingStore) Delete(ctx context.Context, key string) error {
+	if key == s.key {
+		return errors.New("access denied")
+	}
+	return s.Storage.Delete(ctx, key)
+}
+
+func putObjects(t *testing.T, store storage.Storage, keys ...string) {
+	t.Helper()
+	for _, key := range keys {
+		if err := store.Put(context.Background(), key, strings.NewReader(key), int64(len(key)), "text/plain"); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func expectStored(t *testing.T, store storage.Storage, key string, want bool) {
+	t.Helper()
+	exists, err := store.Exists(context.Background(), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exists != want {
+		t.Errorf("%s stored = %v, want %v", key, exists, want)
+	}
+}
+
+func TestSweepOrphansKeepsReferencedObjects(t *testing.T) {
+	store, err := storage.NewLocalStorage(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	putObjects(t, store, "sha256/aa/orphan", "sha256/bb/reused")
+	db, fake := dbtest.New(t)
+	queue := newOrphanQueue(fake, "sha256/aa/orphan", "sha256/bb/reused")
+	queue.references["sha256/bb/reused"] = 1
+
+	if err := SweepOrphans(context.Background(), db, store); err != nil {
+		t.Fatal(err)
+	}
+	expectStored(t, store, "sha256/aa/orphan", false)
+	expectStored(t, store, "sha256/bb/reused", true)
+	if queued := queue.queued(); len(queued) > 0 {
+		t.Errorf("still queued: %v", queued)
+	}
+}
+
+func TestSweepOrphansSkipsObjectsItFailsToDelete(t *testing.T) {
+	local, err := storage.NewLocalStorage(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := failingStore{Storage: local, key: "sha256/aa/stuck"}
+	putObjects(t, store, "sha256/aa/stuck", "sha256/bb/orphan")
+	db, fake := dbtest.New(t)
+	queue := newOrphanQueue(fake, "sha256/aa/stuck", "sha256/bb/orphan")
+
+	if err := SweepOrphans(context.Background(), db, store); err != nil {
+		t.Fatal(err)
+	}
+	expectStored(t, store, "sha256/aa/stuck", true)
+	expectStored(t, store, "sha256/bb/orphan", false)
+	if queued := queue.queued(); !slices.Equal(queued, []string{"sha256/aa/stuck"}) {
+		t.Errorf("queued = %v, want only the object that failed", queued)
+	}
+
+	updates := fake.Statements(`^UPDATE "orphaned_objects"`)
+	if len(updates) != 1 || !slices.Contains(updates[0].Args, any("access denied")) {
+		t.Fatalf("the failure was not recorded: %v", updates)
+	}
+	if queue.attempts["sha256/aa/stuck"] != 1 {
+		t.Errorf("attempts = %d, want 1", queue.attempts["sha256/aa/stuck"])
+	}
+}
@@ -0,0 +1,72 @@
+package jobs
+
+import (
+	"ai-task-manager/events"
+	"ai-task-manager/models"
+	"context"
+	"log"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"gorm.io/gorm"
+)
+
+const purgeBatchSize = 100
+
+// StartTrashPurger permanently deletes tasks and comments that have been in
+// the trash for longer than retention, checking every interval until ctx is
+// cancelled.
+func StartTrashPurger(ctx context.Context, db *gorm.DB, retention, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := PurgeTrash(db, time.Now().Add(-retention)); err != nil {
+			log.Printf("Error purging trash: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// PurgeTrash hard-deletes everything soft-deleted before cutoff.
+func PurgeTrash(db *gorm.DB, cutoff time.Time) error {
+	purged := 0
+	for {
+		// Tasks are loaded and deleted one by one so their delete hooks can
+		// clean up comments and attachment files.
+		var tasks []models.Task
+		if err := db.Unscoped().
+			Where("deleted_at IS NOT NULL AND deleted_at < ?", cutoff).
+			Limit(purgeBatchSize).
+			Find(&tasks).Error; err != nil {
+			return err
+		}
+		for i := range tasks {
+			if err := models.PurgeTask(db, uuid.Nil, &tasks[i]); err != nil {
+				return err
+			}
+			events.Publish(events.NewTaskPurged(uuid.Nil, &tasks[i]))
+		}
+		purged += len(tasks)
+		if len(tasks) < purgeBatchSize {
+			break
+		}
+	}
+
+	result := db.Unscoped().
+		Where("deleted_at IS NOT NULL AND deleted_at < ?", cutoff).
+		Delete(&models.Comment{})
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if purged > 0 || result.RowsAffected > 0 {
+		log.Printf("Purged %d tasks and %d comments from trash", purged, result.RowsAffected)
+	}
+	return nil
+}
@@ -0,0 +1,33 @@
+package jobs
+
+import (
+	"ai-task-manager/models"
+	"context"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// StartWebhookDeliveryCleaner deletes the logs of finished webhook
+// deliveries older than retention every interval until ctx is cancelled.
+func StartWebhookDeliveryCleaner(ctx context.Context, db *gorm.DB, retention, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		result := db.Where("status <> ? AND updated_at < ?", models.WebhookDeliveryPending, time.Now().Add(-retention)).
+			Delete(&models.WebhookDelivery{})
+		if result.Error != nil {
+			log.Printf("Error deleting old webhook deliveries: %v", result.Error)
+		} else if result.RowsAffected > 0 {
+			log.Printf("Deleted %d old webhook deliveries", result.RowsAffected)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
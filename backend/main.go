@@ -1,16 +1,26 @@
 package main
 
 import (
+	"ai-task-manager/collab"
 	"ai-task-manager/config"
 	"ai-task-manager/database"
+	"ai-task-manager/embeddings"
+	"ai-task-manager/events"
+	"ai-task-manager/jobs"
 	"ai-task-manager/middlewares"
+	"ai-task-manager/models"
 	"ai-task-manager/routers"
+	"ai-task-manager/storage"
+	"ai-task-manager/webhooks"
 	"ai-task-manager/websocket"
+	"context"
 	"fmt"
 	"log"
 	"os"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/gofrs/uuid"
 )
 
 func main() {
@@ -52,6 +62,18 @@ func main() {
 	// Initialize the database connection
 	defer database.DisConnectDB()
 
+	store, err := storage.NewFromConfig(configApp)
+	if err != nil {
+		log.Fatal("Critical Error: Shutting down application due to storage failure: ", err)
+	}
+	storage.Default = store
+
+	webhooks.Default = webhooks.NewDispatcher(dbInstance, webhooks.Options{
+		Timeout:              configApp.WebhookTimeout,
+		MaxAttempts:          configApp.WebhookMaxAttempts,
+		AllowPrivateNetworks: configApp.WebhookAllowPrivateNetworks,
+	})
+
 	router := gin.New()
 
 	router.Use(gin.Logger())
@@ -80,13 +102,64 @@ func main() {
 		})
 	})
 
-	// Set up the WebSocket route
-	go func() {
-		for {
-			msg := <-websocket.Manager.Broadcast
-			websocket.Manager.BroadcastMessage(msg)
-		}
-	}()
+	// Log events so that reconnecting clients can catch up
+	eventLog := events.NewDBLog(dbInstance)
+	events.Default.UseLog(eventLog)
+	// Share events with the other instances
+	eventBackend, err := events.NewBackendFromConfig(configApp, eventLog)
+	if err != nil {
+		log.Fatal("Critical Error: Shutting down application due to event backend failure: ", err)
+	}
+	events.Default.UseBackend(eventBackend)
+	go events.Default.Listen(context.Background())
+	go jobs.StartEventLogPruner(context.Background(), dbInstance, configApp.EventLogRetention, configApp.EventLogMaxEntries, 10*time.Minute)
+
+	// Configure WebSocket origins, tickets, send queues, heartbeats and replay
+	websocket.Manager.Configure(websocket.Options{
+		AllowedOrigins:   configApp.WebSocketAllowedOrigins,
+		TicketTTL:        configApp.WebSocketTicketTTL,
+		Tickets:          websocket.NewDBTickets(dbInstance),
+		SendQueueSize:    configApp.WebSocketSendQueueSize,
+		SlowClientPolicy: configApp.WebSocketSlowClient,
+		PongTimeout:      configApp.WebSocketPongTimeout,
+		WriteTimeout:     configApp.WebSocketWriteTimeout,
+		MaxMessageSize:   configApp.WebSocketMaxMessageSize,
+		EventLog:         eventLog,
+		ReplayLimit:      configApp.EventReplayLimit,
+		CanViewTask: func(userID, taskID uuid.UUID) (bool, error) {
+			return models.TaskVisibleTo(dbInstance, taskID, userID)
+		},
+		Presence: websocket.NewDBPresence(dbInstance),
+		Signal:   events.Default.Signal,
+	})
+	events.Default.Subscribe(websocket.Manager.HandleEvent)
+	go websocket.Manager.RunPresence(context.Background())
+
+	// Let several users edit a description at once, saving it periodically
+	collab.Default = collab.NewHub(dbInstance)
+	go collab.Default.Run(context.Background(), configApp.CollabSaveInterval)
+
+	// Send events to webhooks, retrying failed deliveries
+	go webhooks.Default.Run(context.Background(), 2*time.Second)
+	go jobs.StartWebhookDeliveryCleaner(context.Background(), dbInstance, configApp.WebhookDeliveryRetention, time.Hour)
+
+	// Compute task embeddings in the background
+	embedder, err := embeddings.NewFromConfig(configApp)
+	if err != nil {
+		log.Fatal("Critical Error: Shutting down application due to embedding configuration failure: ", err)
+	}
+	embeddings.Default = embeddings.NewIndexer(dbInstance, embedder)
+	go embeddings.Default.Run(context.Background())
+
+	// Purge old items from the trash in the background
+	go jobs.StartTrashPurger(context.Background(), dbInstance, configApp.TrashRetention, configApp.TrashPurgeInterval)
+
+	// Delete attachment files no attachment references anymore
+	go jobs.StartOrphanSweeper(context.Background(), dbInstance, storage.Default, time.Minute)
+
+	// Forget idempotency keys once they expire
+	go jobs.StartIdempotencyKeyCleaner(context.Background(), dbInstance, time.Hour)
+
 	// Start the server
 	router.Run(fmt.Sprintf(":%s", port))
 
@@ -0,0 +1,22 @@
+package middlewares
+
+import (
+	"ai-task-manager/models"
+	"ai-task-manager/utils"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequireAdmin must run after JWTVerifyForUser.
+func RequireAdmin() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		role, err := utils.GetUserRoleFromHeader(c)
+		if err != nil || role != models.RoleAdmin {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Admin access required"})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
@@ -86,6 +86,7 @@ func JWTVerifyForUser(db *gorm.DB) gin.HandlerFunc {
 		userStruct := map[string]interface{}{
 			"userID": user.UserID.String(),
 			"email":  user.Email,
+			"role":   user.Role,
 		}
 		c.Set("user", userStruct)
 		c.Next()
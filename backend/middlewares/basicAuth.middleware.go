@@ -0,0 +1,47 @@
+package middlewares
+
+import (
+	"ai-task-manager/models"
+	"ai-task-manager/utils"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// BasicAuthForUser authenticates with the account's email and password in
+// an HTTP Basic Authorization header, for clients such as calendar apps that
+// cannot obtain a JWT. It sets the same "user" context value as
+// JWTVerifyForUser.
+func BasicAuthForUser(db *gorm.DB, realm string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		email, password, ok := c.Request.BasicAuth()
+		if !ok {
+			challengeBasicAuth(c, realm)
+			return
+		}
+
+		var user models.User
+		if err := db.Where("email = ?", email).First(&user).Error; err != nil {
+			challengeBasicAuth(c, realm)
+			return
+		}
+		if err := utils.CompareHashAndPassword(user.Password, password); err != nil {
+			challengeBasicAuth(c, realm)
+			return
+		}
+
+		c.Set("user", map[string]interface{}{
+			"userID": user.UserID.String(),
+			"email":  user.Email,
+			"role":   user.Role,
+		})
+		c.Next()
+	}
+}
+
+func challengeBasicAuth(c *gin.Context, realm string) {
+	c.Header("WWW-Authenticate", `Basic realm="`+realm+`", charset="UTF-8"`)
+	c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized access"})
+	c.Abort()
+}
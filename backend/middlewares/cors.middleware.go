@@ -17,11 +17,13 @@ func CorsMiddleWare() gin.HandlerFunc {
 			ctx.Writer.Header().Set("Vary", "Origin")
 		}
 		ctx.Writer.Header().Set("Access-Control-Allow-Credentials", "true")
-		ctx.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-CSRF-Token, X-Requested-With, Accept-Encoding")
-		ctx.Writer.Header().Set("Access-Control-Allow-Methods", "POST, GET, PATCH, PUT, DELETE, OPTIONS")
+		ctx.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-CSRF-Token, X-Requested-With, Accept-Encoding, If-Match, If-None-Match, Idempotency-Key, Depth")
+		ctx.Writer.Header().Set("Access-Control-Expose-Headers", "ETag, Idempotent-Replayed")
+		ctx.Writer.Header().Set("Access-Control-Allow-Methods", "POST, GET, PATCH, PUT, DELETE, OPTIONS, PROPFIND, REPORT")
 
-		// Handle preflight OPTIONS request
-		if ctx.Request.Method == "OPTIONS" {
+		// Handle preflight OPTIONS request. Other OPTIONS requests, such as
+		// CalDAV capability discovery, reach their handlers.
+		if ctx.Request.Method == "OPTIONS" && ctx.GetHeader("Access-Control-Request-Method") != "" {
 			ctx.AbortWithStatus(http.StatusNoContent)
 			return
 		}
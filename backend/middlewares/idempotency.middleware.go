@@ -0,0 +1,248 @@
+package middlewares
+
+import (
+	"ai-task-manager/config"
+	"ai-task-manager/models"
+	"ai-task-manager/utils"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gofrs/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	IdempotencyKeyHeader      = "Idempotency-Key"
+	IdempotentReplayedHeader  = "Idempotent-Replayed"
+	maxIdempotencyKeyLength   = 255
+	maxInMemoryIdempotentBody = 1 << 20
+)
+
+// anonymousIdempotencyNamespace derives the owner of an unauthenticated
+// request's key from the client address, so that anonymous clients choosing
+// the same key neither block nor replay each other's requests.
+var anonymousIdempotencyNamespace = uuid.Must(uuid.FromString("bb99b6a1-de51-4c66-8fe6-6c61d0ee2ad9"))
+
+// replayedHeaders are the response headers stored with an idempotency key
+// and sent again on replay.
+var replayedHeaders = []string{"Content-Type", "Location", "ETag"}
+
+// Idempotency makes POST requests carrying an Idempotency-Key header safe to
+// retry. The first response is stored under the caller, key and a hash of
+// the request; a retry with the same request replays it, reuse of the key
+// for a different request is rejected with 422, and a retry that arrives
+// while the first request is still running gets 409. Server errors are not
+// stored, so the client can retry them. It must run after JWTVerifyForUser
+// on authenticated routes, and never on routes whose responses carry secrets,
+// since the stored response is kept in plaintext.
+func Idempotency(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(IdempotencyKeyHeader)
+		if c.Request.Method != http.MethodPost || key == "" {
+			c.Next()
+			return
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Idempotency-Key must not exceed 255 characters"})
+			c.Abort()
+			return
+		}
+
+		userID := uuid.Nil
+		if id, err := utils.GetUserIdFromHeader(c); err == nil {
+			userID, _ = utils.IsUUID(id)
+		}
+		if userID == uuid.Nil {
+			userID = uuid.NewV5(anonymousIdempotencyNamespace, c.ClientIP())
+		}
+
+		requestHash, cleanup, err := hashRequest(c, userID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Error reading request body"})
+			c.Abort()
+			return
+		}
+		defer cleanup()
+
+		record := models.IdempotencyKey{
+			UserID:      userID,
+			Key:         key,
+			RequestHash: requestHash,
+			Status:      models.IdempotencyStatusProcessing,
+			ExpiresAt:   time.Now().Add(config.GetConfig().IdempotencyKeyTTL),
+		}
+		claimed, err := claimIdempotencyKey(db, &record)
+		if err != nil {
+			log.Printf("Error claiming idempotency key: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error checking idempotency key"})
+			c.Abort()
+			return
+		}
+		if !claimed {
+			replayIdempotentResponse(c, record, requestHash)
+			return
+		}
+
+		// A panicking handler never completes the key; release it so that
+		// retries are not told the request is still being processed.
+		defer func() {
+			if r := recover(); r != nil {
+				db.Delete(&models.IdempotencyKey{}, "user_id = ? AND key = ?", userID, key)
+				panic(r)
+			}
+		}()
+
+		writer := &recordingWriter{ResponseWriter: c.Writer}
+		c.Writer = writer
+		c.Next()
+
+		status := writer.Status()
+		if status >= http.StatusInternalServerError {
+			db.Delete(&models.IdempotencyKey{}, "user_id = ? AND key = ?", userID, key)
+			return
+		}
+
+		headers := make(map[string]string)
+		for _, name := range replayedHeaders {
+			if value := writer.Header().Get(name); value != "" {
+				headers[name] = value
+			}
+		}
+		encodedHeaders, _ := json.Marshal(headers)
+		if err := db.Model(&models.IdempotencyKey{}).
+			Where("user_id = ? AND key = ?", userID, key).
+			Updates(map[string]any{
+				"status":           models.IdempotencyStatusCompleted,
+				"response_status":  status,
+				"response_headers": string(encodedHeaders),
+				"response_body":    writer.body.Bytes(),
+			}).Error; err != nil {
+			log.Printf("Error storing idempotent response: %v", err)
+		}
+	}
+}
+
+// claimIdempotencyKey inserts record unless the key is already taken. When it
+// is, record is replaced by the stored row. Expired rows are reclaimed.
+func claimIdempotencyKey(db *gorm.DB, record *models.IdempotencyKey) (bool, error) {
+	for attempt := 0; attempt < 2; attempt++ {
+		result := db.Clauses(clause.OnConflict{DoNothing: true}).Create(record)
+		if result.Error != nil {
+			return false, result.Error
+		}
+		if result.RowsAffected == 1 {
+			return true, nil
+		}
+
+		var existing models.IdempotencyKey
+		err := db.First(&existing, "user_id = ? AND key = ?", record.UserID, record.Key).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			continue
+		}
+		if err != nil {
+			return false, err
+		}
+		if existing.ExpiresAt.After(time.Now()) {
+			*record = existing
+			return false, nil
+		}
+		if err := db.Where("user_id = ? AND key = ? AND expires_at = ?", existing.UserID, existing.Key, existing.ExpiresAt).
+			Delete(&models.IdempotencyKey{}).Error; err != nil {
+			return false, err
+		}
+	}
+	return false, errors.New("idempotency key is being reclaimed concurrently")
+}
+
+func replayIdempotentResponse(c *gin.Context, stored models.IdempotencyKey, requestHash string) {
+	defer c.Abort()
+
+	if stored.RequestHash != requestHash {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Idempotency-Key was already used for a different request"})
+		return
+	}
+	if stored.Status != models.IdempotencyStatusCompleted {
+		c.JSON(http.StatusConflict, gin.H{"error": "A request with this Idempotency-Key is still being processed"})
+		return
+	}
+
+	var headers map[string]string
+	_ = json.Unmarshal([]byte(stored.ResponseHeaders), &headers)
+	for name, value := range headers {
+		c.Header(name, value)
+	}
+	c.Header(IdempotentReplayedHeader, "true")
+	c.Status(stored.ResponseStatus)
+	_, _ = c.Writer.Write(stored.ResponseBody)
+}
+
+// hashRequest fingerprints the caller, method, path and body, and replaces
+// the request body so the handler can still read it. Large bodies are
+// spooled to a temporary file that cleanup removes.
+func hashRequest(c *gin.Context, userID uuid.UUID) (string, func(), error) {
+	hasher := sha256.New()
+	hasher.Write(userID.Bytes())
+	io.WriteString(hasher, c.Request.Method+" "+c.Request.URL.Path+"?"+c.Request.URL.RawQuery+"\n")
+	io.WriteString(hasher, c.ContentType()+"\n")
+
+	cleanup := func() {}
+	if c.Request.Body == nil {
+		return hex.EncodeToString(hasher.Sum(nil)), cleanup, nil
+	}
+
+	head, err := io.ReadAll(io.LimitReader(c.Request.Body, maxInMemoryIdempotentBody+1))
+	if err != nil {
+		return "", cleanup, err
+	}
+	hasher.Write(head)
+	if len(head) <= maxInMemoryIdempotentBody {
+		c.Request.Body = io.NopCloser(bytes.NewReader(head))
+		return hex.EncodeToString(hasher.Sum(nil)), cleanup, nil
+	}
+
+	tmp, err := os.CreateTemp("", "idempotent-body-*")
+	if err != nil {
+		return "", cleanup, err
+	}
+	cleanup = func() {
+		tmp.Close()
+		os.Remove(tmp.Name())
+	}
+	if _, err := tmp.Write(head); err != nil {
+		return "", cleanup, err
+	}
+	if _, err := io.Copy(io.MultiWriter(tmp, hasher), c.Request.Body); err != nil {
+		return "", cleanup, err
+	}
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		return "", cleanup, err
+	}
+	c.Request.Body = tmp
+	return hex.EncodeToString(hasher.Sum(nil)), cleanup, nil
+}
+
+// recordingWriter keeps a copy of the response body for storage.
+type recordingWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *recordingWriter) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *recordingWriter) WriteString(data string) (int, error) {
+	w.body.WriteString(data)
+	return w.ResponseWriter.WriteString(data)
+}
@@ -0,0 +1,44 @@
+package middlewares
+
+import (
+	"ai-task-manager/models"
+	"ai-task-manager/websocket"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// WebSocketAuthForUser authenticates a WebSocket upgrade. Browsers cannot set
+// headers on the upgrade request, so besides the access_token cookie and a
+// bearer token it accepts a short-lived ticket in the ticket query parameter.
+func WebSocketAuthForUser(db *gorm.DB) gin.HandlerFunc {
+	jwtVerify := JWTVerifyForUser(db)
+	return func(c *gin.Context) {
+		token := c.Query("ticket")
+		if token == "" {
+			jwtVerify(c)
+			return
+		}
+
+		userID, ok := websocket.Manager.RedeemTicket(token)
+		if !ok {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired ticket"})
+			c.Abort()
+			return
+		}
+		var user models.User
+		if err := db.First(&user, "user_id = ?", userID).Error; err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "User does not exist"})
+			c.Abort()
+			return
+		}
+
+		c.Set("user", map[string]interface{}{
+			"userID": user.UserID.String(),
+			"email":  user.Email,
+			"role":   user.Role,
+		})
+		c.Next()
+	}
+}
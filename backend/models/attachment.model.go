@@ -0,0 +1,80 @@
+package models
+
+import (
+	"time"
+
+	"github.com/gofrs/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Attachment is a file uploaded to a task. The bytes live in storage under
+// StorageKey, which is derived from the content hash so duplicate uploads
+// share a single stored object.
+type Attachment struct {
+	AttachmentID uuid.UUID `gorm:"type:uuid;primaryKey;unique;not null" json:"attachmentID"`
+	TaskID       uuid.UUID `gorm:"type:uuid;not null;index" json:"taskID"`
+	UserID       uuid.UUID `gorm:"type:uuid;not null" json:"userID"`
+	FileName     string    `gorm:"not null" json:"fileName"`
+	ContentType  string    `gorm:"not null" json:"contentType"`
+	Size         int64     `gorm:"not null" json:"size"`
+	Hash         string    `gorm:"not null;index" json:"hash"`
+	StorageKey   string    `gorm:"not null" json:"-"`
+	CreatedAt    time.Time `gorm:"autoCreateTime" json:"createdAt"`
+}
+
+func (a *Attachment) BeforeCreate(tx *gorm.DB) error {
+	id := uuid.Must(uuid.NewV4())
+	if id != uuid.Nil {
+		a.AttachmentID = id
+	}
+	return nil
+}
+
+// AfterDelete queues the stored object for deletion once no attachment
+// references it anymore. The object itself is deleted by
+// jobs.StartOrphanSweeper after the transaction commits, so a rollback keeps
+// it.
+func (a *Attachment) AfterDelete(tx *gorm.DB) error {
+	if a.StorageKey == "" {
+		return nil
+	}
+	if err := LockStorageKey(tx, a.StorageKey); err != nil {
+		return err
+	}
+
+	var remaining int64
+	if err := tx.Model(&Attachment{}).Where("storage_key = ?", a.StorageKey).Count(&remaining).Error; err != nil {
+		return err
+	}
+	if remaining > 0 {
+		return nil
+	}
+	return tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&OrphanedObject{StorageKey: a.StorageKey}).Error
+}
+
+func (Attachment) TableName() string {
+	return "Attachments"
+}
+
+// OrphanedObject is a stored object whose last attachment was deleted. A new
+// upload of the same content may reference it again before it is swept. An
+// object the store failed to delete keeps the outcome of its latest attempt
+// and is tried again on the next sweep.
+type OrphanedObject struct {
+	StorageKey  string     `gorm:"primaryKey"`
+	Attempts    int        `gorm:"not null;default:0"`
+	LastError   string     `gorm:"type:text"`
+	AttemptedAt *time.Time `gorm:"index"`
+	CreatedAt   time.Time  `gorm:"autoCreateTime;index"`
+}
+
+func (OrphanedObject) TableName() string {
+	return "orphaned_objects"
+}
+
+// LockStorageKey holds key until tx ends, so that adding and removing the
+// references to a stored object and sweeping it never interleave.
+func LockStorageKey(tx *gorm.DB, key string) error {
+	return tx.Exec("SELECT pg_advisory_xact_lock(hashtext(?))", key).Error
+}
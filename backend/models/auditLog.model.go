@@ -0,0 +1,70 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	AuditEntityTask    = "task"
+	AuditEntityUser    = "user"
+	AuditEntityProject = "project"
+)
+
+type FieldChange struct {
+	Field string `json:"field"`
+	From  string `json:"from"`
+	To    string `json:"to"`
+}
+
+// AuditLog is the system-wide, append-only record of changes made by users.
+type AuditLog struct {
+	AuditLogID uuid.UUID `gorm:"type:uuid;primaryKey;unique;not null" json:"auditLogID"`
+	ActorID    uuid.UUID `gorm:"type:uuid;not null;index" json:"actorID"`
+	EntityType string    `gorm:"not null;index:idx_audit_logs_entity" json:"entityType"`
+	EntityID   uuid.UUID `gorm:"type:uuid;not null;index:idx_audit_logs_entity" json:"entityID"`
+	Action     string    `gorm:"not null" json:"action"`
+	Changes    string    `gorm:"type:text" json:"changes,omitempty"`
+	CreatedAt  time.Time `gorm:"autoCreateTime;index" json:"createdAt"`
+}
+
+func (a *AuditLog) BeforeCreate(tx *gorm.DB) error {
+	id := uuid.Must(uuid.NewV4())
+	if id != uuid.Nil {
+		a.AuditLogID = id
+	}
+	return nil
+}
+
+func (a *AuditLog) BeforeUpdate(tx *gorm.DB) error {
+	return ErrAppendOnly
+}
+
+func (a *AuditLog) BeforeDelete(tx *gorm.DB) error {
+	return ErrAppendOnly
+}
+
+func (AuditLog) TableName() string {
+	return "audit_logs"
+}
+
+// RecordAudit appends an entry to the audit log. changes may be nil.
+func RecordAudit(tx *gorm.DB, actorID uuid.UUID, entityType string, entityID uuid.UUID, action string, changes []FieldChange) error {
+	entry := AuditLog{
+		ActorID:    actorID,
+		EntityType: entityType,
+		EntityID:   entityID,
+		Action:     action,
+	}
+	if len(changes) > 0 {
+		encoded, err := json.Marshal(changes)
+		if err != nil {
+			return err
+		}
+		entry.Changes = string(encoded)
+	}
+	return tx.Create(&entry).Error
+}
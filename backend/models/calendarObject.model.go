@@ -0,0 +1,20 @@
+package models
+
+import (
+	"github.com/gofrs/uuid"
+)
+
+// CalendarObject remembers the resource name and UID a CalDAV client chose
+// when it created a task, so the task keeps the href and UID the client
+// knows it by. Tasks created elsewhere have no row and are served as
+// <taskID>.ics.
+type CalendarObject struct {
+	TaskID uuid.UUID `gorm:"type:uuid;primaryKey" json:"taskID"`
+	UserID uuid.UUID `gorm:"type:uuid;not null;uniqueIndex:idx_calendar_objects_user_name" json:"userID"`
+	Name   string    `gorm:"not null;uniqueIndex:idx_calendar_objects_user_name" json:"name"`
+	UID    string    `gorm:"not null" json:"uid"`
+}
+
+func (CalendarObject) TableName() string {
+	return "calendar_objects"
+}
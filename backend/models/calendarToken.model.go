@@ -0,0 +1,32 @@
+package models
+
+import (
+	"time"
+
+	"github.com/gofrs/uuid"
+	"gorm.io/gorm"
+)
+
+// CalendarToken authorizes a user's iCalendar feed URL. Only a hash of the
+// token is stored; the token itself is shown once when it is created. Each
+// user has at most one, and deleting it revokes the URL without touching the
+// user's sessions.
+type CalendarToken struct {
+	TokenID    uuid.UUID  `gorm:"type:uuid;primaryKey;unique;not null" json:"tokenID"`
+	UserID     uuid.UUID  `gorm:"type:uuid;not null;uniqueIndex" json:"userID"`
+	TokenHash  string     `gorm:"not null;uniqueIndex" json:"-"`
+	CreatedAt  time.Time  `gorm:"autoCreateTime" json:"createdAt"`
+	LastUsedAt *time.Time `json:"lastUsedAt"`
+}
+
+func (ct *CalendarToken) BeforeCreate(tx *gorm.DB) error {
+	id := uuid.Must(uuid.NewV4())
+	if id != uuid.Nil {
+		ct.TokenID = id
+	}
+	return nil
+}
+
+func (CalendarToken) TableName() string {
+	return "calendar_tokens"
+}
@@ -0,0 +1,36 @@
+package models
+
+import (
+	"ai-task-manager/validations"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"gorm.io/gorm"
+)
+
+// Comment is a Markdown note left by a user on a task.
+type Comment struct {
+	CommentID uuid.UUID      `gorm:"type:uuid;primaryKey;unique;not null" json:"commentID"`
+	TaskID    uuid.UUID      `gorm:"type:uuid;not null;index" json:"taskID"`
+	UserID    uuid.UUID      `gorm:"type:uuid;not null;index" json:"userID"`
+	Body      string         `gorm:"type:text;not null" json:"body"`
+	CreatedAt time.Time      `gorm:"autoCreateTime;index" json:"createdAt"`
+	UpdatedAt time.Time      `gorm:"autoUpdateTime" json:"updatedAt"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"deletedAt"`
+}
+
+func (cm *Comment) BeforeCreate(tx *gorm.DB) error {
+	id := uuid.Must(uuid.NewV4())
+	if id != uuid.Nil {
+		cm.CommentID = id
+	}
+	return validations.ValidateComment(validations.Comment{Body: cm.Body})
+}
+
+func (cm *Comment) BeforeUpdate(tx *gorm.DB) error {
+	return validations.ValidateComment(validations.Comment{Body: cm.Body})
+}
+
+func (Comment) TableName() string {
+	return "Comments"
+}
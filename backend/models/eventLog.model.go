@@ -0,0 +1,28 @@
+package models
+
+import (
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+// EventLogEntry is a published domain event, kept for a while so that
+// clients which were disconnected can catch up on what they missed. Seq
+// comes from the table's sequence and orders the events of the whole
+// application. Audience, Scope and Payload hold JSON.
+type EventLogEntry struct {
+	Seq        int64      `gorm:"primaryKey;autoIncrement" json:"seq"`
+	EventID    uuid.UUID  `gorm:"type:uuid;not null;uniqueIndex" json:"eventID"`
+	Version    int        `gorm:"not null" json:"version"`
+	Type       string     `gorm:"not null" json:"type"`
+	ActorID    *uuid.UUID `gorm:"type:uuid" json:"actorID"`
+	OccurredAt time.Time  `gorm:"not null" json:"occurredAt"`
+	Audience   string     `gorm:"type:jsonb;not null" json:"-"`
+	Scope      string     `gorm:"type:jsonb;not null" json:"-"`
+	Payload    string     `gorm:"type:jsonb;not null" json:"-"`
+	CreatedAt  time.Time  `gorm:"autoCreateTime;index" json:"createdAt"`
+}
+
+func (EventLogEntry) TableName() string {
+	return "event_log"
+}
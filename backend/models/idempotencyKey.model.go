@@ -0,0 +1,32 @@
+package models
+
+import (
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+const (
+	IdempotencyStatusProcessing = "processing"
+	IdempotencyStatusCompleted  = "completed"
+)
+
+// IdempotencyKey remembers the response to a POST sent with an
+// Idempotency-Key header so that retries of the same request replay it.
+// Unauthenticated requests are stored under an ID derived from the client
+// address.
+type IdempotencyKey struct {
+	UserID          uuid.UUID `gorm:"type:uuid;primaryKey" json:"userID"`
+	Key             string    `gorm:"primaryKey;size:255" json:"key"`
+	RequestHash     string    `gorm:"not null" json:"requestHash"`
+	Status          string    `gorm:"not null" json:"status"`
+	ResponseStatus  int       `json:"responseStatus"`
+	ResponseHeaders string    `gorm:"type:text" json:"responseHeaders"`
+	ResponseBody    []byte    `json:"-"`
+	CreatedAt       time.Time `gorm:"autoCreateTime" json:"createdAt"`
+	ExpiresAt       time.Time `gorm:"not null;index" json:"expiresAt"`
+}
+
+func (IdempotencyKey) TableName() string {
+	return "idempotency_keys"
+}
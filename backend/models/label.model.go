@@ -0,0 +1,74 @@
+package models
+
+import (
+	"ai-task-manager/validations"
+	"strings"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Label is a user-defined tag. Names are unique per user and labels are
+// attached to tasks through the task_labels join table.
+type Label struct {
+	LabelID   uuid.UUID `gorm:"type:uuid;primaryKey;unique;not null" json:"labelID"`
+	UserID    uuid.UUID `gorm:"type:uuid;not null;uniqueIndex:idx_labels_user_name" json:"userID"`
+	Name      string    `gorm:"not null;uniqueIndex:idx_labels_user_name" json:"name"`
+	CreatedAt time.Time `gorm:"autoCreateTime" json:"createdAt"`
+}
+
+func (l *Label) BeforeCreate(tx *gorm.DB) error {
+	if l.LabelID == uuid.Nil {
+		l.LabelID = uuid.Must(uuid.NewV4())
+	}
+	return validations.ValidateLabelName(l.Name)
+}
+
+func (Label) TableName() string {
+	return "Labels"
+}
+
+// FindOrCreateLabels returns the user's labels with the given names,
+// creating any that don't exist yet.
+func FindOrCreateLabels(tx *gorm.DB, userID uuid.UUID, names []string) ([]Label, error) {
+	seen := make(map[string]bool)
+	var unique []string
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if err := validations.ValidateLabelName(name); err != nil {
+			return nil, err
+		}
+		if !seen[name] {
+			seen[name] = true
+			unique = append(unique, name)
+		}
+	}
+	if len(unique) == 0 {
+		return []Label{}, nil
+	}
+
+	labels := make([]Label, len(unique))
+	for i, name := range unique {
+		labels[i] = Label{UserID: userID, Name: name}
+	}
+	if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&labels).Error; err != nil {
+		return nil, err
+	}
+
+	var stored []Label
+	if err := tx.Where("user_id = ? AND name IN ?", userID, unique).Order("name").Find(&stored).Error; err != nil {
+		return nil, err
+	}
+	return stored, nil
+}
+
+// LabelNames returns the names of labels joined with commas.
+func LabelNames(labels []Label) string {
+	names := make([]string, len(labels))
+	for i, label := range labels {
+		names[i] = label.Name
+	}
+	return strings.Join(names, ",")
+}
@@ -0,0 +1,35 @@
+package models
+
+import (
+	"time"
+
+	"github.com/gofrs/uuid"
+	"gorm.io/gorm"
+)
+
+const NotificationTypeMention = "mention"
+
+// Notification is addressed to a single user, e.g. when they are @mentioned in a comment.
+type Notification struct {
+	NotificationID uuid.UUID  `gorm:"type:uuid;primaryKey;unique;not null" json:"notificationID"`
+	UserID         uuid.UUID  `gorm:"type:uuid;not null;index" json:"userID"`
+	ActorID        uuid.UUID  `gorm:"type:uuid;not null" json:"actorID"`
+	Type           string     `gorm:"not null" json:"type"`
+	TaskID         uuid.UUID  `gorm:"type:uuid" json:"taskID"`
+	CommentID      uuid.UUID  `gorm:"type:uuid" json:"commentID"`
+	Message        string     `gorm:"not null" json:"message"`
+	ReadAt         *time.Time `json:"readAt"`
+	CreatedAt      time.Time  `gorm:"autoCreateTime;index" json:"createdAt"`
+}
+
+func (n *Notification) BeforeCreate(tx *gorm.DB) error {
+	id := uuid.Must(uuid.NewV4())
+	if id != uuid.Nil {
+		n.NotificationID = id
+	}
+	return nil
+}
+
+func (Notification) TableName() string {
+	return "Notifications"
+}
@@ -0,0 +1,36 @@
+package models
+
+import (
+	"ai-task-manager/validations"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"gorm.io/gorm"
+)
+
+// Project groups tasks. A task belongs to at most one project.
+type Project struct {
+	ProjectID   uuid.UUID      `gorm:"type:uuid;primaryKey;unique;not null" json:"projectID"`
+	UserID      uuid.UUID      `gorm:"type:uuid;not null;index" json:"userID"`
+	Name        string         `gorm:"not null" json:"name"`
+	Description string         `gorm:"type:text" json:"description"`
+	CreatedAt   time.Time      `gorm:"autoCreateTime" json:"createdAt"`
+	UpdatedAt   time.Time      `gorm:"autoUpdateTime" json:"updatedAt"`
+	DeletedAt   gorm.DeletedAt `gorm:"index" json:"deletedAt"`
+}
+
+func (p *Project) BeforeCreate(tx *gorm.DB) error {
+	id := uuid.Must(uuid.NewV4())
+	if id != uuid.Nil {
+		p.ProjectID = id
+	}
+	return validations.ValidateProject(validations.Project{Name: p.Name})
+}
+
+func (p *Project) BeforeUpdate(tx *gorm.DB) error {
+	return validations.ValidateProject(validations.Project{Name: p.Name})
+}
+
+func (Project) TableName() string {
+	return "Projects"
+}
@@ -2,12 +2,17 @@ package models
 
 import (
 	"ai-task-manager/validations"
+	"errors"
 	"time"
 
 	"github.com/gofrs/uuid"
 	"gorm.io/gorm"
 )
 
+// ErrVersionConflict is returned when a task changed between being read and
+// being written.
+var ErrVersionConflict = errors.New("task was modified by someone else")
+
 type Task struct {
 	TaskID      uuid.UUID      `gorm:"type:uuid;primaryKey;unique;not null;index" json:"taskID"`
 	Title       string         `gorm:"not null" json:"title"`
@@ -18,6 +23,13 @@ type Task struct {
 	UpdatedAt   time.Time      `gorm:"autoUpdateTime" json:"updatedAt"`
 	DeletedAt   gorm.DeletedAt `gorm:"index" json:"deletedAt"`
 	UserID      uuid.UUID      `gorm:"type:uuid;not null" json:"userID"`
+	ProjectID   *uuid.UUID     `gorm:"type:uuid;index" json:"projectID"`
+	DueDate     *time.Time     `gorm:"index" json:"dueDate"`
+	// Priority follows iCalendar: 0 is undefined, 1 the highest and 9 the
+	// lowest.
+	Priority int     `gorm:"not null;default:0" json:"priority"`
+	Labels   []Label `gorm:"many2many:task_labels;joinForeignKey:TaskID;joinReferences:LabelID" json:"labels,omitempty"`
+	Version  int64   `gorm:"not null;default:1" json:"version"`
 	// Foreign key
 	//User User `gorm:"foreignKey:UserID;references:UserID;constraint:OnUpdate:CASCADE,OnDelete:SET NULL" json:"user"`
 }
@@ -27,10 +39,12 @@ func (t *Task) BeforeCreate(tx *gorm.DB) error {
 	if id != uuid.Nil {
 		t.TaskID = id
 	}
+	t.Version = 1
 	if err := validations.ValidateTask(validations.Task{
 		Title:       t.Title,
 		Description: t.Description,
 		Status:      t.Status,
+		Priority:    t.Priority,
 	}); err != nil {
 		return err
 	}
@@ -42,12 +56,131 @@ func (t *Task) BeforeUpdate(tx *gorm.DB) error {
 		Title:       t.Title,
 		Description: t.Description,
 		Status:      t.Status,
+		Priority:    t.Priority,
 	}); err != nil {
 		return err
 	}
 	return nil
 }
 
+// BeforeDelete removes the task's comments, labels, embedding, CalDAV name
+// and attachments when the task is hard-deleted (Unscoped). Soft deletes keep
+// them so the task can still be restored.
+func (t *Task) BeforeDelete(tx *gorm.DB) error {
+	if !tx.Statement.Unscoped || t.TaskID == uuid.Nil {
+		return nil
+	}
+
+	if err := tx.Unscoped().Where("task_id = ?", t.TaskID).Delete(&Comment{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Exec("DELETE FROM task_labels WHERE task_id = ?", t.TaskID).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("task_id = ?", t.TaskID).Delete(&TaskEmbedding{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("task_id = ?", t.TaskID).Delete(&CalendarObject{}).Error; err != nil {
+		return err
+	}
+
+	var attachments []Attachment
+	if err := tx.Where("task_id = ?", t.TaskID).Find(&attachments).Error; err != nil {
+		return err
+	}
+	for i := range attachments {
+		if err := tx.Delete(&attachments[i]).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// VisibleTo reports whether userID may see and work on the task: its owner
+// and its assignee may.
+func (t *Task) VisibleTo(userID uuid.UUID) bool {
+	return userID != uuid.Nil && (t.UserID == userID || t.AssignedTo == userID)
+}
+
+// VisibleTasks is a scope limiting a task query to the tasks userID owns or
+// is assigned.
+func VisibleTasks(userID uuid.UUID) func(*gorm.DB) *gorm.DB {
+	return func(tx *gorm.DB) *gorm.DB {
+		if userID == uuid.Nil {
+			return tx.Where("FALSE")
+		}
+		return tx.Where("(user_id = ? OR assigned_to = ?)", userID, userID)
+	}
+}
+
+// TaskVisibleTo reports whether the task with taskID exists and userID owns
+// or is assigned it.
+func TaskVisibleTo(db *gorm.DB, taskID, userID uuid.UUID) (bool, error) {
+	var count int64
+	err := db.Model(&Task{}).Scopes(VisibleTasks(userID)).Where("task_id = ?", taskID).Count(&count).Error
+	return count > 0, err
+}
+
+// UpdateTaskVersioned applies the non-zero fields of changes to task, but only
+// if the stored row still has task.Version. The version is bumped on success.
+func UpdateTaskVersioned(tx *gorm.DB, task *Task, changes Task) error {
+	expected := task.Version
+	changes.Version = expected + 1
+	changes.Labels = nil
+	result := tx.Model(task).Omit("Labels").Where("version = ?", expected).Updates(changes)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrVersionConflict
+	}
+	return nil
+}
+
+// SaveTaskVersioned writes every column of task, including zero values, with
+// the same version check as UpdateTaskVersioned.
+func SaveTaskVersioned(tx *gorm.DB, task *Task) error {
+	expected := task.Version
+	task.Version = expected + 1
+	result := tx.Model(task).Where("version = ?", expected).
+		Select("*").Omit("task_id", "user_id", "created_at", "deleted_at", "Labels").
+		Updates(task)
+	if result.Error == nil && result.RowsAffected == 0 {
+		result.Error = ErrVersionConflict
+	}
+	if result.Error != nil {
+		task.Version = expected
+	}
+	return result.Error
+}
+
+// DeleteTaskVersioned soft-deletes task if it still has task.Version.
+func DeleteTaskVersioned(tx *gorm.DB, task *Task) error {
+	result := tx.Where("version = ?", task.Version).Delete(task)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrVersionConflict
+	}
+	return nil
+}
+
+// PurgeTask permanently deletes a task and records it in the task history.
+// actorID is uuid.Nil when the purge is done by the system.
+func PurgeTask(tx *gorm.DB, actorID uuid.UUID, task *Task) error {
+	return tx.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Delete(task).Error; err != nil {
+			return err
+		}
+		return RecordTaskEvents(tx, TaskEvent{
+			TaskID:  task.TaskID,
+			ActorID: actorID,
+			Action:  TaskActionPurge,
+		})
+	})
+}
+
 func (Task) TableName() string {
 	return "Tasks"
 }
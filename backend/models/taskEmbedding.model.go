@@ -0,0 +1,56 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+// Vector is a float32 embedding stored as little-endian bytes.
+type Vector []float32
+
+func (v Vector) Value() (driver.Value, error) {
+	buf := make([]byte, 4*len(v))
+	for i, value := range v {
+		binary.LittleEndian.PutUint32(buf[4*i:], math.Float32bits(value))
+	}
+	return buf, nil
+}
+
+func (v *Vector) Scan(src any) error {
+	raw, ok := src.([]byte)
+	if !ok {
+		return fmt.Errorf("cannot scan %T into Vector", src)
+	}
+	if len(raw)%4 != 0 {
+		return fmt.Errorf("invalid vector length %d", len(raw))
+	}
+	vector := make(Vector, len(raw)/4)
+	for i := range vector {
+		vector[i] = math.Float32frombits(binary.LittleEndian.Uint32(raw[4*i:]))
+	}
+	*v = vector
+	return nil
+}
+
+func (Vector) GormDataType() string {
+	return "bytea"
+}
+
+// TaskEmbedding holds the embedding of a task's title and description.
+// ContentHash lets the indexer skip tasks whose text has not changed.
+type TaskEmbedding struct {
+	TaskID      uuid.UUID `gorm:"type:uuid;primaryKey;not null" json:"taskID"`
+	Model       string    `gorm:"not null;index" json:"model"`
+	ContentHash string    `gorm:"not null" json:"contentHash"`
+	Vector      Vector    `gorm:"not null" json:"-"`
+	UpdatedAt   time.Time `gorm:"autoUpdateTime" json:"updatedAt"`
+}
+
+func (TaskEmbedding) TableName() string {
+	return "task_embeddings"
+}
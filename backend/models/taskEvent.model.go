@@ -0,0 +1,138 @@
+package models
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	TaskActionCreate       = "create"
+	TaskActionUpdate       = "update"
+	TaskActionStatusChange = "status_change"
+	TaskActionAssign       = "assign"
+	TaskActionDelete       = "delete"
+	TaskActionRestore      = "restore"
+	TaskActionPurge        = "purge"
+)
+
+var ErrAppendOnly = errors.New("history records are append-only")
+
+// TaskEvent is one entry in a task's history. Field, OldValue and NewValue
+// are only set for changes to a single field.
+type TaskEvent struct {
+	EventID   uuid.UUID `gorm:"type:uuid;primaryKey;unique;not null" json:"eventID"`
+	TaskID    uuid.UUID `gorm:"type:uuid;not null;index:idx_task_events_task_created" json:"taskID"`
+	ActorID   uuid.UUID `gorm:"type:uuid;not null;index" json:"actorID"`
+	Action    string    `gorm:"not null" json:"action"`
+	Field     string    `json:"field,omitempty"`
+	OldValue  string    `gorm:"type:text" json:"oldValue,omitempty"`
+	NewValue  string    `gorm:"type:text" json:"newValue,omitempty"`
+	CreatedAt time.Time `gorm:"autoCreateTime;index:idx_task_events_task_created" json:"createdAt"`
+}
+
+func (e *TaskEvent) BeforeCreate(tx *gorm.DB) error {
+	id := uuid.Must(uuid.NewV4())
+	if id != uuid.Nil {
+		e.EventID = id
+	}
+	return nil
+}
+
+func (e *TaskEvent) BeforeUpdate(tx *gorm.DB) error {
+	return ErrAppendOnly
+}
+
+func (e *TaskEvent) BeforeDelete(tx *gorm.DB) error {
+	return ErrAppendOnly
+}
+
+func (TaskEvent) TableName() string {
+	return "task_events"
+}
+
+// DiffTask returns one event per tracked field that differs between before
+// and after. Status and assignee changes get their own actions.
+func DiffTask(actorID uuid.UUID, before, after *Task) []TaskEvent {
+	var events []TaskEvent
+	add := func(action, field, oldValue, newValue string) {
+		if oldValue == newValue {
+			return
+		}
+		events = append(events, TaskEvent{
+			TaskID:   after.TaskID,
+			ActorID:  actorID,
+			Action:   action,
+			Field:    field,
+			OldValue: oldValue,
+			NewValue: newValue,
+		})
+	}
+
+	add(TaskActionUpdate, "title", before.Title, after.Title)
+	add(TaskActionUpdate, "description", before.Description, after.Description)
+	add(TaskActionStatusChange, "status", before.Status, after.Status)
+	add(TaskActionAssign, "assignedTo", uuidString(before.AssignedTo), uuidString(after.AssignedTo))
+	add(TaskActionUpdate, "projectID", uuidPtrString(before.ProjectID), uuidPtrString(after.ProjectID))
+	add(TaskActionUpdate, "dueDate", timePtrString(before.DueDate), timePtrString(after.DueDate))
+	add(TaskActionUpdate, "priority", strconv.Itoa(before.Priority), strconv.Itoa(after.Priority))
+	return events
+}
+
+// RecordTaskEvents appends events to the task history and mirrors them into
+// the audit log, grouping the field changes of each action into one entry.
+func RecordTaskEvents(tx *gorm.DB, events ...TaskEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+	if err := tx.Create(&events).Error; err != nil {
+		return err
+	}
+
+	var order []string
+	changes := make(map[string][]FieldChange)
+	for _, event := range events {
+		if _, ok := changes[event.Action]; !ok {
+			order = append(order, event.Action)
+			changes[event.Action] = nil
+		}
+		if event.Field != "" {
+			changes[event.Action] = append(changes[event.Action], FieldChange{
+				Field: event.Field,
+				From:  event.OldValue,
+				To:    event.NewValue,
+			})
+		}
+	}
+
+	for _, action := range order {
+		if err := RecordAudit(tx, events[0].ActorID, AuditEntityTask, events[0].TaskID, action, changes[action]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func uuidPtrString(id *uuid.UUID) string {
+	if id == nil {
+		return ""
+	}
+	return uuidString(*id)
+}
+
+func timePtrString(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+func uuidString(id uuid.UUID) string {
+	if id == uuid.Nil {
+		return ""
+	}
+	return id.String()
+}
@@ -0,0 +1,24 @@
+package models
+
+import (
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+// TaskViewer is a WebSocket connection viewing a task, shared by every
+// instance of the application so that each can list all the viewers. The
+// instance holding the connection refreshes SeenAt; rows it stops refreshing,
+// such as those of an instance that went away, expire.
+type TaskViewer struct {
+	ConnectionID uuid.UUID `gorm:"type:uuid;primaryKey" json:"connectionID"`
+	TaskID       uuid.UUID `gorm:"type:uuid;not null;index" json:"taskID"`
+	UserID       uuid.UUID `gorm:"type:uuid;not null" json:"userID"`
+	Since        time.Time `gorm:"not null" json:"since"`
+	TypingUntil  time.Time `json:"typingUntil"`
+	SeenAt       time.Time `gorm:"not null;index" json:"seenAt"`
+}
+
+func (TaskViewer) TableName() string {
+	return "task_viewers"
+}
@@ -11,11 +11,17 @@ import (
 	"gorm.io/gorm"
 )
 
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+)
+
 type User struct {
 	UserID    uuid.UUID      `gorm:"type:uuid;primaryKey;unique;not null" json:"userID"`
 	Email     string         `gorm:"unique;not null" json:"email"`
 	Username  string         `gorm:"unique;not null" json:"username"`
 	Password  string         `gorm:"not null" json:"password"`
+	Role      string         `gorm:"not null;default:'user'" json:"role"`
 	CreatedAt time.Time      `gorm:"autoCreateTime" json:"createdAt"`
 	UpdatedAt time.Time      `gorm:"autoUpdateTime" json:"updatedAt"`
 	DeletedAt gorm.DeletedAt `gorm:"index" json:"deletedAt"`
@@ -0,0 +1,21 @@
+package models
+
+import (
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+// WebSocketTicket is a single-use credential for opening a WebSocket
+// connection, shared by every instance so that a ticket issued by one can be
+// redeemed on another. Only a hash of the ticket is stored; redeeming it
+// deletes the row.
+type WebSocketTicket struct {
+	TicketHash string    `gorm:"primaryKey" json:"-"`
+	UserID     uuid.UUID `gorm:"type:uuid;not null" json:"userID"`
+	ExpiresAt  time.Time `gorm:"not null;index" json:"expiresAt"`
+}
+
+func (WebSocketTicket) TableName() string {
+	return "websocket_tickets"
+}
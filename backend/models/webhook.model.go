@@ -0,0 +1,106 @@
+package models
+
+import (
+	"strings"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"gorm.io/gorm"
+)
+
+// WebhookAllEvents subscribes a webhook to every event type.
+const WebhookAllEvents = "*"
+
+// Webhook posts the events its owner receives to URL, optionally only those
+// of one project. EventTypes is a comma-separated list of event types, or
+// WebhookAllEvents. The secret signs every delivery; it is shown once when
+// the webhook is created.
+type Webhook struct {
+	WebhookID  uuid.UUID  `gorm:"type:uuid;primaryKey;unique;not null" json:"webhookID"`
+	UserID     uuid.UUID  `gorm:"type:uuid;not null;index" json:"userID"`
+	ProjectID  *uuid.UUID `gorm:"type:uuid;index" json:"projectID"`
+	URL        string     `gorm:"not null" json:"url"`
+	EventTypes string     `gorm:"not null" json:"-"`
+	Secret     string     `gorm:"not null" json:"-"`
+	Active     bool       `gorm:"not null;default:true" json:"active"`
+	CreatedAt  time.Time  `gorm:"autoCreateTime" json:"createdAt"`
+	UpdatedAt  time.Time  `gorm:"autoUpdateTime" json:"updatedAt"`
+}
+
+func (w *Webhook) BeforeCreate(tx *gorm.DB) error {
+	id := uuid.Must(uuid.NewV4())
+	if id != uuid.Nil {
+		w.WebhookID = id
+	}
+	return nil
+}
+
+func (Webhook) TableName() string {
+	return "webhooks"
+}
+
+// Types returns the event types the webhook is subscribed to.
+func (w Webhook) Types() []string {
+	return strings.Split(w.EventTypes, ",")
+}
+
+// Subscribes reports whether the webhook wants events of type eventType.
+func (w Webhook) Subscribes(eventType string) bool {
+	for _, t := range w.Types() {
+		if t == WebhookAllEvents || t == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// Webhook delivery statuses.
+const (
+	WebhookDeliveryPending   = "pending"
+	WebhookDeliverySucceeded = "succeeded"
+	WebhookDeliveryFailed    = "failed"
+)
+
+// WebhookDelivery is one event sent, or still to be sent, to a webhook, with
+// the outcome of its latest attempt. A pending delivery is attempted again
+// at NextAttemptAt; each webhook receives an event at most once.
+type WebhookDelivery struct {
+	DeliveryID     uuid.UUID  `gorm:"type:uuid;primaryKey;unique;not null" json:"deliveryID"`
+	WebhookID      uuid.UUID  `gorm:"type:uuid;not null;uniqueIndex:idx_webhook_delivery_event" json:"webhookID"`
+	EventID        uuid.UUID  `gorm:"type:uuid;not null;uniqueIndex:idx_webhook_delivery_event" json:"eventID"`
+	EventType      string     `gorm:"not null" json:"eventType"`
+	Payload        string     `gorm:"type:text;not null" json:"-"`
+	Status         string     `gorm:"not null;index" json:"status"`
+	Attempts       int        `gorm:"not null;default:0" json:"attempts"`
+	NextAttemptAt  *time.Time `gorm:"index" json:"nextAttemptAt"`
+	ResponseStatus int        `json:"responseStatus"`
+	ResponseBody   string     `gorm:"type:text" json:"responseBody"`
+	Error          string     `gorm:"type:text" json:"error"`
+	DurationMs     int64      `json:"durationMs"`
+	DeliveredAt    *time.Time `json:"deliveredAt"`
+	CreatedAt      time.Time  `gorm:"autoCreateTime;index" json:"createdAt"`
+	UpdatedAt      time.Time  `gorm:"autoUpdateTime" json:"updatedAt"`
+}
+
+func (d *WebhookDelivery) BeforeCreate(tx *gorm.DB) error {
+	id := uuid.Must(uuid.NewV4())
+	if id != uuid.Nil {
+		d.DeliveryID = id
+	}
+	return nil
+}
+
+func (WebhookDelivery) TableName() string {
+	return "webhook_deliveries"
+}
+
+// WebhookCursor records how far the webhook dispatcher has read the event
+// log.
+type WebhookCursor struct {
+	Name string `gorm:"primaryKey"`
+	Seq  int64  `gorm:"not null"`
+}
+
+func (WebhookCursor) TableName() string {
+	return "webhook_cursors"
+}
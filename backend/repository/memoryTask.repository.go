@@ -0,0 +1,391 @@
+package repository
+
+import (
+	"ai-task-manager/models"
+	"ai-task-manager/validations"
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/gofrs/uuid"
+)
+
+// MemoryTaskRepository is an in-memory TaskRepository for tests and local
+// tooling. Search is a naive word-matching stand-in for the Postgres
+// full-text search with the same query syntax.
+type MemoryTaskRepository struct {
+	mu    sync.RWMutex
+	tasks map[uuid.UUID]models.Task
+}
+
+var _ TaskRepository = (*MemoryTaskRepository)(nil)
+
+func NewMemoryTaskRepository(tasks ...models.Task) *MemoryTaskRepository {
+	repo := &MemoryTaskRepository{tasks: make(map[uuid.UUID]models.Task)}
+	for _, task := range tasks {
+		repo.Put(task)
+	}
+	return repo
+}
+
+// Put inserts or replaces a task.
+func (m *MemoryTaskRepository) Put(task models.Task) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.tasks[task.TaskID] = task
+}
+
+func (m *MemoryTaskRepository) Remove(taskID uuid.UUID) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.tasks, taskID)
+}
+
+func (m *MemoryTaskRepository) snapshot(keep func(*models.Task) bool) []models.Task {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var tasks []models.Task
+	for _, task := range m.tasks {
+		if task.DeletedAt.Valid || !keep(&task) {
+			continue
+		}
+		tasks = append(tasks, task)
+	}
+	return tasks
+}
+
+func (m *MemoryTaskRepository) List(ctx context.Context, query validations.TaskQuery) (*TaskPage, error) {
+	tasks := m.snapshot(func(task *models.Task) bool { return matchesTaskQuery(task, &query) })
+	sort.Slice(tasks, func(i, j int) bool {
+		return compareTaskKeys(taskSortValues(&tasks[i], query.Sort), taskIDKey(&tasks[i]), taskSortValues(&tasks[j], query.Sort), taskIDKey(&tasks[j]), query.Sort) < 0
+	})
+
+	page := &TaskPage{Total: int64(len(tasks))}
+	backward := query.Cursor != nil && query.Cursor.Direction == validations.CursorPrev
+
+	start, end := 0, len(tasks)
+	if query.Cursor != nil {
+		values := make([]any, len(query.Sort))
+		for i, key := range query.Sort {
+			value, err := parseCursorValue(key.Column, query.Cursor.Values[i])
+			if err != nil {
+				return nil, err
+			}
+			values[i] = value
+		}
+		compareToCursor := func(i int) int {
+			return compareTaskKeys(taskSortValues(&tasks[i], query.Sort), taskIDKey(&tasks[i]), values, query.Cursor.TaskID, query.Sort)
+		}
+		if backward {
+			// Rows strictly before the cursor.
+			end = sort.Search(len(tasks), func(i int) bool { return compareToCursor(i) >= 0 })
+		} else {
+			// Rows strictly after the cursor.
+			start = sort.Search(len(tasks), func(i int) bool { return compareToCursor(i) > 0 })
+		}
+	}
+
+	var selected []models.Task
+	if backward {
+		from := end - query.Limit
+		if from < 0 {
+			from = 0
+		}
+		selected = tasks[from:end]
+		page.HasPrev = from > 0
+		page.HasNext = true
+	} else {
+		to := start + query.Limit
+		if to > len(tasks) {
+			to = len(tasks)
+		}
+		selected = tasks[start:to]
+		page.HasNext = to < len(tasks)
+		page.HasPrev = query.Cursor != nil
+	}
+
+	page.Tasks = append([]models.Task(nil), selected...)
+	if len(page.Tasks) > 0 {
+		if page.HasNext {
+			page.NextCursor = taskCursor(query, &page.Tasks[len(page.Tasks)-1], validations.CursorNext)
+		}
+		if page.HasPrev {
+			page.PrevCursor = taskCursor(query, &page.Tasks[0], validations.CursorPrev)
+		}
+	}
+	return page, nil
+}
+
+func (m *MemoryTaskRepository) Search(ctx context.Context, query validations.SearchQuery, page, limit int) (*TaskSearchResult, error) {
+	var hits []TaskSearchHit
+	for _, task := range m.snapshot(func(task *models.Task) bool {
+		return task.VisibleTo(query.VisibleTo) && matchesStatuses(task, query.Statuses) && matchesLabels(task, query.Labels)
+	}) {
+		titleWords := validations.SearchWords(task.Title)
+		descriptionWords := validations.SearchWords(task.Description)
+
+		rank, ok := scoreSearch(query, titleWords, descriptionWords)
+		if !ok {
+			continue
+		}
+		hits = append(hits, TaskSearchHit{
+			Task: task,
+			Rank: rank,
+			Highlights: TaskHighlights{
+				Title:       highlightWords(task.Title, query),
+				Description: highlightWords(task.Description, query),
+			},
+		})
+	}
+
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].Rank != hits[j].Rank {
+			return hits[i].Rank > hits[j].Rank
+		}
+		if !hits[i].Task.CreatedAt.Equal(hits[j].Task.CreatedAt) {
+			return hits[i].Task.CreatedAt.After(hits[j].Task.CreatedAt)
+		}
+		return hits[i].Task.TaskID.String() < hits[j].Task.TaskID.String()
+	})
+
+	result := &TaskSearchResult{Total: int64(len(hits)), Hits: []TaskSearchHit{}}
+	from := (page - 1) * limit
+	if from < len(hits) {
+		to := from + limit
+		if to > len(hits) {
+			to = len(hits)
+		}
+		result.Hits = hits[from:to]
+	}
+	return result, nil
+}
+
+func matchesTaskQuery(task *models.Task, query *validations.TaskQuery) bool {
+	if !task.VisibleTo(query.VisibleTo) {
+		return false
+	}
+	if !matchesStatuses(task, query.Statuses) || !matchesLabels(task, query.Labels) {
+		return false
+	}
+	if query.AssignedTo != nil && task.AssignedTo != *query.AssignedTo {
+		return false
+	}
+	if query.Unassigned && task.AssignedTo != uuid.Nil {
+		return false
+	}
+	if query.OwnerID != nil && task.UserID != *query.OwnerID {
+		return false
+	}
+	if query.ProjectID != nil && (task.ProjectID == nil || *task.ProjectID != *query.ProjectID) {
+		return false
+	}
+	if query.NoProject && task.ProjectID != nil {
+		return false
+	}
+	if query.Text != "" {
+		text := strings.ToLower(query.Text)
+		if !strings.Contains(strings.ToLower(task.Title), text) && !strings.Contains(strings.ToLower(task.Description), text) {
+			return false
+		}
+	}
+	if query.CreatedAfter != nil && task.CreatedAt.Before(*query.CreatedAfter) {
+		return false
+	}
+	if query.CreatedBefore != nil && !task.CreatedAt.Before(*query.CreatedBefore) {
+		return false
+	}
+	if query.UpdatedAfter != nil && task.UpdatedAt.Before(*query.UpdatedAfter) {
+		return false
+	}
+	if query.UpdatedBefore != nil && !task.UpdatedAt.Before(*query.UpdatedBefore) {
+		return false
+	}
+	return true
+}
+
+func matchesStatuses(task *models.Task, statuses []string) bool {
+	if len(statuses) == 0 {
+		return true
+	}
+	for _, status := range statuses {
+		if task.Status == status {
+			return true
+		}
+	}
+	return false
+}
+
+func matchesLabels(task *models.Task, names []string) bool {
+	if len(names) == 0 {
+		return true
+	}
+	for _, label := range task.Labels {
+		for _, name := range names {
+			if label.Name == name {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func taskSortValues(task *models.Task, keys []validations.SortKey) []any {
+	values := make([]any, len(keys))
+	for i, key := range keys {
+		switch key.Column {
+		case "created_at":
+			values[i] = task.CreatedAt
+		case "updated_at":
+			values[i] = task.UpdatedAt
+		case "title":
+			values[i] = task.Title
+		case "status":
+			values[i] = task.Status
+		}
+	}
+	return values
+}
+
+func taskIDKey(task *models.Task) string {
+	return task.TaskID.String()
+}
+
+// compareTaskKeys orders two rows the same way the SQL ORDER BY does: by each
+// sort key in its direction, then by task ID ascending.
+func compareTaskKeys(a []any, aID string, b []any, bID string, keys []validations.SortKey) int {
+	for i, key := range keys {
+		result := 0
+		switch av := a[i].(type) {
+		case time.Time:
+			bv := b[i].(time.Time)
+			result = av.Compare(bv)
+		case string:
+			result = strings.Compare(av, b[i].(string))
+		}
+		if key.Desc {
+			result = -result
+		}
+		if result != 0 {
+			return result
+		}
+	}
+	return strings.Compare(aID, bID)
+}
+
+// scoreSearch reports whether the words satisfy the query and a rank that,
+// like the weighted tsvector, favours title matches.
+func scoreSearch(query validations.SearchQuery, titleWords, descriptionWords []string) (float64, bool) {
+	for _, excluded := range query.Excluded {
+		if containsPhrase(titleWords, excluded, false) || containsPhrase(descriptionWords, excluded, false) {
+			return 0, false
+		}
+	}
+
+	rank := 0.0
+	for _, term := range query.Terms {
+		inTitle := containsPhrase(titleWords, []string{term}, true)
+		inDescription := containsPhrase(descriptionWords, []string{term}, true)
+		if !inTitle && !inDescription {
+			return 0, false
+		}
+		rank += weight(inTitle, inDescription)
+	}
+	for _, phrase := range query.Phrases {
+		inTitle := containsPhrase(titleWords, phrase, false)
+		inDescription := containsPhrase(descriptionWords, phrase, false)
+		if !inTitle && !inDescription {
+			return 0, false
+		}
+		rank += weight(inTitle, inDescription)
+	}
+	return rank, true
+}
+
+func weight(inTitle, inDescription bool) float64 {
+	rank := 0.0
+	if inTitle {
+		rank += 1.0
+	}
+	if inDescription {
+		rank += 0.4
+	}
+	return rank
+}
+
+// containsPhrase checks for phrase as consecutive words; with prefix set the
+// last word only has to be a prefix.
+func containsPhrase(words, phrase []string, prefix bool) bool {
+	for start := 0; start+len(phrase) <= len(words); start++ {
+		matched := true
+		for i, want := range phrase {
+			got := words[start+i]
+			if prefix && i == len(phrase)-1 {
+				if !strings.HasPrefix(got, want) {
+					matched = false
+				}
+			} else if got != want {
+				matched = false
+			}
+			if !matched {
+				break
+			}
+		}
+		if matched {
+			return true
+		}
+	}
+	return false
+}
+
+// highlightWords escapes text as HTML and wraps every word matching a search
+// term (by prefix) or a phrase word in <mark></mark>.
+func highlightWords(text string, query validations.SearchQuery) string {
+	if !query.HasText() {
+		return markHighlights(text)
+	}
+	phraseWords := make(map[string]bool)
+	for _, phrase := range query.Phrases {
+		for _, word := range phrase {
+			phraseWords[word] = true
+		}
+	}
+	matches := func(word string) bool {
+		word = strings.ToLower(word)
+		if phraseWords[word] {
+			return true
+		}
+		for _, term := range query.Terms {
+			if strings.HasPrefix(word, term) {
+				return true
+			}
+		}
+		return false
+	}
+
+	var b strings.Builder
+	runes := []rune(text)
+	isWordRune := func(r rune) bool { return unicode.IsLetter(r) || unicode.IsDigit(r) }
+	for i := 0; i < len(runes); {
+		if !isWordRune(runes[i]) {
+			b.WriteRune(runes[i])
+			i++
+			continue
+		}
+		end := i
+		for end < len(runes) && isWordRune(runes[end]) {
+			end++
+		}
+		word := string(runes[i:end])
+		if matches(word) {
+			b.WriteString(highlightStart + word + highlightStop)
+		} else {
+			b.WriteString(word)
+		}
+		i = end
+	}
+	return markHighlights(b.String())
+}
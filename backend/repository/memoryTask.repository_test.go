@@ -0,0 +1,209 @@
+package repository
+
+import (
+	"ai-task-manager/models"
+	"ai-task-manager/validations"
+	"context"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+func newTask(title, description, status string, owner, assignee uuid.UUID, createdAt time.Time) models.Task {
+	return models.Task{
+		TaskID:      uuid.Must(uuid.NewV4()),
+		Title:       title,
+		Description: description,
+		Status:      status,
+		UserID:      owner,
+		AssignedTo:  assignee,
+		CreatedAt:   createdAt,
+		UpdatedAt:   createdAt,
+	}
+}
+
+func taskTitles(tasks []models.Task) []string {
+	titles := make([]string, len(tasks))
+	for i, task := range tasks {
+		titles[i] = task.Title
+	}
+	return titles
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestMemoryListOnlyVisibleTasks(t *testing.T) {
+	alice, bob := uuid.Must(uuid.NewV4()), uuid.Must(uuid.NewV4())
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	repo := NewMemoryTaskRepository(
+		newTask("own", "d", "pending", alice, uuid.Nil, start),
+		newTask("assigned", "d", "pending", bob, alice, start.Add(time.Hour)),
+		newTask("foreign", "d", "pending", bob, uuid.Nil, start.Add(2*time.Hour)),
+	)
+
+	query, errs := validations.ParseTaskQuery(url.Values{"sort": {"createdAt"}}, alice)
+	if len(errs) > 0 {
+		t.Fatalf("ParseTaskQuery: %v", errs)
+	}
+	page, err := repo.List(context.Background(), query)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := taskTitles(page.Tasks), []string{"own", "assigned"}; !equalStrings(got, want) {
+		t.Errorf("List = %v, want %v", got, want)
+	}
+	if page.Total != 2 {
+		t.Errorf("Total = %d, want 2", page.Total)
+	}
+
+	if _, errs := validations.ParseTaskQuery(url.Values{"owner": {bob.String()}}, alice); len(errs) == 0 {
+		t.Error("ParseTaskQuery accepted another user as owner")
+	}
+}
+
+func TestMemoryListCursorPagination(t *testing.T) {
+	owner := uuid.Must(uuid.NewV4())
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	var tasks []models.Task
+	var want []string
+	for i := 0; i < 7; i++ {
+		title := string(rune('a' + i))
+		tasks = append(tasks, newTask(title, "d", "pending", owner, uuid.Nil, start.Add(time.Duration(i)*time.Minute)))
+		want = append(want, title)
+	}
+	repo := NewMemoryTaskRepository(tasks...)
+
+	values := url.Values{"sort": {"createdAt"}, "limit": {"3"}}
+	var got []string
+	var pages []*TaskPage
+	for {
+		query, errs := validations.ParseTaskQuery(values, owner)
+		if len(errs) > 0 {
+			t.Fatalf("ParseTaskQuery: %v", errs)
+		}
+		page, err := repo.List(context.Background(), query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pages = append(pages, page)
+		got = append(got, taskTitles(page.Tasks)...)
+		if !page.HasNext {
+			break
+		}
+		values.Set("cursor", page.NextCursor)
+	}
+	if !equalStrings(got, want) {
+		t.Fatalf("forward pages = %v, want %v", got, want)
+	}
+
+	// Going back from the last page gives the previous one again.
+	values.Set("cursor", pages[len(pages)-1].PrevCursor)
+	query, errs := validations.ParseTaskQuery(values, owner)
+	if len(errs) > 0 {
+		t.Fatalf("ParseTaskQuery: %v", errs)
+	}
+	page, err := repo.List(context.Background(), query)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := taskTitles(page.Tasks), taskTitles(pages[len(pages)-2].Tasks); !equalStrings(got, want) {
+		t.Errorf("previous page = %v, want %v", got, want)
+	}
+}
+
+func TestMemorySearch(t *testing.T) {
+	alice, bob := uuid.Must(uuid.NewV4()), uuid.Must(uuid.NewV4())
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	repo := NewMemoryTaskRepository(
+		newTask("Deploy the release", "write release notes", "pending", alice, uuid.Nil, start),
+		newTask("Review notes", "deployment checklist", "completed", alice, uuid.Nil, start.Add(time.Hour)),
+		newTask("Draft deploy plan", "notes release", "pending", bob, alice, start.Add(2*time.Hour)),
+		newTask("Deploy secret", "release notes", "pending", bob, uuid.Nil, start.Add(3*time.Hour)),
+	)
+
+	tests := []struct {
+		q    string
+		want []string
+	}{
+		{"deploy", []string{"Draft deploy plan", "Deploy the release", "Review notes"}},
+		{`"release notes"`, []string{"Deploy the release"}},
+		{"deploy -draft", []string{"Deploy the release", "Review notes"}},
+		{"deploy status:completed", []string{"Review notes"}},
+	}
+	for _, test := range tests {
+		query, err := validations.ParseSearchQuery(test.q, alice)
+		if err != nil {
+			t.Fatalf("ParseSearchQuery(%q): %v", test.q, err)
+		}
+		result, err := repo.Search(context.Background(), query, 1, 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, hit := range result.Hits {
+			got = append(got, hit.Task.Title)
+		}
+		if !equalStrings(got, test.want) {
+			t.Errorf("Search(%q) = %v, want %v", test.q, got, test.want)
+		}
+	}
+
+	query, _ := validations.ParseSearchQuery("deploy", alice)
+	result, _ := repo.Search(context.Background(), query, 1, 10)
+	if got := result.Hits[1].Highlights.Title; got != "<mark>Deploy</mark> the release" {
+		t.Errorf("title highlight = %q", got)
+	}
+}
+
+func TestHighlightsEscapeMarkup(t *testing.T) {
+	alice := uuid.Must(uuid.NewV4())
+	repo := NewMemoryTaskRepository(
+		newTask(`<img src=x onerror="alert(1)"> deploy`, "a & b", "pending", alice, uuid.Nil, time.Now()),
+	)
+	query, err := validations.ParseSearchQuery("deploy", alice)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := repo.Search(context.Background(), query, 1, 10)
+	if err != nil || len(result.Hits) != 1 {
+		t.Fatalf("Search = %v, %v", result, err)
+	}
+	highlights := result.Hits[0].Highlights
+	if want := `&lt;img src=x onerror=&#34;alert(1)&#34;&gt; <mark>deploy</mark>`; highlights.Title != want {
+		t.Errorf("title highlight = %q, want %q", highlights.Title, want)
+	}
+	if highlights.Description != "a &amp; b" {
+		t.Errorf("description highlight = %q", highlights.Description)
+	}
+}
+
+func TestMarkHighlights(t *testing.T) {
+	tests := []struct {
+		headline, want string
+	}{
+		{"plain", "plain"},
+		{"\x02<b>\x03 and <script>", "<mark>&lt;b&gt;</mark> and &lt;script&gt;"},
+		{"\x02a\x03 … \x02b\x03", "<mark>a</mark> … <mark>b</mark>"},
+		// Delimiters typed into the text itself still only make balanced
+		// marks.
+		{"a\x03b\x02c", "ab<mark>c</mark>"},
+		{"\x02a\x02b\x03\x03", "<mark>ab</mark>"},
+	}
+	for _, test := range tests {
+		if got := markHighlights(test.headline); got != test.want {
+			t.Errorf("markHighlights(%q) = %q, want %q", test.headline, got, test.want)
+		}
+	}
+}
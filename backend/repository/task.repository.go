@@ -0,0 +1,449 @@
+package repository
+
+import (
+	"ai-task-manager/models"
+	"ai-task-manager/validations"
+	"context"
+	"fmt"
+	"html"
+	"strings"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// TaskPage is one page of a cursor-paginated task listing.
+type TaskPage struct {
+	Tasks      []models.Task
+	Total      int64
+	NextCursor string
+	PrevCursor string
+	HasNext    bool
+	HasPrev    bool
+}
+
+type TaskHighlights struct {
+	Title       string `json:"title"`
+	Description string `json:"description"`
+}
+
+// TaskSearchHit is a search match. Highlights are HTML: the text is escaped
+// and matched fragments are wrapped in <mark></mark>, the only tags.
+type TaskSearchHit struct {
+	Task       models.Task    `json:"task"`
+	Rank       float64        `json:"rank"`
+	Highlights TaskHighlights `json:"highlights"`
+}
+
+type TaskSearchResult struct {
+	Hits  []TaskSearchHit
+	Total int64
+}
+
+type TaskRepository interface {
+	List(ctx context.Context, query validations.TaskQuery) (*TaskPage, error)
+	Search(ctx context.Context, query validations.SearchQuery, page, limit int) (*TaskSearchResult, error)
+}
+
+type taskRepository struct {
+	db *gorm.DB
+}
+
+func NewTaskRepository(db *gorm.DB) TaskRepository {
+	return &taskRepository{
+		db: db,
+	}
+}
+
+// taskFieldColumns maps the public field names to task columns; labels are
+// preloaded rather than selected.
+var taskFieldColumns = map[string]string{
+	"taskID":      "task_id",
+	"title":       "title",
+	"description": "description",
+	"status":      "status",
+	"assignedTo":  "assigned_to",
+	"userID":      "user_id",
+	"projectID":   "project_id",
+	"dueDate":     "due_date",
+	"priority":    "priority",
+	"createdAt":   "created_at",
+	"updatedAt":   "updated_at",
+	"deletedAt":   "deleted_at",
+}
+
+func (r *taskRepository) List(ctx context.Context, query validations.TaskQuery) (*TaskPage, error) {
+	page := &TaskPage{}
+
+	if err := r.filtered(ctx, query).Count(&page.Total).Error; err != nil {
+		return nil, err
+	}
+
+	backward := query.Cursor != nil && query.Cursor.Direction == validations.CursorPrev
+	tx := r.filtered(ctx, query)
+
+	if query.Cursor != nil {
+		condition, args, err := keysetCondition(query.Sort, query.Cursor, backward)
+		if err != nil {
+			return nil, err
+		}
+		tx = tx.Where(condition, args...)
+	}
+
+	for _, key := range query.Sort {
+		tx = tx.Order(clause.OrderByColumn{Column: clause.Column{Name: key.Column}, Desc: key.Desc != backward})
+	}
+	tx = tx.Order(clause.OrderByColumn{Column: clause.Column{Name: "task_id"}, Desc: backward})
+
+	columns, withLabels := selectedColumns(query)
+	if len(columns) > 0 {
+		tx = tx.Select(columns)
+	}
+	if withLabels {
+		tx = tx.Preload("Labels")
+	}
+
+	var tasks []models.Task
+	if err := tx.Limit(query.Limit + 1).Find(&tasks).Error; err != nil {
+		return nil, err
+	}
+
+	hasMore := len(tasks) > query.Limit
+	if hasMore {
+		tasks = tasks[:query.Limit]
+	}
+	if backward {
+		for i, j := 0, len(tasks)-1; i < j; i, j = i+1, j-1 {
+			tasks[i], tasks[j] = tasks[j], tasks[i]
+		}
+		page.HasPrev = hasMore
+		page.HasNext = true
+	} else {
+		page.HasNext = hasMore
+		page.HasPrev = query.Cursor != nil
+	}
+
+	page.Tasks = tasks
+	if len(tasks) > 0 {
+		if page.HasNext {
+			page.NextCursor = taskCursor(query, &tasks[len(tasks)-1], validations.CursorNext)
+		}
+		if page.HasPrev {
+			page.PrevCursor = taskCursor(query, &tasks[0], validations.CursorPrev)
+		}
+	}
+	return page, nil
+}
+
+func (r *taskRepository) filtered(ctx context.Context, query validations.TaskQuery) *gorm.DB {
+	tx := r.db.WithContext(ctx).Model(&models.Task{}).Scopes(models.VisibleTasks(query.VisibleTo))
+
+	if len(query.Statuses) > 0 {
+		tx = tx.Where("status IN ?", query.Statuses)
+	}
+	if query.AssignedTo != nil {
+		tx = tx.Where("assigned_to = ?", *query.AssignedTo)
+	}
+	if query.Unassigned {
+		tx = tx.Where("assigned_to = ?", uuid.Nil)
+	}
+	if query.OwnerID != nil {
+		tx = tx.Where("user_id = ?", *query.OwnerID)
+	}
+	if query.ProjectID != nil {
+		tx = tx.Where("project_id = ?", *query.ProjectID)
+	}
+	if query.NoProject {
+		tx = tx.Where("project_id IS NULL")
+	}
+	if len(query.Labels) > 0 {
+		tx = tx.Where(`task_id IN (SELECT tl.task_id FROM task_labels tl JOIN "Labels" l ON l.label_id = tl.label_id WHERE l.name IN ?)`, query.Labels)
+	}
+	if query.Text != "" {
+		pattern := "%" + escapeLike(query.Text) + "%"
+		tx = tx.Where("(title ILIKE ? OR description ILIKE ?)", pattern, pattern)
+	}
+	if query.CreatedAfter != nil {
+		tx = tx.Where("created_at >= ?", *query.CreatedAfter)
+	}
+	if query.CreatedBefore != nil {
+		tx = tx.Where("created_at < ?", *query.CreatedBefore)
+	}
+	if query.UpdatedAfter != nil {
+		tx = tx.Where("updated_at >= ?", *query.UpdatedAfter)
+	}
+	if query.UpdatedBefore != nil {
+		tx = tx.Where("updated_at < ?", *query.UpdatedBefore)
+	}
+	if query.DueAfter != nil {
+		tx = tx.Where("due_date >= ?", *query.DueAfter)
+	}
+	if query.DueBefore != nil {
+		tx = tx.Where("due_date < ?", *query.DueBefore)
+	}
+	if query.HasDueDate {
+		tx = tx.Where("due_date IS NOT NULL")
+	}
+	return tx
+}
+
+// keysetCondition builds the WHERE clause selecting rows strictly after (or,
+// going backward, before) the cursor position in the given sort order:
+// (k1 > v1) OR (k1 = v1 AND k2 > v2) OR ... with task_id as the final key.
+func keysetCondition(sort []validations.SortKey, cursor *validations.TaskCursor, backward bool) (string, []any, error) {
+	type bound struct {
+		column string
+		desc   bool
+		value  any
+	}
+	bounds := make([]bound, 0, len(sort)+1)
+	for i, key := range sort {
+		value, err := parseCursorValue(key.Column, cursor.Values[i])
+		if err != nil {
+			return "", nil, err
+		}
+		bounds = append(bounds, bound{column: key.Column, desc: key.Desc, value: value})
+	}
+	taskID, err := uuid.FromString(cursor.TaskID)
+	if err != nil {
+		return "", nil, err
+	}
+	bounds = append(bounds, bound{column: "task_id", value: taskID})
+
+	var (
+		disjuncts []string
+		args      []any
+	)
+	for i, current := range bounds {
+		var parts []string
+		for _, previous := range bounds[:i] {
+			parts = append(parts, previous.column+" = ?")
+			args = append(args, previous.value)
+		}
+		operator := ">"
+		if current.desc != backward {
+			operator = "<"
+		}
+		parts = append(parts, current.column+" "+operator+" ?")
+		args = append(args, current.value)
+		disjuncts = append(disjuncts, "("+strings.Join(parts, " AND ")+")")
+	}
+	return "(" + strings.Join(disjuncts, " OR ") + ")", args, nil
+}
+
+func parseCursorValue(column, value string) (any, error) {
+	switch column {
+	case "created_at", "updated_at":
+		parsed, err := time.Parse(time.RFC3339Nano, value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid cursor value for %s: %w", column, err)
+		}
+		return parsed, nil
+	default:
+		return value, nil
+	}
+}
+
+func taskCursor(query validations.TaskQuery, task *models.Task, direction string) string {
+	return validations.EncodeTaskCursor(cursorAt(query, task, direction))
+}
+
+func cursorAt(query validations.TaskQuery, task *models.Task, direction string) validations.TaskCursor {
+	values := make([]string, len(query.Sort))
+	for i, key := range query.Sort {
+		switch key.Column {
+		case "created_at":
+			values[i] = task.CreatedAt.UTC().Format(time.RFC3339Nano)
+		case "updated_at":
+			values[i] = task.UpdatedAt.UTC().Format(time.RFC3339Nano)
+		case "title":
+			values[i] = task.Title
+		case "status":
+			values[i] = task.Status
+		}
+	}
+	return validations.TaskCursor{
+		Direction: direction,
+		Sort:      query.SortSignature(),
+		Values:    values,
+		TaskID:    task.TaskID.String(),
+	}
+}
+
+// EachTask calls fn for every task matching query, in query order. Tasks are
+// fetched a page at a time, so large listings are never held in memory.
+func EachTask(ctx context.Context, repo TaskRepository, query validations.TaskQuery, fn func(*models.Task) error) error {
+	query.Limit = validations.MaxTaskPageSize
+	query.Cursor = nil
+	query.Fields = nil
+	for {
+		page, err := repo.List(ctx, query)
+		if err != nil {
+			return err
+		}
+		for i := range page.Tasks {
+			if err := fn(&page.Tasks[i]); err != nil {
+				return err
+			}
+		}
+		if !page.HasNext || len(page.Tasks) == 0 {
+			return nil
+		}
+		cursor := cursorAt(query, &page.Tasks[len(page.Tasks)-1], validations.CursorNext)
+		query.Cursor = &cursor
+	}
+}
+
+// selectedColumns returns the columns to load for a sparse fieldset (nil
+// means all) and whether labels must be preloaded. Sort columns and task_id
+// are always loaded so cursors can be built.
+func selectedColumns(query validations.TaskQuery) ([]string, bool) {
+	if len(query.Fields) == 0 {
+		return nil, true
+	}
+
+	seen := map[string]bool{"task_id": true}
+	columns := []string{"task_id"}
+	add := func(column string) {
+		if !seen[column] {
+			seen[column] = true
+			columns = append(columns, column)
+		}
+	}
+
+	withLabels := false
+	for _, field := range query.Fields {
+		if field == "labels" {
+			withLabels = true
+			continue
+		}
+		add(taskFieldColumns[field])
+	}
+	for _, key := range query.Sort {
+		add(key.Column)
+	}
+	return columns, withLabels
+}
+
+// ts_headline marks matches with control characters rather than tags, so
+// that the text around them can be escaped before the tags are added.
+const (
+	highlightStart  = "\x02"
+	highlightStop   = "\x03"
+	headlineOptions = "StartSel=\"" + highlightStart + "\", StopSel=\"" + highlightStop + "\", MaxFragments=2, MaxWords=20, MinWords=5, FragmentDelimiter=\" … \""
+)
+
+// markHighlights escapes text delimited with highlightStart and
+// highlightStop as HTML, turning the delimiters into balanced <mark> tags.
+func markHighlights(text string) string {
+	var b strings.Builder
+	open := false
+	for len(text) > 0 {
+		i := strings.IndexAny(text, highlightStart+highlightStop)
+		if i < 0 {
+			b.WriteString(html.EscapeString(text))
+			break
+		}
+		b.WriteString(html.EscapeString(text[:i]))
+		switch {
+		case text[i:i+1] == highlightStart && !open:
+			b.WriteString("<mark>")
+			open = true
+		case text[i:i+1] == highlightStop && open:
+			b.WriteString("</mark>")
+			open = false
+		}
+		text = text[i+1:]
+	}
+	if open {
+		b.WriteString("</mark>")
+	}
+	return b.String()
+}
+
+// Search ranks tasks against the generated search_vector column (see
+// database.migrateTaskSearch) and highlights the matched fragments.
+func (r *taskRepository) Search(ctx context.Context, query validations.SearchQuery, page, limit int) (*TaskSearchResult, error) {
+	tsquery := query.TSQuery()
+	filtered := func() *gorm.DB {
+		tx := r.db.WithContext(ctx).Model(&models.Task{}).Scopes(models.VisibleTasks(query.VisibleTo))
+		if tsquery != "" {
+			tx = tx.Where("search_vector @@ to_tsquery('english', ?)", tsquery)
+		}
+		if len(query.Statuses) > 0 {
+			tx = tx.Where("status IN ?", query.Statuses)
+		}
+		if len(query.Labels) > 0 {
+			tx = tx.Where(`task_id IN (SELECT tl.task_id FROM task_labels tl JOIN "Labels" l ON l.label_id = tl.label_id WHERE l.name IN ?)`, query.Labels)
+		}
+		return tx
+	}
+
+	result := &TaskSearchResult{}
+	if err := filtered().Count(&result.Total).Error; err != nil {
+		return nil, err
+	}
+
+	var hits []struct {
+		TaskID               uuid.UUID
+		Rank                 float64
+		TitleHighlight       string
+		DescriptionHighlight string
+	}
+	tx := filtered()
+	if tsquery != "" {
+		tx = tx.Select(
+			"task_id, ts_rank_cd(search_vector, to_tsquery('english', ?)) AS rank, "+
+				"ts_headline('english', title, to_tsquery('english', ?), ?) AS title_highlight, "+
+				"ts_headline('english', description, to_tsquery('english', ?), ?) AS description_highlight",
+			tsquery, tsquery, headlineOptions+", HighlightAll=true", tsquery, headlineOptions,
+		)
+	} else {
+		tx = tx.Select("task_id, 0 AS rank, title AS title_highlight, description AS description_highlight")
+	}
+	if err := tx.Order("rank DESC, created_at DESC, task_id").
+		Offset((page - 1) * limit).
+		Limit(limit).
+		Scan(&hits).Error; err != nil {
+		return nil, err
+	}
+	if len(hits) == 0 {
+		result.Hits = []TaskSearchHit{}
+		return result, nil
+	}
+
+	ids := make([]uuid.UUID, len(hits))
+	for i, hit := range hits {
+		ids[i] = hit.TaskID
+	}
+	var tasks []models.Task
+	if err := r.db.WithContext(ctx).Preload("Labels").Where("task_id IN ?", ids).Find(&tasks).Error; err != nil {
+		return nil, err
+	}
+	byID := make(map[uuid.UUID]models.Task, len(tasks))
+	for _, task := range tasks {
+		byID[task.TaskID] = task
+	}
+
+	for _, hit := range hits {
+		task, ok := byID[hit.TaskID]
+		if !ok {
+			continue
+		}
+		result.Hits = append(result.Hits, TaskSearchHit{
+			Task: task,
+			Rank: hit.Rank,
+			Highlights: TaskHighlights{
+				Title:       markHighlights(hit.TitleHighlight),
+				Description: markHighlights(hit.DescriptionHighlight),
+			},
+		})
+	}
+	return result, nil
+}
+
+func escapeLike(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(value)
+}
@@ -0,0 +1,28 @@
+package routers
+
+import (
+	"ai-task-manager/controllers"
+	"ai-task-manager/middlewares"
+	"ai-task-manager/storage"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+func SetupAttachmentRouter(rg *gin.RouterGroup, db *gorm.DB, store storage.Storage) {
+
+	attachmentHandler := controllers.NewAttachmentController(db, store)
+	authMiddleware := middlewares.JWTVerifyForUser(db)
+	router := rg.Group("/attachments")
+
+	{
+		// Signed, time-limited links; the signature replaces the JWT here.
+		router.GET("/download/:attachmentID", attachmentHandler.DownloadAttachment)
+
+		router.POST("/upload-attachment/:taskID", authMiddleware, middlewares.Idempotency(db), attachmentHandler.UploadAttachment)
+		router.GET("/get-attachments/:taskID", authMiddleware, attachmentHandler.GetAttachments)
+		router.GET("/get-download-url/:attachmentID", authMiddleware, attachmentHandler.GetDownloadURL)
+		router.DELETE("/delete-attachment/:attachmentID", authMiddleware, attachmentHandler.DeleteAttachment)
+	}
+
+}
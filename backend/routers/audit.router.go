@@ -0,0 +1,23 @@
+package routers
+
+import (
+	"ai-task-manager/controllers"
+	"ai-task-manager/middlewares"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+func SetupAuditRouter(rg *gin.RouterGroup, db *gorm.DB) {
+
+	auditHandler := controllers.NewAuditController(db)
+	authMiddleware := middlewares.JWTVerifyForUser(db)
+	router := rg.Group("/audit")
+	router.Use(authMiddleware)
+
+	{
+		router.GET("/get-task-history/:taskID", auditHandler.GetTaskHistory)
+		router.GET("/get-audit-logs", middlewares.RequireAdmin(), auditHandler.GetAuditLogs)
+	}
+
+}
@@ -0,0 +1,35 @@
+package routers
+
+import (
+	"ai-task-manager/controllers"
+	"ai-task-manager/middlewares"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// SetupCalDAVRouter mounts the CalDAV tree on the engine root, where calendar
+// clients look for it. Clients can't send a JWT, so requests authenticate
+// with HTTP Basic using the account's email and password.
+func SetupCalDAVRouter(router *gin.Engine, db *gorm.DB) {
+
+	caldavHandler := controllers.NewCalDAVController(db)
+	authMiddleware := middlewares.BasicAuthForUser(db, "Tasks")
+	path := controllers.CalDAVPrefix + "/*path"
+
+	{
+		router.GET("/.well-known/caldav", func(c *gin.Context) {
+			c.Redirect(http.StatusMovedPermanently, controllers.CalDAVPrefix+"/")
+		})
+
+		router.OPTIONS(path, caldavHandler.Options)
+		router.Handle("PROPFIND", path, authMiddleware, caldavHandler.Propfind)
+		router.Handle("REPORT", path, authMiddleware, caldavHandler.Report)
+		router.GET(path, authMiddleware, caldavHandler.GetObject)
+		router.HEAD(path, authMiddleware, caldavHandler.GetObject)
+		router.PUT(path, authMiddleware, caldavHandler.PutObject)
+		router.DELETE(path, authMiddleware, caldavHandler.DeleteObject)
+	}
+
+}
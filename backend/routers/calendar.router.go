@@ -0,0 +1,28 @@
+package routers
+
+import (
+	"ai-task-manager/controllers"
+	"ai-task-manager/middlewares"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+func SetupCalendarRouter(rg *gin.RouterGroup, db *gorm.DB) {
+
+	calendarHandler := controllers.NewCalendarController(db)
+	authMiddleware := middlewares.JWTVerifyForUser(db)
+	router := rg.Group("/calendar")
+
+	{
+		// The secret token in the path replaces the JWT so calendar apps can
+		// subscribe to the feed.
+		router.GET("/feed/:token", calendarHandler.GetFeed)
+
+		// Not idempotent: a replay would have to store the token in plaintext.
+		router.POST("/create-feed-token", authMiddleware, calendarHandler.CreateFeedToken)
+		router.GET("/get-feed-token", authMiddleware, calendarHandler.GetFeedToken)
+		router.DELETE("/revoke-feed-token", authMiddleware, calendarHandler.RevokeFeedToken)
+	}
+
+}
@@ -0,0 +1,25 @@
+package routers
+
+import (
+	"ai-task-manager/controllers"
+	"ai-task-manager/middlewares"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+func SetupCommentRouter(rg *gin.RouterGroup, db *gorm.DB) {
+
+	commentHandler := controllers.NewCommentController(db)
+	authMiddleware := middlewares.JWTVerifyForUser(db)
+	router := rg.Group("/comments")
+	router.Use(authMiddleware, middlewares.Idempotency(db))
+
+	{
+		router.POST("/add-comment/:taskID", commentHandler.AddComment)
+		router.GET("/get-comments/:taskID", commentHandler.GetComments)
+		router.PATCH("/update-comment/:commentID", commentHandler.UpdateComment)
+		router.DELETE("/delete-comment/:commentID", commentHandler.DeleteComment)
+	}
+
+}
@@ -0,0 +1,25 @@
+package routers
+
+import (
+	"ai-task-manager/controllers"
+	"ai-task-manager/events"
+	"ai-task-manager/middlewares"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+func SetupEventRouter(rg *gin.RouterGroup, db *gorm.DB) {
+
+	eventHandler := controllers.NewEventController(events.Default)
+	router := rg.Group("/events")
+
+	{
+		// Schemas document the public event format, so they need no token.
+		router.GET("/get-schemas", eventHandler.GetEventSchemas)
+		router.GET("/get-schema/:type", eventHandler.GetEventSchema)
+		// Server-Sent Events, for clients that cannot keep a WebSocket open.
+		router.GET("/stream", middlewares.JWTVerifyForUser(db), eventHandler.Stream)
+	}
+
+}
@@ -0,0 +1,23 @@
+package routers
+
+import (
+	"ai-task-manager/controllers"
+	"ai-task-manager/middlewares"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+func SetupNotificationRouter(rg *gin.RouterGroup, db *gorm.DB) {
+
+	notificationHandler := controllers.NewNotificationController(db)
+	authMiddleware := middlewares.JWTVerifyForUser(db)
+	router := rg.Group("/notifications")
+	router.Use(authMiddleware)
+
+	{
+		router.GET("/get-notifications", notificationHandler.GetNotifications)
+		router.PATCH("/mark-as-read/:notificationID", notificationHandler.MarkNotificationRead)
+	}
+
+}
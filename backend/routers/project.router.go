@@ -0,0 +1,26 @@
+package routers
+
+import (
+	"ai-task-manager/controllers"
+	"ai-task-manager/middlewares"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+func SetupProjectRouter(rg *gin.RouterGroup, db *gorm.DB) {
+
+	projectHandler := controllers.NewProjectController(db)
+	authMiddleware := middlewares.JWTVerifyForUser(db)
+	router := rg.Group("/projects")
+	router.Use(authMiddleware, middlewares.Idempotency(db))
+
+	{
+		router.POST("/add-new-project", projectHandler.CreateProject)
+		router.GET("/get-all-projects", projectHandler.GetProjects)
+		router.GET("/get-project/:projectID", projectHandler.GetProject)
+		router.PUT("/update-project/:projectID", projectHandler.UpdateProject)
+		router.DELETE("/delete-project/:projectID", projectHandler.DeleteProject)
+	}
+
+}
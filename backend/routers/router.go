@@ -1,6 +1,8 @@
 package routers
 
 import (
+	"ai-task-manager/storage"
+
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 )
@@ -30,7 +32,16 @@ func SetupRouter(router *gin.Engine, db *gorm.DB) {
 	{
 		SetupTaskRouter(rg, db)
 		SetupUserRouter(rg, db)
-		SetWebSocketRoutes(router)
+		SetupCommentRouter(rg, db)
+		SetupNotificationRouter(rg, db)
+		SetupAttachmentRouter(rg, db, storage.Default)
+		SetupAuditRouter(rg, db)
+		SetupProjectRouter(rg, db)
+		SetupCalendarRouter(rg, db)
+		SetupEventRouter(rg, db)
+		SetupWebhookRouter(rg, db)
+		SetWebSocketRoutes(router, rg, db)
+		SetupCalDAVRouter(router, db)
 	}
 
 }
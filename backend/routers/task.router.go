@@ -13,7 +13,7 @@ func SetupTaskRouter(rg *gin.RouterGroup, db *gorm.DB) {
 	taskHandler := controllers.NewTaskController(db)
 	authMiddleware := middlewares.JWTVerifyForUser(db)
 	router := rg.Group("/tasks")
-	router.Use(authMiddleware)
+	router.Use(authMiddleware, middlewares.Idempotency(db))
 
 	{
 		router.GET("/", func(c *gin.Context) {
@@ -26,8 +26,20 @@ func SetupTaskRouter(rg *gin.RouterGroup, db *gorm.DB) {
 		router.POST("/add-new-task", taskHandler.CreateTask)
 		router.GET("/get-task/:taskID", taskHandler.GetTask)
 		router.GET("/get-all-task", taskHandler.GetAllTasks)
+		router.GET("/search", taskHandler.SearchTasks)
+		router.GET("/similar", taskHandler.FindSimilarTasks)
 		router.PUT("/update-task/:taskID", taskHandler.UpdateTask)
+		router.PATCH("/update-task/:taskID", taskHandler.PatchTask)
 		router.DELETE("/delete-task/:taskID", taskHandler.DeleteTask)
+		router.PATCH("/change-status/:taskID", taskHandler.ChangeStatusTask)
+		router.GET("/trash", taskHandler.GetTrash)
+		router.POST("/restore-task/:taskID", taskHandler.RestoreTask)
+		router.DELETE("/permanent-delete-task/:taskID", taskHandler.PermanentDeleteTask)
+		router.PUT("/set-task-labels/:taskID", taskHandler.SetTaskLabels)
+		router.POST("/bulk", taskHandler.BulkTasks)
+		router.GET("/export", taskHandler.ExportTasks)
+		router.POST("/import", taskHandler.ImportTasks)
+		router.POST("/migrate", taskHandler.MigrateTasks)
 	}
 
 }
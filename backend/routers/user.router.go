@@ -22,11 +22,12 @@ func SetupUserRouter(rg *gin.RouterGroup, db *gorm.DB) {
 		},
 		)
 
-		router.POST("/signup", userHandler.SignUp)
+		router.POST("/signup", middlewares.Idempotency(db), userHandler.SignUp)
 		router.POST("/signin", userHandler.SignIn)
 		router.GET("/signout", authMiddleware, userHandler.SignOut)
 		router.GET("/get-user-profile", authMiddleware, userHandler.GetUserProfile)
 		router.PATCH("/update-user-profile/c/:userID", authMiddleware, userHandler.UpdateUserProfile)
+		router.POST("/change-password", authMiddleware, middlewares.Idempotency(db), userHandler.ChangePassword)
 		router.DELETE("/delete-user-profile", authMiddleware, userHandler.DeleteUser)
 		router.GET("/get-all-users", authMiddleware, userHandler.GetAllUsers)
 
@@ -0,0 +1,29 @@
+package routers
+
+import (
+	"ai-task-manager/controllers"
+	"ai-task-manager/middlewares"
+	"ai-task-manager/webhooks"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+func SetupWebhookRouter(rg *gin.RouterGroup, db *gorm.DB) {
+
+	webhookHandler := controllers.NewWebhookController(db, webhooks.Default)
+	authMiddleware := middlewares.JWTVerifyForUser(db)
+	router := rg.Group("/webhooks")
+	router.Use(authMiddleware, middlewares.Idempotency(db))
+
+	{
+		router.POST("/create-webhook", webhookHandler.CreateWebhook)
+		router.GET("/get-webhooks", webhookHandler.GetWebhooks)
+		router.GET("/get-webhook/:webhookID", webhookHandler.GetWebhook)
+		router.PUT("/update-webhook/:webhookID", webhookHandler.UpdateWebhook)
+		router.DELETE("/delete-webhook/:webhookID", webhookHandler.DeleteWebhook)
+		router.POST("/send-test/:webhookID", webhookHandler.SendTestEvent)
+		router.GET("/get-deliveries/:webhookID", webhookHandler.GetDeliveries)
+	}
+
+}
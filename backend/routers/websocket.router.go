@@ -1,10 +1,22 @@
 package routers
 
 import (
+	"ai-task-manager/controllers"
+	"ai-task-manager/middlewares"
 	"ai-task-manager/websocket"
+
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
-func SetWebSocketRoutes(r *gin.Engine) {
-	r.GET("/ws", websocket.Manager.HandleConnections)
+func SetWebSocketRoutes(r *gin.Engine, rg *gin.RouterGroup, db *gorm.DB) {
+	webSocketHandler := controllers.NewWebSocketController(db, websocket.Manager)
+	authMiddleware := middlewares.JWTVerifyForUser(db)
+
+	r.GET("/ws", middlewares.WebSocketAuthForUser(db), websocket.Manager.HandleConnections)
+	// Tickets are secret and single-use, so a retry gets a new one rather
+	// than a stored copy.
+	rg.POST("/ws/create-ticket", authMiddleware, webSocketHandler.CreateTicket)
+	rg.GET("/ws/get-stats", authMiddleware, middlewares.RequireAdmin(), webSocketHandler.GetStats)
+	rg.GET("/ws/get-task-viewers/:taskID", authMiddleware, webSocketHandler.GetTaskViewers)
 }
@@ -0,0 +1,78 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// exercise stores, reads and deletes an object through store.
+func exercise(t *testing.T, store Storage) {
+	t.Helper()
+	ctx := context.Background()
+	key := ContentKey("ab12cd34")
+
+	if err := store.Put(ctx, key, strings.NewReader("report"), 6, "text/plain"); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	if exists, err := store.Exists(ctx, key); err != nil || !exists {
+		t.Fatalf("Exists after Put = %v, %v", exists, err)
+	}
+	body, err := store.Get(ctx, key)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	content, err := io.ReadAll(body)
+	body.Close()
+	if err != nil || string(content) != "report" {
+		t.Fatalf("Get = %q, %v, want \"report\"", content, err)
+	}
+
+	if err := store.Delete(ctx, key); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if exists, err := store.Exists(ctx, key); err != nil || exists {
+		t.Fatalf("Exists after Delete = %v, %v", exists, err)
+	}
+	if _, err := store.Get(ctx, key); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Get after Delete: %v, want ErrNotFound", err)
+	}
+	if err := store.Delete(ctx, key); err != nil {
+		t.Fatalf("deleting a missing object: %v", err)
+	}
+}
+
+// expectInvalidKeys checks that store refuses keys escaping its namespace.
+func expectInvalidKeys(t *testing.T, store Storage) {
+	t.Helper()
+	for _, key := range []string{"", "/etc/passwd", "../secret", "a/../../secret", "a//b", `a\b`} {
+		if err := store.Put(context.Background(), key, strings.NewReader("x"), 1, ""); err == nil {
+			t.Errorf("Put(%q) was accepted", key)
+		}
+	}
+}
+
+func TestLocalStorage(t *testing.T) {
+	root := t.TempDir()
+	store, err := NewLocalStorage(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	exercise(t, store)
+	expectInvalidKeys(t, store)
+
+	if err := store.Put(context.Background(), "a/b", strings.NewReader("x"), 1, ""); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(root, "a", "b")); err != nil {
+		t.Errorf("the object is not stored below the root: %v", err)
+	}
+	leftovers, _ := filepath.Glob(filepath.Join(root, "a", ".upload-*"))
+	if len(leftovers) > 0 {
+		t.Errorf("temporary files were left behind: %v", leftovers)
+	}
+}
@@ -0,0 +1,227 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+const (
+	testBucket    = "attachments"
+	testAccessKey = "minio"
+	testSecretKey = "minio-secret"
+)
+
+// s3Emulator is an in-memory stand-in for an S3-compatible server such as
+// MinIO. It checks the signature of every request and serves the objects of
+// one bucket on path-style and virtual-hosted URLs.
+type s3Emulator struct {
+	mu       sync.Mutex
+	objects  map[string][]byte
+	types    map[string]string
+	failWith int
+}
+
+func newS3Emulator(t *testing.T) (*s3Emulator, *httptest.Server) {
+	emulator := &s3Emulator{objects: make(map[string][]byte), types: make(map[string]string)}
+	server := httptest.NewServer(emulator)
+	t.Cleanup(server.Close)
+	return emulator, server
+}
+
+func (e *s3Emulator) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if err := verifySignature(r); err != nil {
+		http.Error(w, "SignatureDoesNotMatch: "+err.Error(), http.StatusForbidden)
+		return
+	}
+	path := r.URL.Path
+	if strings.HasPrefix(r.Host, testBucket+".") {
+		path = "/" + testBucket + path
+	}
+	key, ok := strings.CutPrefix(path, "/"+testBucket+"/")
+	if !ok {
+		http.Error(w, "NoSuchBucket", http.StatusNotFound)
+		return
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.failWith != 0 {
+		http.Error(w, "InternalError", e.failWith)
+		return
+	}
+	switch r.Method {
+	case http.MethodPut:
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		e.objects[key] = body
+		e.types[key] = r.Header.Get("Content-Type")
+	case http.MethodGet, http.MethodHead:
+		body, ok := e.objects[key]
+		if !ok {
+			http.Error(w, "NoSuchKey", http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", e.types[key])
+		w.Write(body)
+	case http.MethodDelete:
+		delete(e.objects, key)
+		delete(e.types, key)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.Error(w, "MethodNotAllowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// verifySignature recomputes the Signature Version 4 of r from the headers it
+// says it signed.
+func verifySignature(r *http.Request) error {
+	var credential, signedHeaders, signature string
+	for _, field := range strings.Split(strings.TrimPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 "), ", ") {
+		name, value, _ := strings.Cut(field, "=")
+		switch name {
+		case "Credential":
+			credential = value
+		case "SignedHeaders":
+			signedHeaders = value
+		case "Signature":
+			signature = value
+		}
+	}
+	accessKey, scope, _ := strings.Cut(credential, "/")
+	if accessKey != testAccessKey {
+		return fmt.Errorf("unknown access key %q", accessKey)
+	}
+	scopeParts := strings.Split(scope, "/")
+	if len(scopeParts) != 4 || scopeParts[2] != "s3" || scopeParts[3] != "aws4_request" {
+		return fmt.Errorf("malformed scope %q", scope)
+	}
+	amzDate := r.Header.Get("X-Amz-Date")
+	if !strings.HasPrefix(amzDate, scopeParts[0]) {
+		return fmt.Errorf("date %q is outside the scope %q", amzDate, scope)
+	}
+
+	var headers strings.Builder
+	for _, name := range strings.Split(signedHeaders, ";") {
+		value := r.Header.Get(name)
+		if name == "host" {
+			value = r.Host
+		}
+		headers.WriteString(name + ":" + value + "\n")
+	}
+	canonicalRequest := strings.Join([]string{
+		r.Method, r.URL.EscapedPath(), r.URL.RawQuery, headers.String(), signedHeaders,
+		r.Header.Get("X-Amz-Content-Sha256"),
+	}, "\n")
+	stringToSign := strings.Join([]string{"AWS4-HMAC-SHA256", amzDate, scope, hexSHA256([]byte(canonicalRequest))}, "\n")
+
+	key := []byte("AWS4" + testSecretKey)
+	for _, part := range scopeParts {
+		key = hmacSHA256(key, part)
+	}
+	if want := fmt.Sprintf("%x", hmacSHA256(key, stringToSign)); signature != want {
+		return errors.New("signature mismatch")
+	}
+	return nil
+}
+
+func newTestS3Storage(t *testing.T, endpoint string, pathStyle bool, client *http.Client) Storage {
+	t.Helper()
+	store, err := NewS3Storage(S3Options{
+		Endpoint:     endpoint,
+		Bucket:       testBucket,
+		AccessKey:    testAccessKey,
+		SecretKey:    testSecretKey,
+		UsePathStyle: pathStyle,
+		HTTPClient:   client,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return store
+}
+
+func TestS3StoragePathStyle(t *testing.T) {
+	emulator, server := newS3Emulator(t)
+	store := newTestS3Storage(t, server.URL, true, server.Client())
+	exercise(t, store)
+	expectInvalidKeys(t, store)
+
+	if err := store.Put(context.Background(), "a/b", strings.NewReader("x"), 1, "image/png"); err != nil {
+		t.Fatal(err)
+	}
+	if got := emulator.types["a/b"]; got != "image/png" {
+		t.Errorf("stored Content-Type = %q, want image/png", got)
+	}
+}
+
+func TestS3StorageVirtualHosted(t *testing.T) {
+	emulator, server := newS3Emulator(t)
+	// Send bucket.localhost to the emulator, as a wildcard DNS record would.
+	address := server.Listener.Addr().String()
+	client := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			return (&net.Dialer{Timeout: time.Second}).DialContext(ctx, network, address)
+		},
+	}}
+	t.Cleanup(client.CloseIdleConnections)
+	_, port, _ := net.SplitHostPort(address)
+	store := newTestS3Storage(t, "http://localhost:"+port, false, client)
+	exercise(t, store)
+
+	if err := store.Put(context.Background(), "a/b", strings.NewReader("x"), 1, ""); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := emulator.objects["a/b"]; !ok {
+		t.Errorf("the object was not stored in the bucket: %v", emulator.objects)
+	}
+}
+
+func TestS3StorageReportsServerErrors(t *testing.T) {
+	emulator, server := newS3Emulator(t)
+	store := newTestS3Storage(t, server.URL, true, server.Client())
+	emulator.failWith = http.StatusServiceUnavailable
+
+	ctx := context.Background()
+	if err := store.Put(ctx, "a/b", strings.NewReader("x"), 1, ""); err == nil || !strings.Contains(err.Error(), "503") {
+		t.Errorf("Put: %v, want a 503 error", err)
+	}
+	if _, err := store.Get(ctx, "a/b"); err == nil || errors.Is(err, ErrNotFound) {
+		t.Errorf("Get: %v, want a 503 error", err)
+	}
+	if _, err := store.Exists(ctx, "a/b"); err == nil {
+		t.Error("Exists succeeded")
+	}
+	if err := store.Delete(ctx, "a/b"); err == nil {
+		t.Error("Delete succeeded")
+	}
+}
+
+func TestS3StorageRejectsWrongCredentials(t *testing.T) {
+	_, server := newS3Emulator(t)
+	store, err := NewS3Storage(S3Options{
+		Endpoint:     server.URL,
+		Bucket:       testBucket,
+		AccessKey:    testAccessKey,
+		SecretKey:    "wrong",
+		UsePathStyle: true,
+		HTTPClient:   server.Client(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Put(context.Background(), "a/b", strings.NewReader("x"), 1, ""); err == nil || !strings.Contains(err.Error(), "403") {
+		t.Errorf("Put: %v, want a 403 error", err)
+	}
+}
@@ -0,0 +1,141 @@
+package taskio
+
+import (
+	"ai-task-manager/models"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var csvColumns = []string{"taskID", "title", "description", "status", "assignedTo", "projectID", "labels", "dueDate", "priority", "createdAt", "updatedAt"}
+
+type csvWriter struct {
+	w             *csv.Writer
+	headerWritten bool
+}
+
+func newCSVWriter(w io.Writer) *csvWriter {
+	return &csvWriter{w: csv.NewWriter(w)}
+}
+
+func (c *csvWriter) writeHeader() error {
+	if c.headerWritten {
+		return nil
+	}
+	c.headerWritten = true
+	return c.w.Write(csvColumns)
+}
+
+func (c *csvWriter) Write(task *models.Task) error {
+	if err := c.writeHeader(); err != nil {
+		return err
+	}
+	assignedTo, projectID := optionalUUID(task)
+	return c.w.Write([]string{
+		task.TaskID.String(),
+		task.Title,
+		task.Description,
+		task.Status,
+		assignedTo,
+		projectID,
+		strings.Join(labelNames(task), ","),
+		formatDueDate(task),
+		strconv.Itoa(task.Priority),
+		task.CreatedAt.UTC().Format(time.RFC3339),
+		task.UpdatedAt.UTC().Format(time.RFC3339),
+	})
+}
+
+func (c *csvWriter) Close() error {
+	if err := c.writeHeader(); err != nil {
+		return err
+	}
+	c.w.Flush()
+	return c.w.Error()
+}
+
+// csvReader maps columns by the header row. Only title is required; unknown
+// columns such as taskID or createdAt are ignored.
+type csvReader struct {
+	r       *csv.Reader
+	columns map[string]int
+	err     error
+}
+
+func newCSVReader(r io.Reader) *csvReader {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.ReuseRecord = true
+	return &csvReader{r: reader}
+}
+
+func (c *csvReader) readHeader() error {
+	header, err := c.r.Read()
+	if err == io.EOF {
+		return errors.New("file is empty")
+	}
+	if err != nil {
+		return err
+	}
+	c.columns = make(map[string]int, len(header))
+	for i, name := range header {
+		c.columns[strings.TrimSpace(strings.TrimPrefix(name, "\ufeff"))] = i
+	}
+	if _, ok := c.columns["title"]; !ok {
+		return errors.New("header must include a title column")
+	}
+	return nil
+}
+
+func (c *csvReader) Read() (*Record, error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+	if c.columns == nil {
+		if err := c.readHeader(); err != nil {
+			c.err = err
+			return nil, err
+		}
+	}
+
+	row, err := c.r.Read()
+	if err != nil {
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			return nil, &RowError{Line: parseErr.StartLine, Err: parseErr.Err}
+		}
+		return nil, err
+	}
+	line, _ := c.r.FieldPos(0)
+
+	field := func(name string) string {
+		i, ok := c.columns[name]
+		if !ok || i >= len(row) {
+			return ""
+		}
+		return strings.TrimSpace(row[i])
+	}
+	record := &Record{
+		Line:        line,
+		Title:       field("title"),
+		Description: field("description"),
+		Status:      field("status"),
+		Labels:      splitLabels(field("labels")),
+	}
+	if len(row) != len(c.columns) {
+		return nil, &RowError{Line: line, Err: fmt.Errorf("expected %d fields, got %d", len(c.columns), len(row))}
+	}
+	if record.DueDate, err = parseDueDate(field("dueDate")); err != nil {
+		return nil, &RowError{Line: line, Err: err}
+	}
+	if value := field("priority"); value != "" {
+		if record.Priority, err = strconv.Atoi(value); err != nil {
+			return nil, &RowError{Line: line, Err: fmt.Errorf("invalid priority %q", value)}
+		}
+	}
+	return record, nil
+}
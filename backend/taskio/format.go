@@ -0,0 +1,202 @@
+// Package taskio reads and writes tasks in the file formats supported by
+// task import and export.
+package taskio
+
+import (
+	"ai-task-manager/models"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+const (
+	FormatCSV      = "csv"
+	FormatJSONL    = "jsonl"
+	FormatMarkdown = "markdown"
+	FormatICS      = "ics"
+)
+
+var contentTypes = map[string]string{
+	FormatCSV:      "text/csv; charset=utf-8",
+	FormatJSONL:    "application/x-ndjson",
+	FormatMarkdown: "text/markdown; charset=utf-8",
+	FormatICS:      "text/calendar; charset=utf-8",
+}
+
+var extensions = map[string]string{
+	FormatCSV:      "csv",
+	FormatJSONL:    "jsonl",
+	FormatMarkdown: "md",
+	FormatICS:      "ics",
+}
+
+// ParseFormat resolves a format name, accepting common aliases.
+func ParseFormat(name string) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "csv":
+		return FormatCSV, nil
+	case "jsonl", "ndjson", "json":
+		return FormatJSONL, nil
+	case "markdown", "md":
+		return FormatMarkdown, nil
+	case "ics", "ical", "icalendar":
+		return FormatICS, nil
+	default:
+		return "", fmt.Errorf("unknown format %q: use csv, jsonl, markdown or ics", name)
+	}
+}
+
+// FormatFromContentType guesses the format of an upload from its media type.
+func FormatFromContentType(mediaType string) (string, bool) {
+	switch mediaType {
+	case "text/csv":
+		return FormatCSV, true
+	case "application/x-ndjson", "application/jsonl", "application/json":
+		return FormatJSONL, true
+	case "text/markdown", "text/x-markdown":
+		return FormatMarkdown, true
+	case "text/calendar":
+		return FormatICS, true
+	default:
+		return "", false
+	}
+}
+
+func ContentType(format string) string {
+	return contentTypes[format]
+}
+
+func Extension(format string) string {
+	return extensions[format]
+}
+
+// Record is one task read from an import file. Line is the line it starts
+// on, for error reporting; records from other tools' exports carry their
+// original ID in SourceID instead.
+type Record struct {
+	Line        int
+	SourceID    string
+	Title       string
+	Description string
+	Status      string
+	Labels      []string
+	DueDate     *time.Time
+	Priority    int
+	// Project names the project the task belongs to in the source tool.
+	Project  string
+	Comments []Comment
+}
+
+// Comment is a comment imported along with a task.
+type Comment struct {
+	Author    string
+	Body      string
+	CreatedAt time.Time
+}
+
+// RowError reports a row that could not be parsed. Reading can continue
+// after it.
+type RowError struct {
+	Line int
+	Err  error
+}
+
+func (e *RowError) Error() string {
+	return fmt.Sprintf("line %d: %v", e.Line, e.Err)
+}
+
+func (e *RowError) Unwrap() error {
+	return e.Err
+}
+
+// Reader streams records from an import file.
+type Reader interface {
+	// Read returns the next record, or io.EOF after the last one.
+	Read() (*Record, error)
+}
+
+// Writer streams tasks to an export file. Close must be called to flush it.
+type Writer interface {
+	Write(task *models.Task) error
+	Close() error
+}
+
+func NewReader(format string, r io.Reader) (Reader, error) {
+	switch format {
+	case FormatCSV:
+		return newCSVReader(r), nil
+	case FormatJSONL:
+		return newJSONLReader(r), nil
+	case FormatMarkdown:
+		return newMarkdownReader(r), nil
+	case FormatICS:
+		return newICSReader(r), nil
+	default:
+		return nil, fmt.Errorf("unknown format %q", format)
+	}
+}
+
+func NewWriter(format string, w io.Writer) (Writer, error) {
+	switch format {
+	case FormatCSV:
+		return newCSVWriter(w), nil
+	case FormatJSONL:
+		return newJSONLWriter(w), nil
+	case FormatMarkdown:
+		return newMarkdownWriter(w), nil
+	case FormatICS:
+		return NewCalendarWriter(w, ComponentTodo, "Tasks"), nil
+	default:
+		return nil, fmt.Errorf("unknown format %q", format)
+	}
+}
+
+// splitLabels parses a comma-separated label list.
+func splitLabels(value string) []string {
+	var labels []string
+	for _, label := range strings.Split(value, ",") {
+		if label = strings.TrimSpace(label); label != "" {
+			labels = append(labels, label)
+		}
+	}
+	return labels
+}
+
+func labelNames(task *models.Task) []string {
+	names := make([]string, len(task.Labels))
+	for i, label := range task.Labels {
+		names[i] = label.Name
+	}
+	return names
+}
+
+// parseDueDate accepts an RFC 3339 timestamp or a YYYY-MM-DD date.
+func parseDueDate(value string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	for _, layout := range []string{time.RFC3339, time.DateOnly} {
+		if parsed, err := time.Parse(layout, value); err == nil {
+			return &parsed, nil
+		}
+	}
+	return nil, fmt.Errorf("invalid due date %q: use RFC 3339 or YYYY-MM-DD", value)
+}
+
+func formatDueDate(task *models.Task) string {
+	if task.DueDate == nil {
+		return ""
+	}
+	return task.DueDate.UTC().Format(time.RFC3339)
+}
+
+func optionalUUID(task *models.Task) (assignedTo, projectID string) {
+	if !task.AssignedTo.IsNil() {
+		assignedTo = task.AssignedTo.String()
+	}
+	if task.ProjectID != nil {
+		projectID = task.ProjectID.String()
+	}
+	return assignedTo, projectID
+}
@@ -0,0 +1,425 @@
+package taskio
+
+import (
+	"ai-task-manager/models"
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Calendar components a task can be written as. To-dos keep the task status;
+// events show up in calendars that ignore to-dos.
+const (
+	ComponentTodo  = "VTODO"
+	ComponentEvent = "VEVENT"
+)
+
+const (
+	icsProductID     = "-//ai-task-manager//Tasks//EN"
+	icsUIDDomain     = "ai-task-manager"
+	icsDateTime      = "20060102T150405Z"
+	icsLocalDateTime = "20060102T150405"
+	icsDate          = "20060102"
+	// icsMaxLineOctets is the folding limit from RFC 5545 section 3.1.
+	icsMaxLineOctets = 75
+)
+
+var icsStatuses = map[string]string{
+	"pending":     "NEEDS-ACTION",
+	"in_progress": "IN-PROCESS",
+	"completed":   "COMPLETED",
+}
+
+// CalendarWriter writes tasks as an iCalendar file of to-dos or events.
+type CalendarWriter struct {
+	// UID returns the UID of a task's component; it defaults to CalendarUID.
+	UID func(task *models.Task) string
+
+	w             *bufio.Writer
+	component     string
+	name          string
+	headerWritten bool
+	err           error
+}
+
+// NewCalendarWriter writes tasks as an iCalendar file of to-dos or events.
+// Events need a date, so tasks without a due date are skipped for them.
+func NewCalendarWriter(w io.Writer, component, name string) *CalendarWriter {
+	return &CalendarWriter{UID: CalendarUID, w: bufio.NewWriter(w), component: component, name: name}
+}
+
+// CalendarUID is the UID of a task that was not created through a calendar.
+func CalendarUID(task *models.Task) string {
+	return task.TaskID.String() + "@" + icsUIDDomain
+}
+
+func (i *CalendarWriter) line(name, value string) {
+	if i.err != nil {
+		return
+	}
+	content := name + ":" + value
+	// Continuation lines start with a space, which counts towards the limit.
+	limit := icsMaxLineOctets
+	for len(content) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(content[cut]) {
+			cut--
+		}
+		_, i.err = i.w.WriteString(content[:cut] + "\r\n ")
+		content = content[cut:]
+		limit = icsMaxLineOctets - 1
+	}
+	if i.err == nil {
+		_, i.err = i.w.WriteString(content + "\r\n")
+	}
+}
+
+func (i *CalendarWriter) writeHeader() {
+	if i.headerWritten {
+		return
+	}
+	i.headerWritten = true
+	i.line("BEGIN", "VCALENDAR")
+	i.line("VERSION", "2.0")
+	i.line("PRODID", icsProductID)
+	i.line("CALSCALE", "GREGORIAN")
+	if i.name != "" {
+		i.line("X-WR-CALNAME", escapeICSText(i.name))
+	}
+}
+
+func (i *CalendarWriter) Write(task *models.Task) error {
+	if i.component == ComponentEvent && task.DueDate == nil {
+		return nil
+	}
+	i.writeHeader()
+
+	i.line("BEGIN", i.component)
+	i.line("UID", i.UID(task))
+	i.line("DTSTAMP", task.UpdatedAt.UTC().Format(icsDateTime))
+	i.line("CREATED", task.CreatedAt.UTC().Format(icsDateTime))
+	i.line("LAST-MODIFIED", task.UpdatedAt.UTC().Format(icsDateTime))
+	i.line("SEQUENCE", strconv.FormatInt(max(task.Version-1, 0), 10))
+	i.line("SUMMARY", escapeICSText(task.Title))
+	if task.Description != "" && task.Description != task.Title {
+		i.line("DESCRIPTION", escapeICSText(task.Description))
+	}
+	if task.DueDate != nil {
+		name := "DUE"
+		if i.component == ComponentEvent {
+			name = "DTSTART"
+		}
+		due := task.DueDate.UTC()
+		if due.Hour() == 0 && due.Minute() == 0 && due.Second() == 0 {
+			i.line(name+";VALUE=DATE", due.Format(icsDate))
+		} else {
+			i.line(name, due.Format(icsDateTime))
+		}
+	}
+	if i.component == ComponentEvent {
+		// Events have no task status of their own; keep ours alongside and
+		// don't block time in the calendar.
+		i.line("STATUS", "CONFIRMED")
+		i.line("TRANSP", "TRANSPARENT")
+		i.line("X-TASK-STATUS", strings.ToUpper(task.Status))
+	} else if status, ok := icsStatuses[task.Status]; ok {
+		i.line("STATUS", status)
+		if task.Status == "completed" {
+			i.line("PERCENT-COMPLETE", "100")
+		}
+	}
+	if task.Priority > 0 {
+		i.line("PRIORITY", strconv.Itoa(task.Priority))
+	}
+	if names := labelNames(task); len(names) > 0 {
+		for n, name := range names {
+			names[n] = escapeICSText(name)
+		}
+		i.line("CATEGORIES", strings.Join(names, ","))
+	}
+	i.line("END", i.component)
+	return i.err
+}
+
+func (i *CalendarWriter) Close() error {
+	i.writeHeader()
+	i.line("END", "VCALENDAR")
+	if i.err != nil {
+		return i.err
+	}
+	return i.w.Flush()
+}
+
+func escapeICSText(value string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`, "\r", "").Replace(value)
+}
+
+func unescapeICSText(value string) string {
+	return strings.NewReplacer(`\\`, `\`, `\;`, ";", `\,`, ",", `\n`, "\n", `\N`, "\n").Replace(value)
+}
+
+// splitICSList splits a comma-separated property value, honouring escaped
+// commas.
+func splitICSList(value string) []string {
+	var items []string
+	var current strings.Builder
+	for n := 0; n < len(value); n++ {
+		switch {
+		case value[n] == '\\' && n+1 < len(value):
+			current.WriteByte(value[n])
+			current.WriteByte(value[n+1])
+			n++
+		case value[n] == ',':
+			items = append(items, current.String())
+			current.Reset()
+		default:
+			current.WriteByte(value[n])
+		}
+	}
+	items = append(items, current.String())
+
+	var out []string
+	for _, item := range items {
+		if item = strings.TrimSpace(unescapeICSText(item)); item != "" {
+			out = append(out, item)
+		}
+	}
+	return out
+}
+
+// ReadCalendarObject reads the single VTODO of a calendar object resource,
+// such as the body of a CalDAV PUT.
+func ReadCalendarObject(r io.Reader) (*Record, error) {
+	reader := newICSReader(r)
+	record, err := reader.Read()
+	if err == io.EOF {
+		return nil, errors.New("calendar object has no VTODO")
+	}
+	if err != nil {
+		return nil, err
+	}
+	if _, err := reader.Read(); err != io.EOF {
+		return nil, errors.New("calendar object must contain exactly one VTODO")
+	}
+	return record, nil
+}
+
+// icsReader reads the VTODO components of an iCalendar file as records.
+// Events, journals and other components are skipped.
+type icsReader struct {
+	r       *bufio.Reader
+	line    int
+	started bool
+}
+
+func newICSReader(r io.Reader) *icsReader {
+	return &icsReader{r: bufio.NewReader(r)}
+}
+
+// next returns the next unfolded content line and the line it starts on.
+func (i *icsReader) next() (string, int, error) {
+	var content strings.Builder
+	start := 0
+	for {
+		if content.Len() > 0 {
+			b, err := i.r.Peek(1)
+			if err != nil || (b[0] != ' ' && b[0] != '\t') {
+				return content.String(), start, nil
+			}
+			i.r.ReadByte()
+		}
+		text, err := i.r.ReadString('\n')
+		if err != nil && (err != io.EOF || text == "") {
+			if err == io.EOF && content.Len() > 0 {
+				return content.String(), start, nil
+			}
+			return "", 0, err
+		}
+		i.line++
+		text = strings.TrimRight(text, "\r\n")
+		if content.Len() == 0 {
+			if strings.TrimSpace(text) == "" {
+				continue
+			}
+			start = i.line
+			text = strings.TrimPrefix(text, "\ufeff")
+		}
+		content.WriteString(text)
+	}
+}
+
+// parseContentLine splits "NAME;PARAM=VALUE:value" into its parts. Parameter
+// values may be quoted and contain ':' or ';'.
+func parseContentLine(content string) (string, map[string]string, string, error) {
+	params := make(map[string]string)
+	end := strings.IndexAny(content, ";:")
+	if end <= 0 {
+		return "", nil, "", fmt.Errorf("malformed content line %q", content)
+	}
+	name := strings.ToUpper(content[:end])
+	rest := content[end:]
+	for strings.HasPrefix(rest, ";") {
+		rest = rest[1:]
+		eq := strings.IndexByte(rest, '=')
+		if eq < 0 {
+			return "", nil, "", fmt.Errorf("malformed parameter in %s", name)
+		}
+		key := strings.ToUpper(rest[:eq])
+		rest = rest[eq+1:]
+		var value string
+		if strings.HasPrefix(rest, `"`) {
+			closing := strings.IndexByte(rest[1:], '"')
+			if closing < 0 {
+				return "", nil, "", fmt.Errorf("unterminated quoted parameter in %s", name)
+			}
+			value, rest = rest[1:closing+1], rest[closing+2:]
+		} else {
+			stop := strings.IndexAny(rest, ";:")
+			if stop < 0 {
+				return "", nil, "", fmt.Errorf("malformed parameter in %s", name)
+			}
+			value, rest = rest[:stop], rest[stop:]
+		}
+		params[key] = value
+	}
+	if !strings.HasPrefix(rest, ":") {
+		return "", nil, "", fmt.Errorf("missing value in %s", name)
+	}
+	return name, params, rest[1:], nil
+}
+
+func (i *icsReader) Read() (*Record, error) {
+	var (
+		record *Record
+		rowErr error
+		depth  int // components open inside the current VTODO
+	)
+	for {
+		content, line, err := i.next()
+		if err == io.EOF {
+			if !i.started {
+				return nil, errors.New("file is empty")
+			}
+			if record != nil {
+				return nil, &RowError{Line: record.Line, Err: errors.New("VTODO is not closed")}
+			}
+			return nil, io.EOF
+		}
+		if err != nil {
+			return nil, err
+		}
+		name, params, value, err := parseContentLine(content)
+		if !i.started {
+			if err != nil || name != "BEGIN" || !strings.EqualFold(value, "VCALENDAR") {
+				return nil, errors.New("not an iCalendar file: expected BEGIN:VCALENDAR")
+			}
+			i.started = true
+			continue
+		}
+		if err != nil {
+			if record != nil && rowErr == nil {
+				rowErr = err
+			}
+			continue
+		}
+
+		if record == nil {
+			if name == "BEGIN" && strings.EqualFold(value, ComponentTodo) {
+				record = &Record{Line: line, Status: "pending"}
+			}
+			continue
+		}
+		switch {
+		case name == "BEGIN":
+			depth++
+			continue
+		case name == "END" && depth > 0:
+			depth--
+			continue
+		case name == "END":
+			if rowErr != nil {
+				return nil, &RowError{Line: record.Line, Err: rowErr}
+			}
+			if strings.TrimSpace(record.Description) == "" {
+				record.Description = record.Title
+			}
+			return record, nil
+		case depth > 0:
+			// Properties of alarms and other nested components.
+			continue
+		}
+
+		switch name {
+		case "UID":
+			record.SourceID = value
+		case "SUMMARY":
+			record.Title = strings.TrimSpace(unescapeICSText(value))
+		case "DESCRIPTION":
+			record.Description = strings.TrimSpace(unescapeICSText(value))
+		case "STATUS":
+			switch strings.ToUpper(value) {
+			case "IN-PROCESS":
+				record.Status = "in_progress"
+			case "COMPLETED", "CANCELLED":
+				record.Status = "completed"
+			default:
+				record.Status = "pending"
+			}
+		case "COMPLETED":
+			if record.Status == "pending" {
+				record.Status = "completed"
+			}
+		case "PRIORITY":
+			priority, err := strconv.Atoi(strings.TrimSpace(value))
+			if err != nil && rowErr == nil {
+				rowErr = fmt.Errorf("invalid PRIORITY %q", value)
+			}
+			record.Priority = priority
+		case "CATEGORIES":
+			record.Labels = append(record.Labels, splitICSList(value)...)
+		case "DUE":
+			due, err := parseICSTime(value, params)
+			if err != nil && rowErr == nil {
+				rowErr = err
+			}
+			record.DueDate = due
+		}
+	}
+}
+
+// parseICSTime reads a DATE or DATE-TIME value. Times with a TZID are
+// converted from that zone; floating times are taken as UTC.
+func parseICSTime(value string, params map[string]string) (*time.Time, error) {
+	value = strings.TrimSpace(value)
+	if params["VALUE"] == "DATE" || len(value) == len(icsDate) {
+		parsed, err := time.Parse(icsDate, value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid date %q", value)
+		}
+		return &parsed, nil
+	}
+	if strings.HasSuffix(value, "Z") {
+		parsed, err := time.Parse(icsDateTime, value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid date-time %q", value)
+		}
+		return &parsed, nil
+	}
+	location := time.UTC
+	if tzid := params["TZID"]; tzid != "" {
+		if loaded, err := time.LoadLocation(strings.TrimPrefix(tzid, "/")); err == nil {
+			location = loaded
+		}
+	}
+	parsed, err := time.ParseInLocation(icsLocalDateTime, value, location)
+	if err != nil {
+		return nil, fmt.Errorf("invalid date-time %q", value)
+	}
+	parsed = parsed.UTC()
+	return &parsed, nil
+}
@@ -0,0 +1,269 @@
+package taskio
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+)
+
+// Jira writes timestamps like 2024-01-31T10:00:00.000+0000 in JSON and, by
+// default, 31/Jan/24 10:00 AM in CSV.
+var jiraTimeLayouts = []string{
+	"2006-01-02T15:04:05.000-0700",
+	"02/Jan/06 3:04 PM",
+	"02/Jan/06",
+	"2006-01-02 15:04",
+}
+
+// jiraStatusCategories maps Jira's fixed status categories, which are more
+// reliable than workflow-specific status names.
+var jiraStatusCategories = map[string]string{
+	"new":           "pending",
+	"to do":         "pending",
+	"indeterminate": "in_progress",
+	"in progress":   "in_progress",
+	"done":          "completed",
+}
+
+func jiraStatus(category, name string) string {
+	if status, ok := jiraStatusCategories[strings.ToLower(category)]; ok {
+		return status
+	}
+	return statusFromName(name)
+}
+
+// parseJiraJSON reads issues from a Jira search result ({"issues": [...]})
+// or a plain array of issues. Descriptions and comments may be plain text
+// (API v2) or Atlassian Document Format (API v3).
+func parseJiraJSON(r io.Reader, unmapped unmappedFields) ([]Record, error) {
+	var raw json.RawMessage
+	if err := json.NewDecoder(r).Decode(&raw); err != nil {
+		return nil, fmt.Errorf("not a Jira JSON export: %w", err)
+	}
+	var issues []map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &issues); err != nil {
+		var result struct {
+			Issues []map[string]json.RawMessage `json:"issues"`
+		}
+		if err := json.Unmarshal(raw, &result); err != nil || result.Issues == nil {
+			return nil, errors.New("not a Jira JSON export: missing issues")
+		}
+		issues = result.Issues
+	}
+
+	records := make([]Record, 0, len(issues))
+	for _, issue := range issues {
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal(issue["fields"], &fields); err != nil {
+			return nil, fmt.Errorf("issue %s: missing fields", jsonString(issue["key"]))
+		}
+		unmapped.note("fields.", fields, "summary", "description", "status", "labels", "duedate", "project", "comment", "subtasks", "created", "updated", "creator", "reporter", "issuetype", "statuscategorychangedate", "lastViewed", "watches", "votes", "workratio", "progress", "aggregateprogress")
+
+		var status struct {
+			Name           string `json:"name"`
+			StatusCategory struct {
+				Key string `json:"key"`
+			} `json:"statusCategory"`
+		}
+		_ = json.Unmarshal(fields["status"], &status)
+		var project struct {
+			Name string `json:"name"`
+		}
+		_ = json.Unmarshal(fields["project"], &project)
+
+		key := jsonString(issue["key"])
+		record := Record{
+			SourceID:    key,
+			Title:       jsonString(fields["summary"]),
+			Description: jiraText(fields["description"]),
+			Status:      jiraStatus(status.StatusCategory.Key, status.Name),
+			Project:     project.Name,
+		}
+		_ = json.Unmarshal(fields["labels"], &record.Labels)
+		if due, ok := parseFlexibleTime(jsonString(fields["duedate"]), jiraTimeLayouts...); ok {
+			record.DueDate = &due
+		}
+
+		var subtasks []struct {
+			Key    string `json:"key"`
+			Fields struct {
+				Summary string `json:"summary"`
+				Status  struct {
+					Name           string `json:"name"`
+					StatusCategory struct {
+						Key string `json:"key"`
+					} `json:"statusCategory"`
+				} `json:"status"`
+			} `json:"fields"`
+		}
+		_ = json.Unmarshal(fields["subtasks"], &subtasks)
+		items := make([]checklistItemData, len(subtasks))
+		for i, subtask := range subtasks {
+			done := jiraStatus(subtask.Fields.Status.StatusCategory.Key, subtask.Fields.Status.Name) == "completed"
+			items[i] = checklistItemData{text: subtask.Key + " " + subtask.Fields.Summary, done: done}
+		}
+		record.Description = appendParagraph(record.Description, checklistMarkdown("Subtasks", items))
+
+		var comments struct {
+			Comments []struct {
+				Author struct {
+					DisplayName string `json:"displayName"`
+				} `json:"author"`
+				Body    json.RawMessage `json:"body"`
+				Created string          `json:"created"`
+			} `json:"comments"`
+		}
+		_ = json.Unmarshal(fields["comment"], &comments)
+		for _, c := range comments.Comments {
+			comment := Comment{Author: c.Author.DisplayName, Body: jiraText(c.Body)}
+			if created, ok := parseFlexibleTime(c.Created, jiraTimeLayouts...); ok {
+				comment.CreatedAt = created
+			}
+			record.Comments = append(record.Comments, comment)
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+// jiraText returns plain text from a Jira string or an Atlassian Document
+// Format node tree.
+func jiraText(raw json.RawMessage) string {
+	if isEmptyJSON(raw) {
+		return ""
+	}
+	var text string
+	if err := json.Unmarshal(raw, &text); err == nil {
+		return text
+	}
+	var node adfNode
+	if err := json.Unmarshal(raw, &node); err != nil {
+		return ""
+	}
+	var b strings.Builder
+	node.render(&b)
+	return strings.TrimSpace(b.String())
+}
+
+type adfNode struct {
+	Type    string    `json:"type"`
+	Text    string    `json:"text"`
+	Content []adfNode `json:"content"`
+}
+
+func (n adfNode) render(b *strings.Builder) {
+	switch n.Type {
+	case "text":
+		b.WriteString(n.Text)
+		return
+	case "hardBreak":
+		b.WriteString("\n")
+		return
+	case "listItem":
+		b.WriteString("- ")
+	}
+	for _, child := range n.Content {
+		child.render(b)
+	}
+	switch n.Type {
+	case "paragraph", "heading", "codeBlock", "blockquote":
+		b.WriteString("\n\n")
+	case "listItem":
+		if !strings.HasSuffix(b.String(), "\n") {
+			b.WriteString("\n")
+		}
+	}
+}
+
+// parseJiraCSV reads Jira's "Export CSV (all fields)" output. Jira repeats
+// column names for multi-valued fields such as Labels and Comment, and
+// writes comments as "date;author;body".
+func parseJiraCSV(r io.Reader, unmapped unmappedFields) ([]Record, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("reading header: %w", err)
+	}
+	columns := make(map[string][]int)
+	for i, name := range header {
+		name = strings.TrimSpace(strings.TrimPrefix(name, "\ufeff"))
+		columns[name] = append(columns[name], i)
+	}
+	if _, ok := columns["Summary"]; !ok {
+		return nil, errors.New("not a Jira CSV export: missing Summary column")
+	}
+	known := []string{"Summary", "Issue key", "Description", "Status", "Status Category", "Labels", "Due date", "Due Date", "Project name", "Comment", "Issue id", "Created", "Updated", "Issue Type", "Reporter", "Creator"}
+
+	var records []Record
+	for {
+		row, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		line, _ := reader.FieldPos(0)
+		values := func(name string) []string {
+			var out []string
+			for _, i := range columns[name] {
+				if i < len(row) && strings.TrimSpace(row[i]) != "" {
+					out = append(out, strings.TrimSpace(row[i]))
+				}
+			}
+			return out
+		}
+		first := func(names ...string) string {
+			for _, name := range names {
+				if v := values(name); len(v) > 0 {
+					return v[0]
+				}
+			}
+			return ""
+		}
+		for name, indexes := range columns {
+			if slices.Contains(known, name) {
+				continue
+			}
+			for _, i := range indexes {
+				if i < len(row) && strings.TrimSpace(row[i]) != "" {
+					unmapped[name]++
+					break
+				}
+			}
+		}
+
+		record := Record{
+			Line:        line,
+			SourceID:    first("Issue key"),
+			Title:       first("Summary"),
+			Description: first("Description"),
+			Status:      jiraStatus(first("Status Category"), first("Status")),
+			Labels:      values("Labels"),
+			Project:     first("Project name"),
+		}
+		if due, ok := parseFlexibleTime(first("Due date", "Due Date"), jiraTimeLayouts...); ok {
+			record.DueDate = &due
+		}
+		for _, value := range values("Comment") {
+			parts := strings.SplitN(value, ";", 3)
+			if len(parts) != 3 {
+				record.Comments = append(record.Comments, Comment{Body: value})
+				continue
+			}
+			comment := Comment{Author: parts[1], Body: parts[2]}
+			if created, ok := parseFlexibleTime(parts[0], jiraTimeLayouts...); ok {
+				comment.CreatedAt = created
+			}
+			record.Comments = append(record.Comments, comment)
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
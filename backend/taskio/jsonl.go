@@ -0,0 +1,129 @@
+package taskio
+
+import (
+	"ai-task-manager/models"
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+)
+
+// maxJSONLLineSize bounds a single JSON Lines record.
+const maxJSONLLineSize = 1 << 20
+
+type jsonlTask struct {
+	TaskID      string    `json:"taskID"`
+	Title       string    `json:"title"`
+	Description string    `json:"description"`
+	Status      string    `json:"status"`
+	AssignedTo  string    `json:"assignedTo,omitempty"`
+	ProjectID   string    `json:"projectID,omitempty"`
+	Labels      []string  `json:"labels"`
+	DueDate     string    `json:"dueDate,omitempty"`
+	Priority    int       `json:"priority"`
+	CreatedAt   time.Time `json:"createdAt"`
+	UpdatedAt   time.Time `json:"updatedAt"`
+}
+
+type jsonlWriter struct {
+	w       *bufio.Writer
+	encoder *json.Encoder
+}
+
+func newJSONLWriter(w io.Writer) *jsonlWriter {
+	buffered := bufio.NewWriter(w)
+	return &jsonlWriter{w: buffered, encoder: json.NewEncoder(buffered)}
+}
+
+func (j *jsonlWriter) Write(task *models.Task) error {
+	assignedTo, projectID := optionalUUID(task)
+	return j.encoder.Encode(jsonlTask{
+		TaskID:      task.TaskID.String(),
+		Title:       task.Title,
+		Description: task.Description,
+		Status:      task.Status,
+		AssignedTo:  assignedTo,
+		ProjectID:   projectID,
+		Labels:      labelNames(task),
+		DueDate:     formatDueDate(task),
+		Priority:    task.Priority,
+		CreatedAt:   task.CreatedAt.UTC(),
+		UpdatedAt:   task.UpdatedAt.UTC(),
+	})
+}
+
+func (j *jsonlWriter) Close() error {
+	return j.w.Flush()
+}
+
+type jsonlReader struct {
+	scanner *bufio.Scanner
+	line    int
+}
+
+func newJSONLReader(r io.Reader) *jsonlReader {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxJSONLLineSize)
+	return &jsonlReader{scanner: scanner}
+}
+
+func (j *jsonlReader) Read() (*Record, error) {
+	for j.scanner.Scan() {
+		j.line++
+		text := bytes.TrimSpace(j.scanner.Bytes())
+		if len(text) == 0 {
+			continue
+		}
+
+		var row struct {
+			Title       string          `json:"title"`
+			Description string          `json:"description"`
+			Status      string          `json:"status"`
+			Labels      json.RawMessage `json:"labels"`
+			DueDate     string          `json:"dueDate"`
+			Priority    int             `json:"priority"`
+		}
+		if err := json.Unmarshal(text, &row); err != nil {
+			return nil, &RowError{Line: j.line, Err: err}
+		}
+		labels, err := decodeLabels(row.Labels)
+		if err != nil {
+			return nil, &RowError{Line: j.line, Err: err}
+		}
+		dueDate, err := parseDueDate(row.DueDate)
+		if err != nil {
+			return nil, &RowError{Line: j.line, Err: err}
+		}
+		return &Record{
+			Line:        j.line,
+			Title:       row.Title,
+			Description: row.Description,
+			Status:      row.Status,
+			Labels:      labels,
+			DueDate:     dueDate,
+			Priority:    row.Priority,
+		}, nil
+	}
+	if err := j.scanner.Err(); err != nil {
+		return nil, err
+	}
+	return nil, io.EOF
+}
+
+// decodeLabels accepts labels as an array of names or a comma-separated string.
+func decodeLabels(raw json.RawMessage) ([]string, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil, nil
+	}
+	var list []string
+	if err := json.Unmarshal(raw, &list); err == nil {
+		return list, nil
+	}
+	var joined string
+	if err := json.Unmarshal(raw, &joined); err == nil {
+		return splitLabels(joined), nil
+	}
+	return nil, fmt.Errorf("labels must be an array of strings or a comma-separated string")
+}
@@ -0,0 +1,148 @@
+package taskio
+
+import (
+	"ai-task-manager/models"
+	"bufio"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// Markdown checklists use "- [ ]" for pending, "- [/]" for in-progress and
+// "- [x]" for completed tasks. Labels follow the title as #hashtags and the
+// description is indented below the item:
+//
+//   - [x] Ship the release #backend #ops
+//     Tag the build and publish the notes.
+var (
+	checklistItem = regexp.MustCompile(`^\s*[-*+] \[([ xX/])\] (.*)$`)
+	trailingTag   = regexp.MustCompile(`\s+#([^\s#]+)$`)
+)
+
+var checkboxStatus = map[string]string{
+	" ": "pending",
+	"/": "in_progress",
+	"x": "completed",
+	"X": "completed",
+}
+
+var statusCheckbox = map[string]string{
+	"pending":     " ",
+	"in_progress": "/",
+	"completed":   "x",
+}
+
+type markdownWriter struct {
+	w *bufio.Writer
+}
+
+func newMarkdownWriter(w io.Writer) *markdownWriter {
+	return &markdownWriter{w: bufio.NewWriter(w)}
+}
+
+func (m *markdownWriter) Write(task *models.Task) error {
+	checkbox, ok := statusCheckbox[task.Status]
+	if !ok {
+		checkbox = " "
+	}
+	line := "- [" + checkbox + "] " + strings.Join(strings.Fields(task.Title), " ")
+	for _, label := range labelNames(task) {
+		// Hashtags end at whitespace, so spaces inside labels become dashes.
+		line += " #" + strings.Join(strings.Fields(label), "-")
+	}
+	if _, err := m.w.WriteString(line + "\n"); err != nil {
+		return err
+	}
+
+	description := strings.TrimSpace(task.Description)
+	if description == "" || description == task.Title {
+		return nil
+	}
+	for _, descriptionLine := range strings.Split(description, "\n") {
+		if _, err := m.w.WriteString(strings.TrimRight("  "+descriptionLine, " \t\r") + "\n"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *markdownWriter) Close() error {
+	return m.w.Flush()
+}
+
+// markdownReader turns checklist items into records. Indented lines below an
+// item form its description; any other text ends the item and is ignored.
+// Items without a description use their title, since tasks require one.
+type markdownReader struct {
+	scanner     *bufio.Scanner
+	line        int
+	current     *Record
+	description []string
+}
+
+func newMarkdownReader(r io.Reader) *markdownReader {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxJSONLLineSize)
+	return &markdownReader{scanner: scanner}
+}
+
+func (m *markdownReader) Read() (*Record, error) {
+	for m.scanner.Scan() {
+		m.line++
+		text := m.scanner.Text()
+
+		if match := checklistItem.FindStringSubmatch(text); match != nil {
+			finished := m.finish()
+			m.start(match[1], match[2])
+			if finished != nil {
+				return finished, nil
+			}
+			continue
+		}
+
+		if m.current != nil && (strings.TrimSpace(text) == "" || strings.HasPrefix(text, "  ") || strings.HasPrefix(text, "\t")) {
+			m.description = append(m.description, strings.TrimSpace(text))
+			continue
+		}
+
+		if finished := m.finish(); finished != nil {
+			return finished, nil
+		}
+	}
+	if err := m.scanner.Err(); err != nil {
+		return nil, err
+	}
+	if finished := m.finish(); finished != nil {
+		return finished, nil
+	}
+	return nil, io.EOF
+}
+
+func (m *markdownReader) start(checkbox, text string) {
+	record := &Record{Line: m.line, Status: checkboxStatus[checkbox]}
+	text = strings.TrimSpace(text)
+	for {
+		match := trailingTag.FindStringSubmatchIndex(text)
+		if match == nil {
+			break
+		}
+		record.Labels = append([]string{text[match[2]:match[3]]}, record.Labels...)
+		text = strings.TrimSpace(text[:match[0]])
+	}
+	record.Title = text
+	m.current = record
+	m.description = m.description[:0]
+}
+
+func (m *markdownReader) finish() *Record {
+	record := m.current
+	if record == nil {
+		return nil
+	}
+	m.current = nil
+	record.Description = strings.TrimSpace(strings.Join(m.description, "\n"))
+	if record.Description == "" {
+		record.Description = record.Title
+	}
+	return record
+}
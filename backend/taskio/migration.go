@@ -0,0 +1,223 @@
+package taskio
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	SourceTodoist = "todoist"
+	SourceTrello  = "trello"
+	SourceJira    = "jira"
+)
+
+// Migration is the content of another tool's export file mapped onto
+// records. Unmapped lists the source fields that had values but no place in
+// our models, so users know what was left behind.
+type Migration struct {
+	Source   string
+	Records  []Record
+	Unmapped []UnmappedField
+}
+
+type UnmappedField struct {
+	Field string `json:"field"`
+	Count int    `json:"count"`
+}
+
+// ParseMigration reads an export file from Todoist (CSV or JSON backup),
+// Trello (board JSON) or Jira (CSV or JSON issue export). defaultProject
+// names the project for exports that don't carry one, such as Todoist's
+// per-project CSV files.
+func ParseMigration(source string, r io.Reader, defaultProject string) (*Migration, error) {
+	buffered := bufio.NewReader(r)
+	isJSON, err := looksLikeJSON(buffered)
+	if err != nil {
+		return nil, err
+	}
+
+	unmapped := make(unmappedFields)
+	var records []Record
+	switch {
+	case source == SourceTodoist && isJSON:
+		records, err = parseTodoistJSON(buffered, unmapped)
+	case source == SourceTodoist:
+		records, err = parseTodoistCSV(buffered, defaultProject, unmapped)
+	case source == SourceTrello && isJSON:
+		records, err = parseTrelloJSON(buffered, unmapped)
+	case source == SourceTrello:
+		return nil, fmt.Errorf("trello exports must be board JSON files")
+	case source == SourceJira && isJSON:
+		records, err = parseJiraJSON(buffered, unmapped)
+	case source == SourceJira:
+		records, err = parseJiraCSV(buffered, unmapped)
+	default:
+		return nil, fmt.Errorf("unknown source %q: use todoist, trello or jira", source)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range records {
+		if records[i].Project == "" {
+			records[i].Project = defaultProject
+		}
+		// Tasks require a description; most tools don't.
+		if strings.TrimSpace(records[i].Description) == "" {
+			records[i].Description = records[i].Title
+		}
+	}
+	return &Migration{Source: source, Records: records, Unmapped: unmapped.list()}, nil
+}
+
+func looksLikeJSON(r *bufio.Reader) (bool, error) {
+	for {
+		b, err := r.Peek(1)
+		if err == io.EOF {
+			return false, fmt.Errorf("file is empty")
+		}
+		if err != nil {
+			return false, err
+		}
+		switch b[0] {
+		case ' ', '\t', '\r', '\n':
+			r.ReadByte()
+			continue
+		case 0xEF:
+			// UTF-8 byte order mark.
+			if bom, _ := r.Peek(3); bytes.Equal(bom, []byte{0xEF, 0xBB, 0xBF}) {
+				r.Discard(3)
+				continue
+			}
+		}
+		return b[0] == '{' || b[0] == '[', nil
+	}
+}
+
+// unmappedFields counts source fields that were present but not imported.
+type unmappedFields map[string]int
+
+// note records every non-empty member of object that is not in known.
+func (u unmappedFields) note(prefix string, object map[string]json.RawMessage, known ...string) {
+	for key, value := range object {
+		if isEmptyJSON(value) || slices.Contains(known, key) {
+			continue
+		}
+		u[prefix+key]++
+	}
+}
+
+func (u unmappedFields) list() []UnmappedField {
+	fields := make([]UnmappedField, 0, len(u))
+	for field, count := range u {
+		fields = append(fields, UnmappedField{Field: field, Count: count})
+	}
+	sort.Slice(fields, func(i, j int) bool { return fields[i].Field < fields[j].Field })
+	return fields
+}
+
+func isEmptyJSON(value json.RawMessage) bool {
+	switch strings.TrimSpace(string(value)) {
+	case "", "null", `""`, "[]", "{}", "false", "0":
+		return true
+	}
+	return false
+}
+
+// jsonString decodes a JSON string or number, as IDs are either depending on
+// the tool and API version.
+func jsonString(raw json.RawMessage) string {
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return s
+	}
+	var n json.Number
+	if err := json.Unmarshal(raw, &n); err == nil {
+		return n.String()
+	}
+	return ""
+}
+
+// jsonBool decodes a JSON boolean or 0/1.
+func jsonBool(raw json.RawMessage) bool {
+	var b bool
+	if err := json.Unmarshal(raw, &b); err == nil {
+		return b
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(string(raw)))
+	return err == nil && n != 0
+}
+
+// statusFromName maps a workflow column or status name onto our statuses.
+func statusFromName(name string) string {
+	name = strings.ToLower(name)
+	for _, word := range []string{"done", "complete", "closed", "resolved", "finished", "shipped"} {
+		if strings.Contains(name, word) {
+			return "completed"
+		}
+	}
+	for _, word := range []string{"progress", "doing", "review", "active", "started", "testing"} {
+		if strings.Contains(name, word) {
+			return "in_progress"
+		}
+	}
+	return "pending"
+}
+
+// checklistMarkdown renders checklist items the way the Markdown export does,
+// so they survive a round trip through this tool.
+func checklistMarkdown(title string, items []checklistItemData) string {
+	if len(items) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	if title != "" {
+		b.WriteString(title + ":\n")
+	}
+	for _, item := range items {
+		checkbox := " "
+		if item.done {
+			checkbox = "x"
+		}
+		b.WriteString("- [" + checkbox + "] " + strings.Join(strings.Fields(item.text), " ") + "\n")
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+type checklistItemData struct {
+	text string
+	done bool
+}
+
+func appendParagraph(text, paragraph string) string {
+	if paragraph == "" {
+		return text
+	}
+	if strings.TrimSpace(text) == "" {
+		return paragraph
+	}
+	return text + "\n\n" + paragraph
+}
+
+// parseFlexibleTime tries the timestamp layouts used by the supported tools.
+func parseFlexibleTime(value string, layouts ...string) (time.Time, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return time.Time{}, false
+	}
+	layouts = append(layouts, time.RFC3339Nano, time.RFC3339, "2006-01-02T15:04:05", time.DateOnly)
+	for _, layout := range layouts {
+		if parsed, err := time.Parse(layout, value); err == nil {
+			return parsed, true
+		}
+	}
+	return time.Time{}, false
+}
@@ -0,0 +1,258 @@
+package taskio
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// todoistLabel matches @label tokens in Todoist task content.
+var todoistLabel = regexp.MustCompile(`(^|\s)@([^\s@]+)`)
+
+// parseTodoistCSV reads Todoist's per-project CSV template export. Rows are
+// tasks, sections or notes; notes are comments on the task above them and
+// indented tasks become checklist items of their parent.
+func parseTodoistCSV(r io.Reader, project string, unmapped unmappedFields) ([]Record, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("reading header: %w", err)
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.ToUpper(strings.TrimSpace(name))] = i
+	}
+	if _, ok := columns["CONTENT"]; !ok {
+		return nil, errors.New("not a Todoist CSV export: missing CONTENT column")
+	}
+	known := []string{"TYPE", "CONTENT", "DESCRIPTION", "INDENT", "AUTHOR", "DATE", "DATE_LANG", "TIMEZONE"}
+
+	type parent struct {
+		record    int
+		checklist []checklistItemData
+	}
+	var (
+		records []Record
+		parents []parent
+		section string
+	)
+	flush := func() {
+		for _, p := range parents {
+			records[p.record].Description = appendParagraph(records[p.record].Description, checklistMarkdown("Subtasks", p.checklist))
+		}
+		parents = nil
+	}
+
+	for {
+		row, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		line, _ := reader.FieldPos(0)
+		field := func(name string) string {
+			i, ok := columns[name]
+			if !ok || i >= len(row) {
+				return ""
+			}
+			return strings.TrimSpace(row[i])
+		}
+		for name, i := range columns {
+			if i < len(row) && strings.TrimSpace(row[i]) != "" && !slices.Contains(known, name) {
+				unmapped[name]++
+			}
+		}
+
+		switch strings.ToLower(field("TYPE")) {
+		case "section":
+			section = field("CONTENT")
+		case "note":
+			if len(records) > 0 {
+				last := &records[len(records)-1]
+				last.Comments = append(last.Comments, Comment{Author: field("AUTHOR"), Body: field("CONTENT")})
+			}
+		case "task", "":
+			content := field("CONTENT")
+			if content == "" {
+				continue
+			}
+			indent, _ := strconv.Atoi(field("INDENT"))
+			if indent > 1 && len(parents) > 0 {
+				p := &parents[len(parents)-1]
+				p.checklist = append(p.checklist, checklistItemData{text: content})
+				continue
+			}
+			flush()
+
+			title, labels := extractTodoistLabels(content)
+			record := Record{
+				Line:        line,
+				Title:       title,
+				Description: field("DESCRIPTION"),
+				Status:      "pending",
+				Labels:      labels,
+				Project:     project,
+			}
+			if section != "" {
+				record.Labels = append(record.Labels, section)
+			}
+			if date := field("DATE"); date != "" {
+				if parsed, ok := parseFlexibleTime(date); ok {
+					record.DueDate = &parsed
+				} else {
+					// Recurring or natural-language dates like "every monday".
+					unmapped["DATE (not a fixed date)"]++
+				}
+			}
+			records = append(records, record)
+			parents = append(parents, parent{record: len(records) - 1})
+		}
+	}
+	flush()
+	return records, nil
+}
+
+func extractTodoistLabels(content string) (string, []string) {
+	var labels []string
+	for _, match := range todoistLabel.FindAllStringSubmatch(content, -1) {
+		labels = append(labels, match[2])
+	}
+	title := strings.Join(strings.Fields(todoistLabel.ReplaceAllString(content, "$1")), " ")
+	return title, labels
+}
+
+// parseTodoistJSON reads a Todoist backup in the Sync API layout: projects,
+// sections, items, labels and notes. Sub-items become checklist items of
+// their parent.
+func parseTodoistJSON(r io.Reader, unmapped unmappedFields) ([]Record, error) {
+	var backup struct {
+		Projects []map[string]json.RawMessage `json:"projects"`
+		Sections []map[string]json.RawMessage `json:"sections"`
+		Items    []map[string]json.RawMessage `json:"items"`
+		Notes    []map[string]json.RawMessage `json:"notes"`
+		Labels   []map[string]json.RawMessage `json:"labels"`
+	}
+	if err := json.NewDecoder(r).Decode(&backup); err != nil {
+		return nil, fmt.Errorf("not a Todoist JSON export: %w", err)
+	}
+	if backup.Items == nil {
+		return nil, errors.New("not a Todoist JSON export: missing items")
+	}
+
+	projects := make(map[string]string)
+	for _, project := range backup.Projects {
+		projects[jsonString(project["id"])] = jsonString(project["name"])
+	}
+	sections := make(map[string]string)
+	for _, section := range backup.Sections {
+		sections[jsonString(section["id"])] = jsonString(section["name"])
+	}
+	// Older backups reference labels by ID, newer ones by name.
+	labelNamesByID := make(map[string]string)
+	for _, label := range backup.Labels {
+		labelNamesByID[jsonString(label["id"])] = jsonString(label["name"])
+	}
+
+	var records []Record
+	index := make(map[string]int)
+	checklists := make(map[string][]checklistItemData)
+	var order []string
+	for _, item := range backup.Items {
+		unmapped.note("items.", item, "id", "project_id", "section_id", "parent_id", "content", "description", "checked", "labels", "due", "user_id", "added_by_uid", "child_order", "is_deleted", "collapsed", "date_added", "added_at", "sync_id", "v2_id", "v2_project_id", "v2_section_id", "v2_parent_id")
+		if jsonBool(item["is_deleted"]) {
+			continue
+		}
+
+		id := jsonString(item["id"])
+		checked := jsonBool(item["checked"])
+		content := jsonString(item["content"])
+		if parentID := jsonString(item["parent_id"]); parentID != "" {
+			if _, ok := checklists[parentID]; !ok {
+				order = append(order, parentID)
+			}
+			checklists[parentID] = append(checklists[parentID], checklistItemData{text: content, done: checked})
+			continue
+		}
+
+		title, labels := extractTodoistLabels(content)
+		var itemLabels []json.RawMessage
+		_ = json.Unmarshal(item["labels"], &itemLabels)
+		for _, raw := range itemLabels {
+			name := jsonString(raw)
+			if byID, ok := labelNamesByID[name]; ok {
+				name = byID
+			}
+			if name != "" {
+				labels = append(labels, name)
+			}
+		}
+		if section := sections[jsonString(item["section_id"])]; section != "" {
+			labels = append(labels, section)
+		}
+
+		record := Record{
+			SourceID:    id,
+			Title:       title,
+			Description: jsonString(item["description"]),
+			Status:      "pending",
+			Labels:      labels,
+			Project:     projects[jsonString(item["project_id"])],
+		}
+		if checked {
+			record.Status = "completed"
+		}
+		var due struct {
+			Date        string `json:"date"`
+			IsRecurring bool   `json:"is_recurring"`
+		}
+		if raw, ok := item["due"]; ok && json.Unmarshal(raw, &due) == nil && due.Date != "" {
+			if parsed, ok := parseFlexibleTime(due.Date); ok {
+				record.DueDate = &parsed
+			}
+			if due.IsRecurring {
+				unmapped["items.due.is_recurring"]++
+			}
+		}
+		index[id] = len(records)
+		records = append(records, record)
+	}
+
+	for _, parentID := range order {
+		if i, ok := index[parentID]; ok {
+			records[i].Description = appendParagraph(records[i].Description, checklistMarkdown("Subtasks", checklists[parentID]))
+		} else {
+			unmapped["items.parent_id (parent missing)"] += len(checklists[parentID])
+		}
+	}
+
+	for _, note := range backup.Notes {
+		unmapped.note("notes.", note, "id", "item_id", "content", "posted", "posted_at", "posted_uid", "is_deleted", "project_id")
+		if jsonBool(note["is_deleted"]) {
+			continue
+		}
+		i, ok := index[jsonString(note["item_id"])]
+		if !ok {
+			continue
+		}
+		comment := Comment{Author: jsonString(note["posted_uid"]), Body: jsonString(note["content"])}
+		postedAt := jsonString(note["posted_at"])
+		if postedAt == "" {
+			postedAt = jsonString(note["posted"])
+		}
+		if parsed, ok := parseFlexibleTime(postedAt, "Mon 2 Jan 2006 15:04:05 -0700"); ok {
+			comment.CreatedAt = parsed
+		}
+		records[i].Comments = append(records[i].Comments, comment)
+	}
+	return records, nil
+}
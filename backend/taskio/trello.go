@@ -0,0 +1,135 @@
+package taskio
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// parseTrelloJSON reads a Trello board export. The board becomes the
+// project, lists decide the status, and checklists are appended to the
+// description. Archived cards and cards on archived lists are skipped.
+func parseTrelloJSON(r io.Reader, unmapped unmappedFields) ([]Record, error) {
+	var board struct {
+		Name       string                       `json:"name"`
+		Lists      []map[string]json.RawMessage `json:"lists"`
+		Cards      []map[string]json.RawMessage `json:"cards"`
+		Labels     []map[string]json.RawMessage `json:"labels"`
+		Checklists []struct {
+			ID         string `json:"id"`
+			IDCard     string `json:"idCard"`
+			Name       string `json:"name"`
+			CheckItems []struct {
+				Name  string `json:"name"`
+				State string `json:"state"`
+			} `json:"checkItems"`
+		} `json:"checklists"`
+		Actions []struct {
+			Type string `json:"type"`
+			Date string `json:"date"`
+			Data struct {
+				Text string `json:"text"`
+				Card struct {
+					ID string `json:"id"`
+				} `json:"card"`
+			} `json:"data"`
+			MemberCreator struct {
+				FullName string `json:"fullName"`
+				Username string `json:"username"`
+			} `json:"memberCreator"`
+		} `json:"actions"`
+	}
+	if err := json.NewDecoder(r).Decode(&board); err != nil {
+		return nil, fmt.Errorf("not a Trello board export: %w", err)
+	}
+	if board.Cards == nil || board.Lists == nil {
+		return nil, errors.New("not a Trello board export: missing lists or cards")
+	}
+
+	type list struct {
+		name   string
+		closed bool
+	}
+	lists := make(map[string]list)
+	for _, l := range board.Lists {
+		lists[jsonString(l["id"])] = list{name: jsonString(l["name"]), closed: jsonBool(l["closed"])}
+	}
+	labels := make(map[string]string)
+	for _, label := range board.Labels {
+		name := jsonString(label["name"])
+		if name == "" {
+			// Trello labels may be colour-only.
+			name = jsonString(label["color"])
+		}
+		labels[jsonString(label["id"])] = name
+	}
+
+	var records []Record
+	index := make(map[string]int)
+	for _, card := range board.Cards {
+		unmapped.note("cards.", card, "id", "name", "desc", "idList", "idLabels", "labels", "due", "dueComplete", "closed", "idChecklists", "idBoard", "pos", "shortLink", "shortUrl", "url", "dateLastActivity", "idShort", "badges", "cover", "subscribed")
+		l := lists[jsonString(card["idList"])]
+		if jsonBool(card["closed"]) || l.closed {
+			unmapped["cards (archived, skipped)"]++
+			continue
+		}
+
+		record := Record{
+			SourceID:    jsonString(card["id"]),
+			Title:       jsonString(card["name"]),
+			Description: jsonString(card["desc"]),
+			Status:      statusFromName(l.name),
+			Project:     board.Name,
+		}
+		if jsonBool(card["dueComplete"]) {
+			record.Status = "completed"
+		}
+		if due, ok := parseFlexibleTime(jsonString(card["due"])); ok {
+			record.DueDate = &due
+		}
+		var labelIDs []string
+		_ = json.Unmarshal(card["idLabels"], &labelIDs)
+		for _, id := range labelIDs {
+			if name := labels[id]; name != "" {
+				record.Labels = append(record.Labels, name)
+			}
+		}
+		index[record.SourceID] = len(records)
+		records = append(records, record)
+	}
+
+	for _, checklist := range board.Checklists {
+		i, ok := index[checklist.IDCard]
+		if !ok {
+			continue
+		}
+		items := make([]checklistItemData, len(checklist.CheckItems))
+		for j, item := range checklist.CheckItems {
+			items[j] = checklistItemData{text: item.Name, done: item.State == "complete"}
+		}
+		records[i].Description = appendParagraph(records[i].Description, checklistMarkdown(checklist.Name, items))
+	}
+
+	// Actions are newest first; comments are attached oldest first.
+	for a := len(board.Actions) - 1; a >= 0; a-- {
+		action := board.Actions[a]
+		if action.Type != "commentCard" {
+			continue
+		}
+		i, ok := index[action.Data.Card.ID]
+		if !ok {
+			continue
+		}
+		author := action.MemberCreator.FullName
+		if author == "" {
+			author = action.MemberCreator.Username
+		}
+		comment := Comment{Author: author, Body: action.Data.Text}
+		if created, ok := parseFlexibleTime(action.Date); ok {
+			comment.CreatedAt = created
+		}
+		records[i].Comments = append(records[i].Comments, comment)
+	}
+	return records, nil
+}
@@ -0,0 +1,34 @@
+package utils
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ETag builds a strong entity tag for a versioned resource.
+func ETag(id string, version int64) string {
+	return fmt.Sprintf("\"%s-%d\"", id, version)
+}
+
+// ETagMatches reports whether an If-Match or If-None-Match header value
+// matches etag. The header may be "*" or a comma-separated list of tags.
+// If-Match requires strong comparison (weak=false); If-None-Match uses weak
+// comparison, which ignores the W/ prefix.
+func ETagMatches(header, etag string, weak bool) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" {
+			return true
+		}
+		if strings.HasPrefix(candidate, "W/") {
+			if !weak {
+				continue
+			}
+			candidate = strings.TrimPrefix(candidate, "W/")
+		}
+		if candidate == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+	return false
+}
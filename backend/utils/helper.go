@@ -9,6 +9,7 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/gofrs/uuid"
 	"log"
+	"strconv"
 
 	"golang.org/x/crypto/bcrypt"
 )
@@ -48,6 +49,34 @@ func SuccessResponse(ctx *gin.Context, statusCode int, customMessage string, dat
 
 }
 
+// FieldErrorsResponse reports request validation problems one per field so
+// clients can map them back to their inputs.
+func FieldErrorsResponse(ctx *gin.Context, statusCode int, customMessage string, fieldErrors any) {
+	ctx.JSON(statusCode, gin.H{
+		"success": false,
+		"message": customMessage,
+		"error":   customMessage,
+		"errors":  fieldErrors,
+	})
+}
+
+type PageInfo struct {
+	Limit      int    `json:"limit"`
+	NextCursor string `json:"nextCursor,omitempty"`
+	PrevCursor string `json:"prevCursor,omitempty"`
+	HasNext    bool   `json:"hasNext"`
+	HasPrev    bool   `json:"hasPrev"`
+}
+
+// PaginatedResponse is the envelope for cursor-paginated listings.
+func PaginatedResponse(ctx *gin.Context, statusCode int, customMessage string, items any, pageInfo PageInfo, total int64) {
+	SuccessResponse(ctx, statusCode, customMessage, gin.H{
+		"items":    items,
+		"pageInfo": pageInfo,
+		"total":    total,
+	})
+}
+
 func HashPassword(password string) (string, error) {
 	bytes, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
 	return string(bytes), err
@@ -79,4 +108,38 @@ func GetUserIdFromHeader(c *gin.Context) (string, error) {
 	return userID, nil
 }
 
+func GetUserRoleFromHeader(c *gin.Context) (string, error) {
+	user, exists := c.Get("user")
+	if !exists {
+		return "", fmt.Errorf("failed to get user from header")
+	}
+	userMap, ok := user.(map[string]interface{})
+	if !ok {
+		return "", fmt.Errorf("failed to convert user to map in helper function")
+	}
+
+	role, ok := userMap["role"].(string)
+	if !ok {
+		return "", fmt.Errorf("failed to extract user role from map")
+	}
+	return role, nil
+}
+
+// ParsePagination reads the page and limit query values, falling back to
+// defaults and clamping limit to maxLimit.
+func ParsePagination(pageParam, limitParam string, defaultLimit, maxLimit int) (int, int) {
+	page, err := strconv.Atoi(pageParam)
+	if err != nil || page < 1 {
+		page = 1
+	}
+	limit, err := strconv.Atoi(limitParam)
+	if err != nil || limit < 1 {
+		limit = defaultLimit
+	}
+	if limit > maxLimit {
+		limit = maxLimit
+	}
+	return page, limit
+}
+
 // Moved SignJWTForUser to a different package to avoid import cycle
@@ -0,0 +1,406 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+const (
+	MergePatchContentType = "application/merge-patch+json"
+	JSONPatchContentType  = "application/json-patch+json"
+)
+
+// ErrInvalidPatch marks a patch document that is malformed, as opposed to a
+// well-formed patch that cannot be applied to the target.
+var ErrInvalidPatch = errors.New("invalid patch document")
+
+// MergePatch applies an RFC 7396 JSON Merge Patch to original. Objects are
+// merged recursively, null removes a member and any other value replaces it.
+func MergePatch(original, patch []byte) ([]byte, error) {
+	var target, changes any
+	if err := decodeJSON(original, &target); err != nil {
+		return nil, err
+	}
+	if err := decodeJSON(patch, &changes); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPatch, err)
+	}
+	return json.Marshal(mergeValue(target, changes))
+}
+
+func mergeValue(target, patch any) any {
+	patchObject, ok := patch.(map[string]any)
+	if !ok {
+		return patch
+	}
+	targetObject, ok := target.(map[string]any)
+	if !ok {
+		targetObject = make(map[string]any)
+	}
+	for key, value := range patchObject {
+		if value == nil {
+			delete(targetObject, key)
+			continue
+		}
+		targetObject[key] = mergeValue(targetObject[key], value)
+	}
+	return targetObject
+}
+
+type jsonPatchOperation struct {
+	Op    string          `json:"op"`
+	Path  *string         `json:"path"`
+	From  *string         `json:"from"`
+	Value json.RawMessage `json:"value"`
+}
+
+// ApplyJSONPatch applies an RFC 6902 JSON Patch to original. Operations are
+// applied in order and the patch fails as a whole if any of them fails.
+func ApplyJSONPatch(original, patch []byte) ([]byte, error) {
+	var document any
+	if err := decodeJSON(original, &document); err != nil {
+		return nil, err
+	}
+
+	var operations []jsonPatchOperation
+	if err := json.Unmarshal(patch, &operations); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPatch, err)
+	}
+
+	for i, operation := range operations {
+		var err error
+		document, err = applyOperation(document, operation)
+		if err != nil {
+			return nil, fmt.Errorf("operation %d (%s): %w", i, operation.Op, err)
+		}
+	}
+	return json.Marshal(document)
+}
+
+func applyOperation(document any, operation jsonPatchOperation) (any, error) {
+	if operation.Path == nil {
+		return nil, fmt.Errorf("%w: missing path", ErrInvalidPatch)
+	}
+	path, err := parsePointer(*operation.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	value := func() (any, error) {
+		if operation.Value == nil {
+			return nil, fmt.Errorf("%w: missing value", ErrInvalidPatch)
+		}
+		var decoded any
+		if err := decodeJSON(operation.Value, &decoded); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidPatch, err)
+		}
+		return decoded, nil
+	}
+	from := func() ([]string, error) {
+		if operation.From == nil {
+			return nil, fmt.Errorf("%w: missing from", ErrInvalidPatch)
+		}
+		return parsePointer(*operation.From)
+	}
+
+	switch operation.Op {
+	case "add":
+		v, err := value()
+		if err != nil {
+			return nil, err
+		}
+		return addValue(document, path, v)
+	case "remove":
+		document, _, err := removeValue(document, path)
+		return document, err
+	case "replace":
+		v, err := value()
+		if err != nil {
+			return nil, err
+		}
+		if len(path) == 0 {
+			return v, nil
+		}
+		document, _, err := removeValue(document, path)
+		if err != nil {
+			return nil, err
+		}
+		return addValue(document, path, v)
+	case "move":
+		source, err := from()
+		if err != nil {
+			return nil, err
+		}
+		if len(path) > len(source) && slices.Equal(path[:len(source)], source) {
+			return nil, errors.New("cannot move a value into one of its children")
+		}
+		document, moved, err := removeValue(document, source)
+		if err != nil {
+			return nil, err
+		}
+		return addValue(document, path, moved)
+	case "copy":
+		source, err := from()
+		if err != nil {
+			return nil, err
+		}
+		copied, err := getValue(document, source)
+		if err != nil {
+			return nil, err
+		}
+		return addValue(document, path, deepCopy(copied))
+	case "test":
+		v, err := value()
+		if err != nil {
+			return nil, err
+		}
+		actual, err := getValue(document, path)
+		if err != nil {
+			return nil, err
+		}
+		if !jsonEqual(actual, v) {
+			return nil, fmt.Errorf("test failed at %q", *operation.Path)
+		}
+		return document, nil
+	default:
+		return nil, fmt.Errorf("%w: unknown op %q", ErrInvalidPatch, operation.Op)
+	}
+}
+
+// parsePointer splits an RFC 6901 JSON Pointer into unescaped reference
+// tokens. The empty pointer refers to the whole document.
+func parsePointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("%w: pointer %q must start with /", ErrInvalidPatch, pointer)
+	}
+	tokens := strings.Split(pointer[1:], "/")
+	for i, token := range tokens {
+		tokens[i] = strings.NewReplacer("~1", "/", "~0", "~").Replace(token)
+	}
+	return tokens, nil
+}
+
+func arrayIndex(token string, length int, allowEnd bool) (int, error) {
+	if allowEnd && token == "-" {
+		return length, nil
+	}
+	if token == "" || (len(token) > 1 && token[0] == '0') {
+		return 0, fmt.Errorf("invalid array index %q", token)
+	}
+	index, err := strconv.Atoi(token)
+	if err != nil || index < 0 {
+		return 0, fmt.Errorf("invalid array index %q", token)
+	}
+	limit := length - 1
+	if allowEnd {
+		limit = length
+	}
+	if index > limit {
+		return 0, fmt.Errorf("array index %d out of range", index)
+	}
+	return index, nil
+}
+
+func getValue(node any, path []string) (any, error) {
+	for _, token := range path {
+		switch current := node.(type) {
+		case map[string]any:
+			child, ok := current[token]
+			if !ok {
+				return nil, fmt.Errorf("path member %q not found", token)
+			}
+			node = child
+		case []any:
+			index, err := arrayIndex(token, len(current), false)
+			if err != nil {
+				return nil, err
+			}
+			node = current[index]
+		default:
+			return nil, fmt.Errorf("cannot traverse into %q", token)
+		}
+	}
+	return node, nil
+}
+
+// addValue returns node with value added at path. Slices may be reallocated,
+// so callers must use the returned node.
+func addValue(node any, path []string, value any) (any, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+	token, rest := path[0], path[1:]
+
+	switch current := node.(type) {
+	case map[string]any:
+		if len(rest) == 0 {
+			current[token] = value
+			return current, nil
+		}
+		child, ok := current[token]
+		if !ok {
+			return nil, fmt.Errorf("path member %q not found", token)
+		}
+		updated, err := addValue(child, rest, value)
+		if err != nil {
+			return nil, err
+		}
+		current[token] = updated
+		return current, nil
+	case []any:
+		if len(rest) == 0 {
+			index, err := arrayIndex(token, len(current), true)
+			if err != nil {
+				return nil, err
+			}
+			current = append(current, nil)
+			copy(current[index+1:], current[index:])
+			current[index] = value
+			return current, nil
+		}
+		index, err := arrayIndex(token, len(current), false)
+		if err != nil {
+			return nil, err
+		}
+		updated, err := addValue(current[index], rest, value)
+		if err != nil {
+			return nil, err
+		}
+		current[index] = updated
+		return current, nil
+	default:
+		return nil, fmt.Errorf("cannot add into %q", token)
+	}
+}
+
+// removeValue returns node without the value at path, and that value.
+func removeValue(node any, path []string) (any, any, error) {
+	if len(path) == 0 {
+		return nil, nil, errors.New("cannot remove the whole document")
+	}
+	token, rest := path[0], path[1:]
+
+	switch current := node.(type) {
+	case map[string]any:
+		child, ok := current[token]
+		if !ok {
+			return nil, nil, fmt.Errorf("path member %q not found", token)
+		}
+		if len(rest) == 0 {
+			delete(current, token)
+			return current, child, nil
+		}
+		updated, removed, err := removeValue(child, rest)
+		if err != nil {
+			return nil, nil, err
+		}
+		current[token] = updated
+		return current, removed, nil
+	case []any:
+		index, err := arrayIndex(token, len(current), false)
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(rest) == 0 {
+			removed := current[index]
+			return append(current[:index], current[index+1:]...), removed, nil
+		}
+		updated, removed, err := removeValue(current[index], rest)
+		if err != nil {
+			return nil, nil, err
+		}
+		current[index] = updated
+		return current, removed, nil
+	default:
+		return nil, nil, fmt.Errorf("cannot remove from %q", token)
+	}
+}
+
+func deepCopy(value any) any {
+	switch v := value.(type) {
+	case map[string]any:
+		copied := make(map[string]any, len(v))
+		for key, child := range v {
+			copied[key] = deepCopy(child)
+		}
+		return copied
+	case []any:
+		copied := make([]any, len(v))
+		for i, child := range v {
+			copied[i] = deepCopy(child)
+		}
+		return copied
+	default:
+		return v
+	}
+}
+
+// jsonEqual compares decoded JSON values, treating numbers by value so that
+// 1 and 1.0 are equal.
+func jsonEqual(a, b any) bool {
+	if na, ok := a.(json.Number); ok {
+		nb, ok := b.(json.Number)
+		if !ok {
+			return false
+		}
+		fa, errA := na.Float64()
+		fb, errB := nb.Float64()
+		return errA == nil && errB == nil && fa == fb
+	}
+	switch va := a.(type) {
+	case map[string]any:
+		vb, ok := b.(map[string]any)
+		if !ok || len(va) != len(vb) {
+			return false
+		}
+		for key, child := range va {
+			other, ok := vb[key]
+			if !ok || !jsonEqual(child, other) {
+				return false
+			}
+		}
+		return true
+	case []any:
+		vb, ok := b.([]any)
+		if !ok || len(va) != len(vb) {
+			return false
+		}
+		for i := range va {
+			if !jsonEqual(va[i], vb[i]) {
+				return false
+			}
+		}
+		return true
+	default:
+		return reflect.DeepEqual(a, b)
+	}
+}
+
+// JSONEqual reports whether two JSON documents encode the same value.
+func JSONEqual(a, b []byte) bool {
+	var va, vb any
+	if decodeJSON(a, &va) != nil || decodeJSON(b, &vb) != nil {
+		return bytes.Equal(a, b)
+	}
+	return jsonEqual(va, vb)
+}
+
+func decodeJSON(data []byte, target any) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(target); err != nil {
+		return err
+	}
+	if decoder.More() {
+		return errors.New("unexpected data after JSON value")
+	}
+	return nil
+}
@@ -0,0 +1,32 @@
+package utils
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	mentionRegex    = regexp.MustCompile(`(^|[^\w@.])@([A-Za-z0-9_][A-Za-z0-9_.-]*)`)
+	fencedCodeRegex = regexp.MustCompile("(?s)```.*?```")
+	inlineCodeRegex = regexp.MustCompile("`[^`\n]*`")
+)
+
+// ParseMentions returns the unique usernames @mentioned in a Markdown body,
+// in order of first appearance. Mentions inside code spans and fenced code
+// blocks are ignored, as are email addresses.
+func ParseMentions(body string) []string {
+	body = fencedCodeRegex.ReplaceAllString(body, "")
+	body = inlineCodeRegex.ReplaceAllString(body, "")
+
+	seen := make(map[string]bool)
+	var usernames []string
+	for _, match := range mentionRegex.FindAllStringSubmatch(body, -1) {
+		username := strings.TrimRight(match[2], ".-")
+		if username == "" || seen[username] {
+			continue
+		}
+		seen[username] = true
+		usernames = append(usernames, username)
+	}
+	return usernames
+}
@@ -0,0 +1,39 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// GenerateSignature returns a hex HMAC-SHA256 of the given parts joined by ":".
+func GenerateSignature(secret string, parts ...string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strings.Join(parts, ":")))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifySignature checks a signature produced by GenerateSignature in constant time.
+func VerifySignature(secret, signature string, parts ...string) bool {
+	expected := GenerateSignature(secret, parts...)
+	return hmac.Equal([]byte(expected), []byte(signature))
+}
+
+// SignExpiring signs resourceID together with an expiry time and returns the
+// expiry (unix seconds) and signature to embed in a URL.
+func SignExpiring(secret, resourceID string, ttl time.Duration) (string, string) {
+	expires := strconv.FormatInt(time.Now().Add(ttl).Unix(), 10)
+	return expires, GenerateSignature(secret, resourceID, expires)
+}
+
+// VerifyExpiring validates values produced by SignExpiring.
+func VerifyExpiring(secret, resourceID, expires, signature string) bool {
+	expiresAt, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || time.Now().Unix() > expiresAt {
+		return false
+	}
+	return VerifySignature(secret, signature, resourceID, expires)
+}
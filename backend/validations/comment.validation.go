@@ -0,0 +1,22 @@
+package validations
+
+import (
+	"errors"
+	"strings"
+)
+
+const MaxCommentLength = 10000
+
+type Comment struct {
+	Body string
+}
+
+func ValidateComment(comment Comment) error {
+	if strings.TrimSpace(comment.Body) == "" {
+		return errors.New("comment body must not be empty")
+	}
+	if len(comment.Body) > MaxCommentLength {
+		return errors.New("comment body must not exceed 10000 characters")
+	}
+	return nil
+}
@@ -0,0 +1,20 @@
+package validations
+
+import (
+	"errors"
+	"strings"
+)
+
+func ValidateLabelName(name string) error {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return errors.New("label name must not be empty")
+	}
+	if len(name) > 50 {
+		return errors.New("label name must not exceed 50 characters")
+	}
+	if strings.Contains(name, ",") {
+		return errors.New("label name must not contain commas")
+	}
+	return nil
+}
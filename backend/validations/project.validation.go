@@ -0,0 +1,21 @@
+package validations
+
+import (
+	"errors"
+	"strings"
+)
+
+type Project struct {
+	Name string
+}
+
+func ValidateProject(project Project) error {
+	name := strings.TrimSpace(project.Name)
+	if name == "" {
+		return errors.New("project name must not be empty")
+	}
+	if len(name) > 100 {
+		return errors.New("project name must not exceed 100 characters")
+	}
+	return nil
+}
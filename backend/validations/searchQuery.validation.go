@@ -0,0 +1,159 @@
+package validations
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/gofrs/uuid"
+)
+
+const MaxSearchQueryLength = 500
+
+// SearchQuery is the parsed form of the task search syntax:
+//
+//	deploy "release notes" -draft status:completed label:backend
+//
+// Bare words are prefix-matched, quoted phrases must appear in order,
+// a leading "-" excludes a word or phrase, and status:/label: filter.
+// Only the tasks VisibleTo owns or is assigned are searched.
+type SearchQuery struct {
+	VisibleTo uuid.UUID
+
+	Terms    []string
+	Phrases  [][]string
+	Excluded [][]string
+	Statuses []string
+	Labels   []string
+}
+
+func (q SearchQuery) HasText() bool {
+	return len(q.Terms) > 0 || len(q.Phrases) > 0 || len(q.Excluded) > 0
+}
+
+// TSQuery renders the text part of the query for Postgres to_tsquery. Every
+// lexeme has been reduced to letters and digits, so no tsquery operators
+// from user input can leak through.
+func (q SearchQuery) TSQuery() string {
+	var parts []string
+	for _, term := range q.Terms {
+		parts = append(parts, term+":*")
+	}
+	for _, phrase := range q.Phrases {
+		parts = append(parts, "("+strings.Join(phrase, " <-> ")+")")
+	}
+	for _, excluded := range q.Excluded {
+		if len(excluded) == 1 {
+			parts = append(parts, "!"+excluded[0])
+		} else {
+			parts = append(parts, "!("+strings.Join(excluded, " <-> ")+")")
+		}
+	}
+	return strings.Join(parts, " & ")
+}
+
+func ParseSearchQuery(raw string, currentUserID uuid.UUID) (SearchQuery, error) {
+	query := SearchQuery{VisibleTo: currentUserID}
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return query, errors.New("q must not be empty")
+	}
+	if len(raw) > MaxSearchQueryLength {
+		return query, fmt.Errorf("q must not exceed %d characters", MaxSearchQueryLength)
+	}
+
+	for _, token := range tokenizeSearch(raw) {
+		if token.quoted {
+			words := SearchWords(token.text)
+			if len(words) == 0 {
+				continue
+			}
+			if token.negated {
+				query.Excluded = append(query.Excluded, words)
+			} else {
+				query.Phrases = append(query.Phrases, words)
+			}
+			continue
+		}
+
+		if key, value, ok := strings.Cut(token.text, ":"); ok && !token.negated {
+			switch strings.ToLower(key) {
+			case "status":
+				if err := ValidateTaskStatus(value); err != nil {
+					return query, err
+				}
+				query.Statuses = append(query.Statuses, value)
+				continue
+			case "label":
+				if err := ValidateLabelName(value); err != nil {
+					return query, err
+				}
+				query.Labels = append(query.Labels, value)
+				continue
+			}
+		}
+
+		for _, word := range SearchWords(token.text) {
+			if token.negated {
+				query.Excluded = append(query.Excluded, []string{word})
+			} else {
+				query.Terms = append(query.Terms, word)
+			}
+		}
+	}
+
+	if !query.HasText() && len(query.Statuses) == 0 && len(query.Labels) == 0 {
+		return query, errors.New("q must contain at least one search term or filter")
+	}
+	return query, nil
+}
+
+type searchToken struct {
+	text    string
+	quoted  bool
+	negated bool
+}
+
+func tokenizeSearch(raw string) []searchToken {
+	var tokens []searchToken
+	runes := []rune(raw)
+	for i := 0; i < len(runes); {
+		if unicode.IsSpace(runes[i]) {
+			i++
+			continue
+		}
+
+		token := searchToken{}
+		if runes[i] == '-' && i+1 < len(runes) && !unicode.IsSpace(runes[i+1]) {
+			token.negated = true
+			i++
+		}
+
+		if runes[i] == '"' {
+			end := i + 1
+			for end < len(runes) && runes[end] != '"' {
+				end++
+			}
+			token.text = string(runes[i+1 : end])
+			token.quoted = true
+			i = end + 1
+		} else {
+			end := i
+			for end < len(runes) && !unicode.IsSpace(runes[end]) {
+				end++
+			}
+			token.text = string(runes[i:end])
+			i = end
+		}
+		tokens = append(tokens, token)
+	}
+	return tokens
+}
+
+// SearchWords lowercases text and splits it into runs of letters and digits.
+func SearchWords(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
@@ -8,9 +8,11 @@ type Task struct {
 	Title       string
 	Description string
 	Status      string
+	Priority    int
 }
 
-func validateTaskStatus(status string) error {
+// ValidateTaskStatus checks status against the allowed task statuses.
+func ValidateTaskStatus(status string) error {
 	validStatuses := map[string]bool{
 		"pending":     true,
 		"in_progress": true,
@@ -22,6 +24,15 @@ func validateTaskStatus(status string) error {
 	return nil
 }
 
+// ValidateTaskPriority checks priority against the iCalendar range, where 0
+// means no priority.
+func ValidateTaskPriority(priority int) error {
+	if priority < 0 || priority > 9 {
+		return errors.New("invalid priority: must be between 0 (none) and 9, 1 being the highest")
+	}
+	return nil
+}
+
 func ValidateTask(task Task) error {
 	if task.Title == "" {
 		return errors.New("title must not be empty")
@@ -32,7 +43,10 @@ func ValidateTask(task Task) error {
 	if task.Description == "" {
 		return errors.New("description must not be empty")
 	}
-	if err := validateTaskStatus(task.Status); err != nil {
+	if err := ValidateTaskStatus(task.Status); err != nil {
+		return err
+	}
+	if err := ValidateTaskPriority(task.Priority); err != nil {
 		return err
 	}
 	return nil
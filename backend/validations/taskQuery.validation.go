@@ -0,0 +1,288 @@
+package validations
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+const (
+	DefaultTaskPageSize = 20
+	MaxTaskPageSize     = 100
+)
+
+const (
+	CursorNext = "next"
+	CursorPrev = "prev"
+)
+
+// FieldError describes why a single request parameter was rejected.
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// TaskSortKeys maps the public sort names to task columns.
+var TaskSortKeys = map[string]string{
+	"createdAt": "created_at",
+	"updatedAt": "updated_at",
+	"title":     "title",
+	"status":    "status",
+}
+
+// TaskFields lists the fields that can be requested through ?fields=.
+var TaskFields = map[string]bool{
+	"taskID":      true,
+	"title":       true,
+	"description": true,
+	"status":      true,
+	"assignedTo":  true,
+	"userID":      true,
+	"projectID":   true,
+	"dueDate":     true,
+	"priority":    true,
+	"createdAt":   true,
+	"updatedAt":   true,
+	"deletedAt":   true,
+	"labels":      true,
+}
+
+type SortKey struct {
+	Field  string
+	Column string
+	Desc   bool
+}
+
+// TaskCursor marks a position in a sorted task listing. Values holds the
+// sort key values of the boundary row, and TaskID breaks ties.
+type TaskCursor struct {
+	Direction string   `json:"d"`
+	Sort      string   `json:"s"`
+	Values    []string `json:"v"`
+	TaskID    string   `json:"id"`
+}
+
+type TaskQuery struct {
+	Limit  int
+	Cursor *TaskCursor
+	Sort   []SortKey
+	Fields []string
+
+	// VisibleTo limits the listing to the tasks this user owns or is
+	// assigned; nobody else's tasks are ever listed.
+	VisibleTo uuid.UUID
+
+	Statuses      []string
+	AssignedTo    *uuid.UUID
+	Unassigned    bool
+	OwnerID       *uuid.UUID
+	ProjectID     *uuid.UUID
+	NoProject     bool
+	Labels        []string
+	Text          string
+	CreatedAfter  *time.Time
+	CreatedBefore *time.Time
+	UpdatedAfter  *time.Time
+	UpdatedBefore *time.Time
+	DueAfter      *time.Time
+	DueBefore     *time.Time
+	// HasDueDate limits the listing to tasks with a due date.
+	HasDueDate bool
+}
+
+// SortSignature identifies the sort order so cursors cannot be reused
+// with a different ?sort=.
+func (q *TaskQuery) SortSignature() string {
+	parts := make([]string, len(q.Sort))
+	for i, key := range q.Sort {
+		if key.Desc {
+			parts[i] = "-" + key.Field
+		} else {
+			parts[i] = key.Field
+		}
+	}
+	return strings.Join(parts, ",")
+}
+
+func EncodeTaskCursor(cursor TaskCursor) string {
+	raw, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+func decodeTaskCursor(value string) (*TaskCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, err
+	}
+	var cursor TaskCursor
+	if err := json.Unmarshal(raw, &cursor); err != nil {
+		return nil, err
+	}
+	if cursor.Direction != CursorNext && cursor.Direction != CursorPrev {
+		return nil, fmt.Errorf("unknown direction %q", cursor.Direction)
+	}
+	if _, err := uuid.FromString(cursor.TaskID); err != nil {
+		return nil, err
+	}
+	return &cursor, nil
+}
+
+// ParseTaskQuery validates the task listing query string. The listing is
+// limited to the tasks currentUserID owns or is assigned, and currentUserID
+// resolves "me" in the assignee and owner filters. All problems are reported
+// together.
+func ParseTaskQuery(values url.Values, currentUserID uuid.UUID) (TaskQuery, []FieldError) {
+	query := TaskQuery{Limit: DefaultTaskPageSize, VisibleTo: currentUserID}
+	var errs []FieldError
+	fail := func(field, format string, args ...any) {
+		errs = append(errs, FieldError{Field: field, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if limit := values.Get("limit"); limit != "" {
+		parsed, err := strconv.Atoi(limit)
+		if err != nil || parsed < 1 || parsed > MaxTaskPageSize {
+			fail("limit", "must be an integer between 1 and %d", MaxTaskPageSize)
+		} else {
+			query.Limit = parsed
+		}
+	}
+
+	sortParam := values.Get("sort")
+	if sortParam == "" {
+		sortParam = "-createdAt"
+	}
+	seen := make(map[string]bool)
+	for _, item := range splitList(sortParam) {
+		key := SortKey{Field: strings.TrimPrefix(item, "-"), Desc: strings.HasPrefix(item, "-")}
+		column, ok := TaskSortKeys[key.Field]
+		if !ok {
+			fail("sort", "unknown sort key %q", key.Field)
+			continue
+		}
+		if seen[key.Field] {
+			fail("sort", "duplicate sort key %q", key.Field)
+			continue
+		}
+		seen[key.Field] = true
+		key.Column = column
+		query.Sort = append(query.Sort, key)
+	}
+
+	for _, field := range splitList(values.Get("fields")) {
+		if !TaskFields[field] {
+			fail("fields", "unknown field %q", field)
+			continue
+		}
+		query.Fields = append(query.Fields, field)
+	}
+
+	for _, status := range splitList(values.Get("status")) {
+		if err := ValidateTaskStatus(status); err != nil {
+			fail("status", "%s", err.Error())
+			continue
+		}
+		query.Statuses = append(query.Statuses, status)
+	}
+
+	switch assignee := values.Get("assignee"); assignee {
+	case "":
+	case "none":
+		query.Unassigned = true
+	case "me":
+		query.AssignedTo = &currentUserID
+	default:
+		id, err := uuid.FromString(assignee)
+		if err != nil {
+			fail("assignee", "must be a UUID, \"me\" or \"none\"")
+		} else {
+			query.AssignedTo = &id
+		}
+	}
+
+	// Only the caller's own tasks can be asked for by owner; the others
+	// they see are the ones assigned to them.
+	switch owner := values.Get("owner"); owner {
+	case "":
+	case "me", currentUserID.String():
+		query.OwnerID = &currentUserID
+	default:
+		fail("owner", "must be \"me\" or your own user ID")
+	}
+
+	switch project := values.Get("project"); project {
+	case "":
+	case "none":
+		query.NoProject = true
+	default:
+		id, err := uuid.FromString(project)
+		if err != nil {
+			fail("project", "must be a UUID or \"none\"")
+		} else {
+			query.ProjectID = &id
+		}
+	}
+
+	query.Labels = splitList(values.Get("labels"))
+	query.Text = strings.TrimSpace(values.Get("q"))
+
+	timeFilters := []struct {
+		param  string
+		target **time.Time
+	}{
+		{"createdAfter", &query.CreatedAfter},
+		{"createdBefore", &query.CreatedBefore},
+		{"updatedAfter", &query.UpdatedAfter},
+		{"updatedBefore", &query.UpdatedBefore},
+		{"dueAfter", &query.DueAfter},
+		{"dueBefore", &query.DueBefore},
+	}
+	for _, filter := range timeFilters {
+		value := values.Get(filter.param)
+		if value == "" {
+			continue
+		}
+		parsed, err := parseQueryTime(value)
+		if err != nil {
+			fail(filter.param, "must be an RFC 3339 timestamp or YYYY-MM-DD date")
+			continue
+		}
+		*filter.target = &parsed
+	}
+
+	if cursor := values.Get("cursor"); cursor != "" {
+		decoded, err := decodeTaskCursor(cursor)
+		switch {
+		case err != nil:
+			fail("cursor", "malformed cursor")
+		case decoded.Sort != query.SortSignature() || len(decoded.Values) != len(query.Sort):
+			fail("cursor", "cursor was issued for a different sort order")
+		default:
+			query.Cursor = decoded
+		}
+	}
+
+	return query, errs
+}
+
+func parseQueryTime(value string) (time.Time, error) {
+	if parsed, err := time.Parse(time.RFC3339, value); err == nil {
+		return parsed, nil
+	}
+	return time.Parse(time.DateOnly, value)
+}
+
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
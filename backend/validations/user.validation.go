@@ -67,3 +67,22 @@ func ValidateUser(user User) error {
 	}
 	return nil
 }
+
+// ValidateUserUpdate validates a user after a profile update. The stored
+// password is a bcrypt hash, so the password rules only apply when the
+// update sets a new one.
+func ValidateUserUpdate(user User, passwordChanged bool) error {
+	if user.Email == "" {
+		return errors.New("email must not be empty")
+	}
+	if err := validateEmail(user.Email); err != nil {
+		return err
+	}
+	if user.Username == "" {
+		return errors.New("username must not be empty")
+	}
+	if passwordChanged {
+		return validatePassword(user.Password)
+	}
+	return nil
+}
@@ -0,0 +1,54 @@
+package validations
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+const (
+	maxWebhookURLLength = 2048
+	// MinWebhookSecretLength keeps chosen secrets hard to guess; generated
+	// ones are longer.
+	MinWebhookSecretLength = 16
+)
+
+type Webhook struct {
+	URL        string
+	EventTypes []string
+	Secret     string
+}
+
+// ValidateWebhook checks the target URL, the secret and that every event
+// type is one of known.
+func ValidateWebhook(webhook Webhook, known []string) error {
+	if webhook.URL == "" {
+		return errors.New("webhook url must not be empty")
+	}
+	if len(webhook.URL) > maxWebhookURLLength {
+		return fmt.Errorf("webhook url must not exceed %d characters", maxWebhookURLLength)
+	}
+	target, err := url.Parse(webhook.URL)
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Hostname() == "" {
+		return errors.New("webhook url must be an absolute http or https url")
+	}
+	if target.User != nil {
+		return errors.New("webhook url must not contain credentials")
+	}
+
+	if webhook.Secret != "" && len(webhook.Secret) < MinWebhookSecretLength {
+		return fmt.Errorf("webhook secret must be at least %d characters", MinWebhookSecretLength)
+	}
+
+	if len(webhook.EventTypes) == 0 {
+		return errors.New("webhook must subscribe to at least one event type")
+	}
+	for _, eventType := range webhook.EventTypes {
+		if !slices.Contains(known, strings.TrimSpace(eventType)) {
+			return fmt.Errorf("unknown event type: %s", eventType)
+		}
+	}
+	return nil
+}
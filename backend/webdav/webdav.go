@@ -0,0 +1,220 @@
+// Package webdav reads WebDAV request bodies and writes multistatus
+// responses (RFC 4918) for the CalDAV endpoint.
+package webdav
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+const (
+	NamespaceDAV            = "DAV:"
+	NamespaceCalDAV         = "urn:ietf:params:xml:ns:caldav"
+	NamespaceCalendarServer = "http://calendarserver.org/ns/"
+)
+
+// prefixes are declared on every multistatus and error document, so property
+// values may use d:, c: and cs: elements.
+var prefixes = map[string]string{
+	NamespaceDAV:            "d",
+	NamespaceCalDAV:         "c",
+	NamespaceCalendarServer: "cs",
+}
+
+func DAV(local string) xml.Name    { return xml.Name{Space: NamespaceDAV, Local: local} }
+func CalDAV(local string) xml.Name { return xml.Name{Space: NamespaceCalDAV, Local: local} }
+func CS(local string) xml.Name     { return xml.Name{Space: NamespaceCalendarServer, Local: local} }
+
+// Request is a PROPFIND or REPORT body. An empty body is a PROPFIND for all
+// properties.
+type Request struct {
+	Root      xml.Name
+	AllProp   bool
+	PropNames []xml.Name
+	// Hrefs lists the resources of a calendar-multiget report.
+	Hrefs []string
+	// CompFilters lists the components named by a calendar-query filter.
+	CompFilters []string
+}
+
+func ParseRequest(r io.Reader) (*Request, error) {
+	request := &Request{}
+	decoder := xml.NewDecoder(r)
+	var (
+		stack []xml.Name
+		text  strings.Builder
+	)
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("malformed XML body: %w", err)
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			stack = append(stack, t.Name)
+			switch {
+			case len(stack) == 1:
+				request.Root = t.Name
+			case len(stack) == 2 && t.Name == DAV("allprop"):
+				request.AllProp = true
+			case len(stack) == 3 && stack[1] == DAV("prop"):
+				request.PropNames = append(request.PropNames, t.Name)
+			case t.Name == CalDAV("comp-filter"):
+				for _, attr := range t.Attr {
+					if attr.Name.Local == "name" {
+						request.CompFilters = append(request.CompFilters, strings.ToUpper(attr.Value))
+					}
+				}
+			}
+			text.Reset()
+		case xml.CharData:
+			text.Write(t)
+		case xml.EndElement:
+			if len(stack) == 2 && t.Name == DAV("href") {
+				request.Hrefs = append(request.Hrefs, strings.TrimSpace(text.String()))
+			}
+			if len(stack) > 0 {
+				stack = stack[:len(stack)-1]
+			}
+		}
+	}
+	if request.Root.Local == "" {
+		request.Root = DAV("propfind")
+		request.AllProp = true
+	}
+	if request.Root == DAV("propfind") && len(request.PropNames) == 0 {
+		request.AllProp = true
+	}
+	return request, nil
+}
+
+// Property is a property value as inner XML, which may use the d:, c: and
+// cs: prefixes. An empty value is written as an empty element.
+type Property struct {
+	Name  xml.Name
+	Value string
+}
+
+// Text escapes s for use as a property value.
+func Text(s string) string {
+	var b bytes.Buffer
+	_ = xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
+
+// Href renders a d:href element.
+func Href(path string) string {
+	return "<d:href>" + Text(path) + "</d:href>"
+}
+
+// Response is one resource in a multistatus. A non-zero Status reports the
+// whole resource, such as a 404 for an unknown href in a multiget.
+type Response struct {
+	Href     string
+	Found    []Property
+	NotFound []xml.Name
+	Status   int
+}
+
+// Select answers the request from the properties available on a resource.
+// allprop returns every available property except the ones in exclude,
+// which are only sent when asked for by name.
+func (r *Request) Select(href string, available []Property, exclude ...xml.Name) Response {
+	response := Response{Href: href}
+	if r.AllProp {
+	next:
+		for _, prop := range available {
+			for _, name := range exclude {
+				if prop.Name == name {
+					continue next
+				}
+			}
+			response.Found = append(response.Found, prop)
+		}
+		return response
+	}
+
+	byName := make(map[xml.Name]Property, len(available))
+	for _, prop := range available {
+		byName[prop.Name] = prop
+	}
+	for _, name := range r.PropNames {
+		if prop, ok := byName[name]; ok {
+			response.Found = append(response.Found, prop)
+		} else {
+			response.NotFound = append(response.NotFound, name)
+		}
+	}
+	return response
+}
+
+func WriteMultistatus(w http.ResponseWriter, responses []Response) error {
+	var b strings.Builder
+	b.WriteString(`<?xml version="1.0" encoding="utf-8"?>` + "\n")
+	b.WriteString("<d:multistatus" + namespaceDeclarations() + ">")
+	for _, response := range responses {
+		b.WriteString("<d:response>" + Href(response.Href))
+		if response.Status != 0 {
+			b.WriteString("<d:status>" + statusLine(response.Status) + "</d:status>")
+		}
+		if len(response.Found) > 0 {
+			b.WriteString("<d:propstat><d:prop>")
+			for _, prop := range response.Found {
+				b.WriteString(element(prop.Name, prop.Value))
+			}
+			b.WriteString("</d:prop><d:status>" + statusLine(http.StatusOK) + "</d:status></d:propstat>")
+		}
+		if len(response.NotFound) > 0 {
+			b.WriteString("<d:propstat><d:prop>")
+			for _, name := range response.NotFound {
+				b.WriteString(element(name, ""))
+			}
+			b.WriteString("</d:prop><d:status>" + statusLine(http.StatusNotFound) + "</d:status></d:propstat>")
+		}
+		b.WriteString("</d:response>")
+	}
+	b.WriteString("</d:multistatus>\n")
+
+	w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+	w.WriteHeader(http.StatusMultiStatus)
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// WriteError sends a DAV:error body naming the failed precondition.
+func WriteError(w http.ResponseWriter, status int, condition xml.Name) error {
+	w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+	w.WriteHeader(status)
+	_, err := io.WriteString(w, `<?xml version="1.0" encoding="utf-8"?>`+"\n"+
+		"<d:error"+namespaceDeclarations()+">"+element(condition, "")+"</d:error>\n")
+	return err
+}
+
+func namespaceDeclarations() string {
+	return ` xmlns:d="` + NamespaceDAV + `" xmlns:c="` + NamespaceCalDAV + `" xmlns:cs="` + NamespaceCalendarServer + `"`
+}
+
+func element(name xml.Name, value string) string {
+	open, closing := name.Local, name.Local
+	if prefix, ok := prefixes[name.Space]; ok {
+		open, closing = prefix+":"+name.Local, prefix+":"+name.Local
+	} else if name.Space != "" {
+		open = "x:" + name.Local + ` xmlns:x="` + Text(name.Space) + `"`
+		closing = "x:" + name.Local
+	}
+	if value == "" {
+		return "<" + open + "/>"
+	}
+	return "<" + open + ">" + value + "</" + closing + ">"
+}
+
+func statusLine(status int) string {
+	return fmt.Sprintf("HTTP/1.1 %d %s", status, http.StatusText(status))
+}
@@ -0,0 +1,441 @@
+// Package webhooks posts events to the URLs users subscribe. The dispatcher
+// reads published events from the event log, records a delivery for every
+// webhook that wants one and sends the deliveries, retrying failed ones with
+// exponential backoff. Deliveries live in the database, so they survive a
+// restart and are shared by every instance.
+package webhooks
+
+import (
+	"ai-task-manager/events"
+	"ai-task-manager/models"
+	"ai-task-manager/utils"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	// cursorName names the dispatcher's position in the event log.
+	cursorName = "events"
+	// settleDelay leaves events this recent in the log for a later pass, so
+	// that an event logged concurrently by another instance with a lower
+	// sequence number is not skipped.
+	settleDelay = 2 * time.Second
+	// batchSize bounds the events read and the deliveries claimed at once.
+	batchSize = 200
+	// concurrency bounds the deliveries sent at once.
+	concurrency = 8
+	// baseRetryDelay is the wait after the first failed attempt; it doubles
+	// after each further one up to maxRetryDelay.
+	baseRetryDelay = 30 * time.Second
+	maxRetryDelay  = 6 * time.Hour
+	// maxResponseBody bounds the part of a response kept in the delivery log.
+	maxResponseBody = 1024
+	userAgent       = "ai-task-manager-webhooks/1"
+)
+
+// TestEvent is the type of the event sent by SendTest.
+const TestEvent = "webhook.test"
+
+// TestPayload is the payload of a test event.
+type TestPayload struct {
+	WebhookID uuid.UUID `json:"webhookID"`
+}
+
+// Delivery headers. The signature is "sha256=" followed by the hex
+// HMAC-SHA256, keyed with the webhook's secret, of the timestamp, a colon and
+// the request body. Receivers should reject stale timestamps to prevent
+// replays, and may use the delivery ID to drop duplicates.
+const (
+	HeaderDelivery  = "X-Webhook-ID"
+	HeaderEvent     = "X-Webhook-Event"
+	HeaderTimestamp = "X-Webhook-Timestamp"
+	HeaderSignature = "X-Webhook-Signature"
+)
+
+type Options struct {
+	// Timeout bounds each attempt, from dialing to reading the response.
+	Timeout time.Duration
+	// MaxAttempts is how many times a delivery is tried before it fails.
+	MaxAttempts int
+	// AllowPrivateNetworks lets webhooks target loopback and private
+	// addresses, which are refused by default so that users cannot reach
+	// internal services.
+	AllowPrivateNetworks bool
+}
+
+// Dispatcher sends events to webhooks.
+type Dispatcher struct {
+	db      *gorm.DB
+	client  *http.Client
+	options Options
+}
+
+// Default is the dispatcher configured at startup.
+var Default *Dispatcher
+
+func NewDispatcher(db *gorm.DB, options Options) *Dispatcher {
+	if options.Timeout <= 0 {
+		options.Timeout = 10 * time.Second
+	}
+	if options.MaxAttempts <= 0 {
+		options.MaxAttempts = 8
+	}
+	return &Dispatcher{
+		db:      db,
+		client:  newClient(options),
+		options: options,
+	}
+}
+
+// EventTypes lists the types a webhook may subscribe to. Presence events are
+// not logged, so they cannot be delivered.
+func EventTypes() []string {
+	types := []string{models.WebhookAllEvents}
+	for _, eventType := range events.Types {
+		if !strings.HasPrefix(eventType, "presence.") {
+			types = append(types, eventType)
+		}
+	}
+	return types
+}
+
+// Run records and sends deliveries every interval until ctx is cancelled.
+func (d *Dispatcher) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := d.enqueue(); err != nil {
+			log.Printf("Error queueing webhook deliveries: %v", err)
+		}
+		if err := d.deliverDue(ctx); err != nil {
+			log.Printf("Error sending webhook deliveries: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// enqueue records a delivery of every new event in the log to each active
+// webhook that wants it. The first run starts from the end of the log.
+func (d *Dispatcher) enqueue() error {
+	for {
+		read := 0
+		err := d.db.Transaction(func(tx *gorm.DB) error {
+			var cursor models.WebhookCursor
+			err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&cursor, "name = ?", cursorName).Error
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				cursor.Name = cursorName
+				if err := tx.Model(&models.EventLogEntry{}).Select("COALESCE(MAX(seq), 0)").Row().Scan(&cursor.Seq); err != nil {
+					return err
+				}
+				return tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&cursor).Error
+			}
+			if err != nil {
+				return err
+			}
+
+			var entries []models.EventLogEntry
+			if err := tx.Where("seq > ? AND created_at < ?", cursor.Seq, time.Now().Add(-settleDelay)).
+				Order("seq").Limit(batchSize).Find(&entries).Error; err != nil {
+				return err
+			}
+			if len(entries) == 0 {
+				return nil
+			}
+			read = len(entries)
+			logged, err := events.FromLogEntries(entries)
+			if err != nil {
+				return err
+			}
+
+			deliveries, err := deliveriesFor(tx, logged)
+			if err != nil {
+				return err
+			}
+			if len(deliveries) > 0 {
+				if err := tx.Clauses(clause.OnConflict{DoNothing: true}).CreateInBatches(&deliveries, batchSize).Error; err != nil {
+					return err
+				}
+			}
+
+			cursor.Seq = entries[len(entries)-1].Seq
+			return tx.Save(&cursor).Error
+		})
+		if err != nil || read < batchSize {
+			return err
+		}
+	}
+}
+
+// deliveriesFor returns the deliveries of logged events to the active
+// webhooks of their audiences.
+func deliveriesFor(tx *gorm.DB, logged []events.Event) ([]models.WebhookDelivery, error) {
+	var userIDs []uuid.UUID
+	for _, event := range logged {
+		for _, userID := range event.Audience {
+			if !slices.Contains(userIDs, userID) {
+				userIDs = append(userIDs, userID)
+			}
+		}
+	}
+	if len(userIDs) == 0 {
+		return nil, nil
+	}
+	var hooks []models.Webhook
+	if err := tx.Where("active AND user_id IN ?", userIDs).Find(&hooks).Error; err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	var deliveries []models.WebhookDelivery
+	for _, event := range logged {
+		var body []byte
+		for _, hook := range hooks {
+			if !wants(hook, event) {
+				continue
+			}
+			if body == nil {
+				var err error
+				if body, err = json.Marshal(event); err != nil {
+					return nil, err
+				}
+			}
+			deliveries = append(deliveries, models.WebhookDelivery{
+				WebhookID:     hook.WebhookID,
+				EventID:       event.ID,
+				EventType:     event.Type,
+				Payload:       string(body),
+				Status:        models.WebhookDeliveryPending,
+				NextAttemptAt: &now,
+			})
+		}
+	}
+	return deliveries, nil
+}
+
+// wants reports whether hook subscribes to event: its owner may see the
+// event, it is of a subscribed type and, for a project webhook, it concerns
+// the project.
+func wants(hook models.Webhook, event events.Event) bool {
+	if !slices.Contains(event.Audience, hook.UserID) || !hook.Subscribes(event.Type) {
+		return false
+	}
+	return hook.ProjectID == nil || slices.Contains(event.Scope.ProjectIDs, *hook.ProjectID)
+}
+
+// deliverDue sends the pending deliveries whose attempt is due, batch by
+// batch, until none are left or ctx is cancelled.
+func (d *Dispatcher) deliverDue(ctx context.Context) error {
+	for ctx.Err() == nil {
+		due, err := d.claim()
+		if err != nil {
+			return err
+		}
+		if len(due) == 0 {
+			return nil
+		}
+
+		hookIDs := make([]uuid.UUID, 0, len(due))
+		for _, delivery := range due {
+			hookIDs = append(hookIDs, delivery.WebhookID)
+		}
+		var hooks []models.Webhook
+		if err := d.db.Where("webhook_id IN ?", hookIDs).Find(&hooks).Error; err != nil {
+			return err
+		}
+		byID := make(map[uuid.UUID]*models.Webhook, len(hooks))
+		for i := range hooks {
+			byID[hooks[i].WebhookID] = &hooks[i]
+		}
+
+		var wg sync.WaitGroup
+		slots := make(chan struct{}, concurrency)
+		for i := range due {
+			delivery := &due[i]
+			hook := byID[delivery.WebhookID]
+			if hook == nil || !hook.Active {
+				d.abandon(delivery, "webhook is disabled")
+				continue
+			}
+			slots <- struct{}{}
+			wg.Add(1)
+			go func() {
+				defer func() {
+					<-slots
+					wg.Done()
+				}()
+				if err := d.attempt(ctx, hook, delivery, true); err != nil {
+					log.Printf("Error saving webhook delivery %s: %v", delivery.DeliveryID, err)
+				}
+			}()
+		}
+		wg.Wait()
+
+		if len(due) < batchSize {
+			return nil
+		}
+	}
+	return nil
+}
+
+// claim takes a batch of due deliveries, pushing their next attempt back
+// past the time sending them may take so that no other instance sends them
+// meanwhile.
+func (d *Dispatcher) claim() ([]models.WebhookDelivery, error) {
+	var due []models.WebhookDelivery
+	err := d.db.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ? AND next_attempt_at <= ?", models.WebhookDeliveryPending, now).
+			Order("next_attempt_at").Limit(batchSize).Find(&due).Error; err != nil {
+			return err
+		}
+		if len(due) == 0 {
+			return nil
+		}
+		ids := make([]uuid.UUID, len(due))
+		for i, delivery := range due {
+			ids[i] = delivery.DeliveryID
+		}
+		lease := now.Add(batchSize/concurrency*d.options.Timeout + time.Minute)
+		return tx.Model(&models.WebhookDelivery{}).Where("delivery_id IN ?", ids).Update("next_attempt_at", lease).Error
+	})
+	return due, err
+}
+
+// attempt sends delivery to hook once and records the outcome. A failed
+// attempt is scheduled again when retry is set and attempts remain.
+func (d *Dispatcher) attempt(ctx context.Context, hook *models.Webhook, delivery *models.WebhookDelivery, retry bool) error {
+	started := time.Now()
+	status, body, err := d.send(ctx, hook, delivery)
+	d.record(delivery, started, status, body, err, retry)
+	return d.db.Save(delivery).Error
+}
+
+// record updates delivery with the outcome of an attempt begun at started
+// and, for a failed attempt, when to try again.
+func (d *Dispatcher) record(delivery *models.WebhookDelivery, started time.Time, status int, body string, err error, retry bool) {
+	delivery.Attempts++
+	delivery.DurationMs = time.Since(started).Milliseconds()
+	delivery.ResponseStatus = status
+	delivery.ResponseBody = body
+	delivery.Error = ""
+
+	now := time.Now()
+	switch {
+	case err == nil && status >= 200 && status < 300:
+		delivery.Status = models.WebhookDeliverySucceeded
+		delivery.NextAttemptAt = nil
+		delivery.DeliveredAt = &now
+	default:
+		if err != nil {
+			delivery.Error = sanitize(err.Error())
+		} else {
+			delivery.Error = "unexpected response status " + strconv.Itoa(status)
+		}
+		if retry && delivery.Attempts < d.options.MaxAttempts {
+			next := now.Add(retryDelay(delivery.Attempts))
+			delivery.Status = models.WebhookDeliveryPending
+			delivery.NextAttemptAt = &next
+		} else {
+			delivery.Status = models.WebhookDeliveryFailed
+			delivery.NextAttemptAt = nil
+		}
+	}
+}
+
+// send posts the delivery and returns the response status and the start of
+// the response body.
+func (d *Dispatcher) send(ctx context.Context, hook *models.Webhook, delivery *models.WebhookDelivery) (int, string, error) {
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, hook.URL, bytes.NewBufferString(delivery.Payload))
+	if err != nil {
+		return 0, "", err
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("User-Agent", userAgent)
+	request.Header.Set(HeaderDelivery, delivery.DeliveryID.String())
+	request.Header.Set(HeaderEvent, delivery.EventType)
+	request.Header.Set(HeaderTimestamp, timestamp)
+	request.Header.Set(HeaderSignature, "sha256="+Sign(hook.Secret, timestamp, []byte(delivery.Payload)))
+
+	response, err := d.client.Do(request)
+	if err != nil {
+		return 0, "", err
+	}
+	defer response.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(response.Body, maxResponseBody))
+	// Drain a little more so the connection can be reused.
+	io.CopyN(io.Discard, response.Body, 64<<10)
+	return response.StatusCode, sanitize(string(body)), nil
+}
+
+// abandon fails a delivery without attempting it.
+func (d *Dispatcher) abandon(delivery *models.WebhookDelivery, reason string) {
+	delivery.Status = models.WebhookDeliveryFailed
+	delivery.NextAttemptAt = nil
+	delivery.Error = reason
+	if err := d.db.Save(delivery).Error; err != nil {
+		log.Printf("Error saving webhook delivery %s: %v", delivery.DeliveryID, err)
+	}
+}
+
+// SendTest sends hook a test event right away, without retrying, and returns
+// the logged delivery.
+func (d *Dispatcher) SendTest(ctx context.Context, hook *models.Webhook) (models.WebhookDelivery, error) {
+	event := events.New(TestEvent, hook.UserID, TestPayload{WebhookID: hook.WebhookID}, hook.UserID)
+	body, err := json.Marshal(event)
+	if err != nil {
+		return models.WebhookDelivery{}, err
+	}
+	delivery := models.WebhookDelivery{
+		WebhookID: hook.WebhookID,
+		EventID:   event.ID,
+		EventType: event.Type,
+		Payload:   string(body),
+		Status:    models.WebhookDeliveryPending,
+	}
+	if err := d.db.Create(&delivery).Error; err != nil {
+		return delivery, err
+	}
+	return delivery, d.attempt(ctx, hook, &delivery, false)
+}
+
+// Sign returns the hex HMAC-SHA256 of timestamp and body, as sent in the
+// signature header.
+func Sign(secret, timestamp string, body []byte) string {
+	return utils.GenerateSignature(secret, timestamp, string(body))
+}
+
+// retryDelay is the wait after the given number of failed attempts.
+func retryDelay(attempts int) time.Duration {
+	delay := baseRetryDelay
+	for i := 1; i < attempts && delay < maxRetryDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, maxRetryDelay)
+}
+
+// sanitize makes text storable in a Postgres text column.
+func sanitize(text string) string {
+	return strings.ReplaceAll(strings.ToValidUTF8(text, "�"), "\x00", "")
+}
@@ -0,0 +1,262 @@
+package webhooks
+
+import (
+	"ai-task-manager/events"
+	"ai-task-manager/models"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+func testDelivery(payload string) *models.WebhookDelivery {
+	return &models.WebhookDelivery{
+		DeliveryID: uuid.Must(uuid.NewV4()),
+		WebhookID:  uuid.Must(uuid.NewV4()),
+		EventID:    uuid.Must(uuid.NewV4()),
+		EventType:  events.TaskUpdated,
+		Payload:    payload,
+		Status:     models.WebhookDeliveryPending,
+	}
+}
+
+// receiver answers each delivery with the next status, repeating the last.
+func receiver(t *testing.T, statuses ...int) (*httptest.Server, *atomic.Int32) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := int(calls.Add(1)) - 1
+		w.WriteHeader(statuses[min(n, len(statuses)-1)])
+		io.WriteString(w, "attempt "+strconv.Itoa(n+1))
+	}))
+	t.Cleanup(server.Close)
+	return server, &calls
+}
+
+func TestSign(t *testing.T) {
+	mac := hmac.New(sha256.New, []byte("secret"))
+	mac.Write([]byte(`1700000000:{"type":"task.updated"}`))
+	want := hex.EncodeToString(mac.Sum(nil))
+
+	if got := Sign("secret", "1700000000", []byte(`{"type":"task.updated"}`)); got != want {
+		t.Errorf("Sign = %s, want %s", got, want)
+	}
+	if Sign("other", "1700000000", []byte(`{"type":"task.updated"}`)) == want {
+		t.Error("signatures with different secrets match")
+	}
+	if Sign("secret", "1700000001", []byte(`{"type":"task.updated"}`)) == want {
+		t.Error("signatures with different timestamps match")
+	}
+}
+
+func TestSendSignsTheDelivery(t *testing.T) {
+	const secret = "whsec_test"
+	delivery := testDelivery(`{"type":"task.updated","payload":{}}`)
+
+	var checked atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		timestamp := r.Header.Get(HeaderTimestamp)
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write([]byte(timestamp + ":"))
+		mac.Write(body)
+		want := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+
+		switch {
+		case r.Method != http.MethodPost:
+			t.Errorf("method = %s", r.Method)
+		case string(body) != delivery.Payload:
+			t.Errorf("body = %s", body)
+		case r.Header.Get(HeaderSignature) != want:
+			t.Errorf("signature = %s, want %s", r.Header.Get(HeaderSignature), want)
+		case r.Header.Get(HeaderDelivery) != delivery.DeliveryID.String():
+			t.Errorf("delivery header = %s", r.Header.Get(HeaderDelivery))
+		case r.Header.Get(HeaderEvent) != delivery.EventType:
+			t.Errorf("event header = %s", r.Header.Get(HeaderEvent))
+		case r.Header.Get("Content-Type") != "application/json":
+			t.Errorf("content type = %s", r.Header.Get("Content-Type"))
+		default:
+			checked.Store(true)
+		}
+		if sent, err := strconv.ParseInt(timestamp, 10, 64); err != nil || time.Since(time.Unix(sent, 0)) > time.Minute {
+			t.Errorf("timestamp = %q", timestamp)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	d := NewDispatcher(nil, Options{AllowPrivateNetworks: true})
+	status, _, err := d.send(context.Background(), &models.Webhook{URL: server.URL, Secret: secret}, delivery)
+	if err != nil || status != http.StatusNoContent {
+		t.Fatalf("send = %d, %v", status, err)
+	}
+	if !checked.Load() {
+		t.Error("the receiver rejected the delivery")
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{1, 30 * time.Second},
+		{2, time.Minute},
+		{3, 2 * time.Minute},
+		{4, 4 * time.Minute},
+		{8, 64 * time.Minute},
+		{10, 256 * time.Minute},
+		{11, 6 * time.Hour},
+		{1000, 6 * time.Hour},
+	}
+	for _, test := range tests {
+		if got := retryDelay(test.attempts); got != test.want {
+			t.Errorf("retryDelay(%d) = %v, want %v", test.attempts, got, test.want)
+		}
+	}
+}
+
+// TestRetriesUntilDelivered runs the attempts the dispatcher would make
+// against a receiver that fails three times, checking the backoff between
+// them.
+func TestRetriesUntilDelivered(t *testing.T) {
+	server, calls := receiver(t, http.StatusInternalServerError, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusOK)
+	d := NewDispatcher(nil, Options{AllowPrivateNetworks: true, MaxAttempts: 5})
+	hook := &models.Webhook{URL: server.URL, Secret: "s"}
+	delivery := testDelivery(`{}`)
+
+	for attempt := 1; attempt <= 3; attempt++ {
+		started := time.Now()
+		status, body, err := d.send(context.Background(), hook, delivery)
+		d.record(delivery, started, status, body, err, true)
+
+		if delivery.Status != models.WebhookDeliveryPending || delivery.Attempts != attempt {
+			t.Fatalf("after attempt %d: status %s, attempts %d", attempt, delivery.Status, delivery.Attempts)
+		}
+		if delivery.Error != "unexpected response status "+strconv.Itoa(status) || delivery.ResponseBody != "attempt "+strconv.Itoa(attempt) {
+			t.Errorf("after attempt %d: error %q, body %q", attempt, delivery.Error, delivery.ResponseBody)
+		}
+		wait := delivery.NextAttemptAt.Sub(started)
+		if want := retryDelay(attempt); wait < want || wait > want+time.Second {
+			t.Errorf("after attempt %d: next attempt in %v, want %v", attempt, wait, want)
+		}
+	}
+
+	started := time.Now()
+	status, body, err := d.send(context.Background(), hook, delivery)
+	d.record(delivery, started, status, body, err, true)
+	if delivery.Status != models.WebhookDeliverySucceeded || delivery.NextAttemptAt != nil || delivery.DeliveredAt == nil {
+		t.Errorf("after the last attempt: status %s, next %v, delivered %v", delivery.Status, delivery.NextAttemptAt, delivery.DeliveredAt)
+	}
+	if delivery.Error != "" || delivery.ResponseStatus != http.StatusOK {
+		t.Errorf("after the last attempt: error %q, response %d", delivery.Error, delivery.ResponseStatus)
+	}
+	if calls.Load() != 4 {
+		t.Errorf("receiver called %d times, want 4", calls.Load())
+	}
+}
+
+func TestGivesUpAfterMaxAttempts(t *testing.T) {
+	server, _ := receiver(t, http.StatusServiceUnavailable)
+	d := NewDispatcher(nil, Options{AllowPrivateNetworks: true, MaxAttempts: 3})
+	hook := &models.Webhook{URL: server.URL, Secret: "s"}
+
+	delivery := testDelivery(`{}`)
+	for attempt := 1; attempt <= 3; attempt++ {
+		status, body, err := d.send(context.Background(), hook, delivery)
+		d.record(delivery, time.Now(), status, body, err, true)
+	}
+	if delivery.Status != models.WebhookDeliveryFailed || delivery.NextAttemptAt != nil {
+		t.Errorf("after MaxAttempts: status %s, next %v", delivery.Status, delivery.NextAttemptAt)
+	}
+
+	// Test deliveries are never retried.
+	test := testDelivery(`{}`)
+	status, body, err := d.send(context.Background(), hook, test)
+	d.record(test, time.Now(), status, body, err, false)
+	if test.Status != models.WebhookDeliveryFailed || test.Attempts != 1 {
+		t.Errorf("without retry: status %s, attempts %d", test.Status, test.Attempts)
+	}
+}
+
+func TestTimeoutIsRetried(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+
+	d := NewDispatcher(nil, Options{AllowPrivateNetworks: true, Timeout: 100 * time.Millisecond})
+	delivery := testDelivery(`{}`)
+	started := time.Now()
+	status, body, err := d.send(context.Background(), &models.Webhook{URL: server.URL}, delivery)
+	if err == nil {
+		t.Fatal("send to a receiver that never answers succeeded")
+	}
+	d.record(delivery, started, status, body, err, true)
+	if delivery.Status != models.WebhookDeliveryPending || delivery.Error == "" || delivery.NextAttemptAt == nil {
+		t.Errorf("after a timeout: status %s, error %q, next %v", delivery.Status, delivery.Error, delivery.NextAttemptAt)
+	}
+}
+
+func TestRedirectsAreNotFollowed(t *testing.T) {
+	target, calls := receiver(t, http.StatusOK)
+	server := httptest.NewServer(http.RedirectHandler(target.URL, http.StatusTemporaryRedirect))
+	defer server.Close()
+
+	d := NewDispatcher(nil, Options{AllowPrivateNetworks: true})
+	status, _, err := d.send(context.Background(), &models.Webhook{URL: server.URL}, testDelivery(`{}`))
+	if err != nil || status != http.StatusTemporaryRedirect {
+		t.Errorf("send = %d, %v, want %d", status, err, http.StatusTemporaryRedirect)
+	}
+	if calls.Load() != 0 {
+		t.Error("the redirect was followed")
+	}
+}
+
+func TestPrivateAddressesAreRefused(t *testing.T) {
+	server, calls := receiver(t, http.StatusOK)
+	d := NewDispatcher(nil, Options{})
+	_, _, err := d.send(context.Background(), &models.Webhook{URL: server.URL}, testDelivery(`{}`))
+	if !errors.Is(err, ErrForbiddenAddress) {
+		t.Errorf("send to loopback: err = %v, want ErrForbiddenAddress", err)
+	}
+	if calls.Load() != 0 {
+		t.Error("the loopback receiver was called")
+	}
+}
+
+func TestWants(t *testing.T) {
+	owner, other := uuid.Must(uuid.NewV4()), uuid.Must(uuid.NewV4())
+	project, otherProject := uuid.Must(uuid.NewV4()), uuid.Must(uuid.NewV4())
+	event := events.New(events.TaskUpdated, owner, nil, owner)
+	event.Scope.ProjectIDs = []uuid.UUID{project}
+
+	tests := []struct {
+		name string
+		hook models.Webhook
+		want bool
+	}{
+		{"all events", models.Webhook{UserID: owner, EventTypes: models.WebhookAllEvents}, true},
+		{"subscribed type", models.Webhook{UserID: owner, EventTypes: events.TaskCreated + "," + events.TaskUpdated}, true},
+		{"other type", models.Webhook{UserID: owner, EventTypes: events.TaskDeleted}, false},
+		{"outside the audience", models.Webhook{UserID: other, EventTypes: models.WebhookAllEvents}, false},
+		{"its project", models.Webhook{UserID: owner, EventTypes: models.WebhookAllEvents, ProjectID: &project}, true},
+		{"another project", models.Webhook{UserID: owner, EventTypes: models.WebhookAllEvents, ProjectID: &otherProject}, false},
+	}
+	for _, test := range tests {
+		if got := wants(test.hook, event); got != test.want {
+			t.Errorf("%s: wants = %v, want %v", test.name, got, test.want)
+		}
+	}
+}
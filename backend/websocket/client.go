@@ -0,0 +1,532 @@
+package websocket
+
+import (
+	"ai-task-manager/collab"
+	"ai-task-manager/events"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/gorilla/websocket"
+	"gorm.io/gorm"
+)
+
+// maxSubscriptions bounds the topics one connection can subscribe to.
+const maxSubscriptions = 100
+
+// Commands a client can send.
+const (
+	CommandSubscribe   = "subscribe"
+	CommandUnsubscribe = "unsubscribe"
+	CommandPing        = "ping"
+	CommandView        = "view"
+	CommandLeave       = "leave"
+	CommandTyping      = "typing"
+	CommandJoinEdit    = "join_edit"
+	CommandEdit        = "edit"
+	CommandLeaveEdit   = "leave_edit"
+)
+
+// Frames sent while catching a reconnecting client up.
+const (
+	// ReplyReplayed follows the replayed events; lastEventId is the seq of
+	// the last event the client has now been sent.
+	ReplyReplayed = "replayed"
+	// ReplyResyncRequired means the missed events could not be replayed and
+	// the client has to refetch its state. Live events follow as usual.
+	ReplyResyncRequired = "resync_required"
+)
+
+// Error codes sent in error frames.
+const (
+	ErrorInvalidCommand = "invalid_command"
+	ErrorUnknownCommand = "unknown_command"
+	ErrorInvalidTopic   = "invalid_topic"
+	ErrorTooManyTopics  = "too_many_topics"
+	ErrorNotSubscribed  = "not_subscribed"
+	ErrorInvalidTask    = "invalid_task"
+	ErrorNotViewing     = "not_viewing"
+	ErrorNotEditing     = "not_editing"
+	ErrorInvalidOp      = "invalid_operation"
+	ErrorStaleRevision  = "stale_revision"
+	ErrorTooLong        = "too_long"
+	ErrorUnavailable    = "unavailable"
+)
+
+// command is a frame sent by the client, for example
+//
+//	{"type": "subscribe", "topic": "project:<projectID>", "id": "1"}
+//	{"type": "view", "taskID": "<taskID>"}
+//	{"type": "edit", "taskID": "<taskID>", "revision": 7, "op": [3, "abc", -1, 8]}
+//
+// The optional id is echoed in the reply so clients can match them up.
+// Typing is not answered, and editing is answered with collab frames.
+type command struct {
+	Type     string          `json:"type"`
+	Topic    string          `json:"topic"`
+	TaskID   string          `json:"taskID"`
+	Revision *int            `json:"revision"`
+	Op       json.RawMessage `json:"op"`
+	ID       string          `json:"id,omitempty"`
+}
+
+// reply is a frame sent in answer to a command. Its type never contains a
+// dot, so it cannot be confused with an event.
+type reply struct {
+	Type        string   `json:"type"`
+	ID          string   `json:"id,omitempty"`
+	Topics      []string `json:"topics,omitempty"`
+	Code        string   `json:"code,omitempty"`
+	Message     string   `json:"message,omitempty"`
+	Count       int      `json:"count,omitempty"`
+	LastEventID int64    `json:"lastEventId,omitempty"`
+	TaskID      string   `json:"taskID,omitempty"`
+	Viewers     []Viewer `json:"viewers,omitempty"`
+}
+
+// client is one connection. Until it subscribes to something it receives
+// every event its user may see, as if subscribed to "all"; its first
+// subscription replaces that default, and unsubscribing from every topic
+// silences it.
+type client struct {
+	manager *WebSocketManager
+	id      uuid.UUID
+	userID  uuid.UUID
+	conn    *websocket.Conn
+	policy  string
+
+	// send is drained by writePump; nothing else writes to conn.
+	send      chan []byte
+	done      chan struct{}
+	closeMu   sync.Mutex
+	closed    bool
+	closeCode int
+	closeText string
+
+	mu     sync.Mutex
+	topics []events.Topic
+	chosen bool
+
+	// While replaying, live events wait in pending so that they follow the
+	// replayed ones and are not sent twice.
+	replaying    bool
+	pending      []pendingEvent
+	pendingLimit int
+
+	presence presence
+}
+
+type pendingEvent struct {
+	seq     int64
+	message []byte
+}
+
+func newClient(manager *WebSocketManager, userID uuid.UUID, conn *websocket.Conn, options Options) *client {
+	return &client{
+		manager:      manager,
+		id:           uuid.Must(uuid.NewV4()),
+		userID:       userID,
+		conn:         conn,
+		policy:       options.SlowClientPolicy,
+		send:         make(chan []byte, options.SendQueueSize),
+		done:         make(chan struct{}),
+		pendingLimit: options.ReplayLimit,
+	}
+}
+
+// enqueue queues a message without waiting. When the queue is full the
+// message is dropped or the connection closed, depending on the policy.
+func (c *client) enqueue(message []byte) {
+	select {
+	case <-c.done:
+		return
+	default:
+	}
+
+	select {
+	case c.send <- message:
+	default:
+		if c.policy == SlowClientDrop {
+			c.manager.dropped.Add(1)
+			return
+		}
+		c.manager.slowDisconnects.Add(1)
+		c.close(websocket.CloseTryAgainLater, "client too slow")
+	}
+}
+
+// enqueueWait queues a message, waiting for room rather than applying the
+// slow client policy. It is used for replays, which are sent in bursts. It
+// returns false once the connection is closing.
+func (c *client) enqueueWait(message []byte) bool {
+	select {
+	case c.send <- message:
+		return true
+	case <-c.done:
+		return false
+	}
+}
+
+// deliver queues a live event, or holds it back while a replay is running.
+func (c *client) deliver(seq int64, message []byte) {
+	c.mu.Lock()
+	if !c.replaying {
+		c.mu.Unlock()
+		c.enqueue(message)
+		return
+	}
+	if len(c.pending) < c.pendingLimit {
+		c.pending = append(c.pending, pendingEvent{seq: seq, message: message})
+		c.mu.Unlock()
+		return
+	}
+	c.mu.Unlock()
+
+	if c.policy == SlowClientDrop {
+		c.manager.dropped.Add(1)
+		return
+	}
+	c.manager.slowDisconnects.Add(1)
+	c.close(websocket.CloseTryAgainLater, "client too slow")
+}
+
+// replay sends the events published after lastEventID, or a resync_required
+// frame when they are no longer all retained, and then the live events held
+// back meanwhile.
+func (c *client) replay(options Options, lastEventID int64) {
+	missed, err := events.Replay(options.EventLog, c.userID, lastEventID, options.ReplayLimit)
+	last, count := lastEventID, 0
+	// Live events can arrive out of sequence order, so those held back are
+	// matched against the replayed ones rather than against last.
+	replayed := make(map[int64]bool, len(missed))
+	if err == nil {
+		for _, event := range missed {
+			last = event.Seq
+			replayed[event.Seq] = true
+			if !c.wants(event) {
+				continue
+			}
+			message, err := json.Marshal(event)
+			if err != nil {
+				log.Printf("Error encoding %s event: %v", event.Type, err)
+				continue
+			}
+			if !c.enqueueWait(message) {
+				return
+			}
+			count++
+		}
+		c.replyWait(reply{Type: ReplyReplayed, Count: count, LastEventID: last})
+	} else {
+		if !errors.Is(err, events.ErrResyncRequired) {
+			log.Printf("Error replaying events for user %s: %v", c.userID, err)
+		}
+		// Nothing was replayed, so every held back event is new to the client.
+		c.replyWait(reply{Type: ReplyResyncRequired, LastEventID: lastEventID, Message: events.ErrResyncRequired.Error()})
+	}
+
+	for {
+		c.mu.Lock()
+		held := c.pending
+		c.pending = nil
+		if len(held) == 0 {
+			c.replaying = false
+			c.mu.Unlock()
+			return
+		}
+		c.mu.Unlock()
+
+		for _, event := range held {
+			if replayed[event.seq] {
+				continue
+			}
+			if !c.enqueueWait(event.message) {
+				return
+			}
+		}
+	}
+}
+
+// close asks writePump to send a close frame and shut the connection down.
+func (c *client) close(code int, text string) {
+	c.closeMu.Lock()
+	defer c.closeMu.Unlock()
+
+	if c.closed {
+		return
+	}
+	c.closed = true
+	c.closeCode, c.closeText = code, text
+	close(c.done)
+}
+
+func (c *client) reply(r reply) {
+	message, err := json.Marshal(r)
+	if err != nil {
+		return
+	}
+	c.enqueue(message)
+}
+
+func (c *client) replyWait(r reply) {
+	message, err := json.Marshal(r)
+	if err != nil {
+		return
+	}
+	c.enqueueWait(message)
+}
+
+func (c *client) sendError(id, code, message string) {
+	c.reply(reply{Type: "error", ID: id, Code: code, Message: message})
+}
+
+// writePump is the only writer to the connection. It sends queued messages
+// and heartbeat pings, and closes the connection when it stops.
+func (c *client) writePump(options Options) {
+	ticker := time.NewTicker(options.PongTimeout / 2)
+	defer func() {
+		ticker.Stop()
+		c.conn.Close()
+	}()
+
+	for {
+		select {
+		case message := <-c.send:
+			c.conn.SetWriteDeadline(time.Now().Add(options.WriteTimeout))
+			if err := c.conn.WriteMessage(websocket.TextMessage, message); err != nil {
+				c.close(websocket.CloseAbnormalClosure, "")
+				return
+			}
+		case <-ticker.C:
+			c.conn.SetWriteDeadline(time.Now().Add(options.WriteTimeout))
+			if err := c.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				c.close(websocket.CloseAbnormalClosure, "")
+				return
+			}
+		case <-c.done:
+			c.closeMu.Lock()
+			code, text := c.closeCode, c.closeText
+			c.closeMu.Unlock()
+			if code != websocket.CloseAbnormalClosure {
+				deadline := time.Now().Add(options.WriteTimeout)
+				_ = c.conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, text), deadline)
+			}
+			return
+		}
+	}
+}
+
+// readPump serves client commands until the connection fails, the client
+// goes away or it misses its heartbeat.
+func (c *client) readPump(options Options) {
+	defer c.close(websocket.CloseNormalClosure, "")
+
+	c.conn.SetReadLimit(options.MaxMessageSize)
+	extend := func() {
+		c.conn.SetReadDeadline(time.Now().Add(options.PongTimeout))
+	}
+	extend()
+	c.conn.SetPongHandler(func(string) error {
+		extend()
+		return nil
+	})
+
+	for {
+		_, message, err := c.conn.ReadMessage()
+		if err != nil {
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				c.manager.heartbeatTimeouts.Add(1)
+			} else if websocket.IsUnexpectedCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway, websocket.CloseNoStatusReceived) &&
+				!errors.Is(err, net.ErrClosed) {
+				log.Printf("WebSocket read error for user %s: %v", c.userID, err)
+			}
+			return
+		}
+		extend()
+		c.handleCommand(message)
+	}
+}
+
+func (c *client) wants(event events.Event) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.chosen {
+		return true
+	}
+	return events.MatchesAny(c.topics, event, c.userID)
+}
+
+func (c *client) topicNames() []string {
+	names := make([]string, len(c.topics))
+	for i, topic := range c.topics {
+		names[i] = topic.String()
+	}
+	return names
+}
+
+func (c *client) handleCommand(message []byte) {
+	var cmd command
+	if err := json.Unmarshal(message, &cmd); err != nil {
+		c.sendError("", ErrorInvalidCommand, "commands must be JSON objects with a type")
+		return
+	}
+
+	switch cmd.Type {
+	case CommandPing:
+		c.reply(reply{Type: "pong", ID: cmd.ID})
+	case CommandSubscribe, CommandUnsubscribe:
+		topic, err := events.ParseTopic(cmd.Topic)
+		if err != nil {
+			c.sendError(cmd.ID, ErrorInvalidTopic, err.Error())
+			return
+		}
+		if cmd.Type == CommandSubscribe {
+			c.subscribe(cmd.ID, topic)
+		} else {
+			c.unsubscribe(cmd.ID, topic)
+		}
+	case CommandView:
+		taskID, err := uuid.FromString(cmd.TaskID)
+		if err != nil || taskID == uuid.Nil {
+			c.sendError(cmd.ID, ErrorInvalidTask, "taskID must be a task ID")
+			return
+		}
+		allowed, err := c.manager.canViewTask(c.userID, taskID)
+		if err != nil {
+			log.Printf("Error checking access to task %s for user %s: %v", taskID, c.userID, err)
+			c.sendError(cmd.ID, ErrorUnavailable, "could not view the task, try again")
+			return
+		}
+		if !allowed {
+			c.sendError(cmd.ID, ErrorInvalidTask, "task not found")
+			return
+		}
+		viewers, err := c.manager.view(c, taskID)
+		if err != nil {
+			log.Printf("Error viewing task %s for user %s: %v", taskID, c.userID, err)
+			c.sendError(cmd.ID, ErrorUnavailable, "could not view the task, try again")
+			return
+		}
+		c.reply(reply{Type: "viewing", ID: cmd.ID, TaskID: taskID.String(), Viewers: viewers})
+	case CommandLeave:
+		if !c.manager.leave(c) {
+			c.sendError(cmd.ID, ErrorNotViewing, "not viewing a task")
+			return
+		}
+		c.reply(reply{Type: "left", ID: cmd.ID})
+	case CommandTyping:
+		if !c.manager.typing(c) {
+			c.sendError(cmd.ID, ErrorNotViewing, "view a task before typing on it")
+		}
+	case CommandJoinEdit, CommandEdit, CommandLeaveEdit:
+		c.handleEdit(cmd)
+	case "":
+		c.sendError(cmd.ID, ErrorInvalidCommand, "type is required")
+	default:
+		c.sendError(cmd.ID, ErrorUnknownCommand, fmt.Sprintf("unknown command %q", cmd.Type))
+	}
+}
+
+func (c *client) subscribe(id string, topic events.Topic) {
+	c.mu.Lock()
+	if !c.chosen {
+		c.chosen = true
+		c.topics = nil
+	}
+	if !slices.Contains(c.topics, topic) {
+		if len(c.topics) >= maxSubscriptions {
+			c.mu.Unlock()
+			c.sendError(id, ErrorTooManyTopics, fmt.Sprintf("a connection can subscribe to at most %d topics", maxSubscriptions))
+			return
+		}
+		c.topics = append(c.topics, topic)
+	}
+	topics := c.topicNames()
+	c.mu.Unlock()
+
+	c.reply(reply{Type: "subscribed", ID: id, Topics: topics})
+}
+
+func (c *client) unsubscribe(id string, topic events.Topic) {
+	c.mu.Lock()
+	i := slices.Index(c.topics, topic)
+	if !c.chosen || i < 0 {
+		c.mu.Unlock()
+		c.sendError(id, ErrorNotSubscribed, fmt.Sprintf("not subscribed to %s", topic))
+		return
+	}
+	c.topics = slices.Delete(c.topics, i, i+1)
+	topics := c.topicNames()
+	c.mu.Unlock()
+
+	c.reply(reply{Type: "unsubscribed", ID: id, Topics: topics})
+}
+
+// UserID and Send make a client a collab.Peer.
+func (c *client) UserID() uuid.UUID {
+	return c.userID
+}
+
+func (c *client) Send(frame collab.Frame) {
+	message, err := json.Marshal(frame)
+	if err != nil {
+		log.Printf("Error encoding %s frame: %v", frame.Type, err)
+		return
+	}
+	c.enqueue(message)
+}
+
+func (c *client) handleEdit(cmd command) {
+	if collab.Default == nil {
+		c.sendError(cmd.ID, ErrorUnavailable, "collaborative editing is not available")
+		return
+	}
+	taskID, err := uuid.FromString(cmd.TaskID)
+	if err != nil || taskID == uuid.Nil {
+		c.sendError(cmd.ID, ErrorInvalidTask, "taskID must be a task ID")
+		return
+	}
+
+	switch cmd.Type {
+	case CommandJoinEdit:
+		err = collab.Default.Join(c, taskID)
+	case CommandLeaveEdit:
+		err = collab.Default.Leave(c, taskID)
+	case CommandEdit:
+		var op collab.Operation
+		if cmd.Revision == nil || len(cmd.Op) == 0 {
+			c.sendError(cmd.ID, ErrorInvalidOp, "revision and op are required")
+			return
+		}
+		if err := json.Unmarshal(cmd.Op, &op); err != nil {
+			c.sendError(cmd.ID, ErrorInvalidOp, err.Error())
+			return
+		}
+		err = collab.Default.Edit(c, taskID, *cmd.Revision, op)
+	}
+
+	switch {
+	case err == nil:
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.sendError(cmd.ID, ErrorInvalidTask, "task not found")
+	case errors.Is(err, collab.ErrNotEditing):
+		c.sendError(cmd.ID, ErrorNotEditing, err.Error())
+	case errors.Is(err, collab.ErrStaleRevision):
+		c.sendError(cmd.ID, ErrorStaleRevision, err.Error())
+	case errors.Is(err, collab.ErrTooLong):
+		c.sendError(cmd.ID, ErrorTooLong, err.Error())
+	case errors.Is(err, collab.ErrInvalidOperation):
+		c.sendError(cmd.ID, ErrorInvalidOp, err.Error())
+	default:
+		log.Printf("Error handling %s for user %s: %v", cmd.Type, c.userID, err)
+		c.sendError(cmd.ID, ErrorUnavailable, "could not handle the edit, try again")
+	}
+}
@@ -1,66 +1,353 @@
 package websocket
 
 import (
-	"fmt"
+	"ai-task-manager/collab"
+	"ai-task-manager/events"
+	"ai-task-manager/utils"
+	"encoding/json"
+	"log"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/gofrs/uuid"
 	"github.com/gorilla/websocket"
-	"net/http"
 )
 
+// Policies for a client whose send queue is full.
+const (
+	// SlowClientDisconnect closes the connection; the client reconnects and
+	// refetches what it missed.
+	SlowClientDisconnect = "disconnect"
+	// SlowClientDrop discards the message and keeps the connection.
+	SlowClientDrop = "drop"
+)
+
+// Options tune the manager. Zero values fall back to DefaultOptions.
+type Options struct {
+	// AllowedOrigins lists the origins allowed to connect. An empty list
+	// only admits same-origin requests; "*" admits any origin.
+	AllowedOrigins []string
+	// TicketTTL is how long a connection ticket stays valid.
+	TicketTTL time.Duration
+	// Tickets keeps the connection tickets. Without it a ticket can only be
+	// redeemed on the instance that issued it.
+	Tickets TicketStore
+	// SendQueueSize is the number of messages buffered per connection.
+	SendQueueSize int
+	// SlowClientPolicy is SlowClientDisconnect or SlowClientDrop.
+	SlowClientPolicy string
+	// PongTimeout is how long a connection may stay silent, pongs included,
+	// before it is considered dead. Pings go out at half of it.
+	PongTimeout time.Duration
+	// WriteTimeout bounds a single write to the connection.
+	WriteTimeout time.Duration
+	// MaxMessageSize bounds a frame sent by the client. Edits pasting text
+	// into a description need room.
+	MaxMessageSize int64
+	// EventLog is replayed to clients that reconnect with lastEventId. Without
+	// one they are always told to resync.
+	EventLog events.Log
+	// ReplayLimit bounds the events replayed to a reconnecting client; a
+	// client that missed more has to resync.
+	ReplayLimit int
+	// CanViewTask reports whether a user may see a task, and so view it and
+	// hear who else does. Without it no task can be viewed.
+	CanViewTask func(userID, taskID uuid.UUID) (bool, error)
+	// Presence records who views which task. Without it the viewers are only
+	// known to this instance.
+	Presence PresenceStore
+	// Signal shares presence events with every instance, this one included.
+	// Without it they only reach the connections of this instance.
+	Signal func(events ...events.Event)
+}
+
+var DefaultOptions = Options{
+	TicketTTL:        30 * time.Second,
+	SendQueueSize:    64,
+	SlowClientPolicy: SlowClientDisconnect,
+	PongTimeout:      60 * time.Second,
+	WriteTimeout:     10 * time.Second,
+	MaxMessageSize:   64 << 10,
+	ReplayLimit:      1000,
+}
+
+// WebSocketManager keeps the open connections of each user. Messages are
+// only ever sent to the users they are addressed to. Each connection has its
+// own bounded send queue drained by its own goroutine, so a slow client only
+// ever delays itself. Tickets and viewers are shared with the other instances
+// through the ticket and presence stores.
 type WebSocketManager struct {
-	clients   map[*websocket.Conn]bool
-	Broadcast chan []byte
-	mu        sync.Mutex
+	clients map[uuid.UUID]map[*client]bool
+	// viewers holds the connections of this instance viewing each task, to
+	// send presence events to.
+	viewers map[uuid.UUID]map[*client]bool
+	mu      sync.RWMutex
+
+	localTickets  TicketStore
+	localPresence PresenceStore
+
+	options Options
+
+	dropped           atomic.Int64
+	slowDisconnects   atomic.Int64
+	heartbeatTimeouts atomic.Int64
+}
+
+var Manager = NewManager(DefaultOptions)
+
+func NewManager(options Options) *WebSocketManager {
+	manager := &WebSocketManager{
+		clients: make(map[uuid.UUID]map[*client]bool),
+		viewers: make(map[uuid.UUID]map[*client]bool),
+
+		localTickets:  newMemoryTickets(),
+		localPresence: newMemoryPresence(),
+	}
+	manager.Configure(options)
+	return manager
+}
+
+// Configure replaces the options. Connections already open keep the queue
+// size they were opened with. Change the presence store before any
+// connection views a task.
+func (manager *WebSocketManager) Configure(options Options) {
+	if options.TicketTTL <= 0 {
+		options.TicketTTL = DefaultOptions.TicketTTL
+	}
+	if options.SendQueueSize <= 0 {
+		options.SendQueueSize = DefaultOptions.SendQueueSize
+	}
+	if options.SlowClientPolicy != SlowClientDrop {
+		options.SlowClientPolicy = SlowClientDisconnect
+	}
+	if options.PongTimeout <= 0 {
+		options.PongTimeout = DefaultOptions.PongTimeout
+	}
+	if options.WriteTimeout <= 0 {
+		options.WriteTimeout = DefaultOptions.WriteTimeout
+	}
+	if options.MaxMessageSize <= 0 {
+		options.MaxMessageSize = DefaultOptions.MaxMessageSize
+	}
+	if options.ReplayLimit <= 0 {
+		options.ReplayLimit = DefaultOptions.ReplayLimit
+	}
+	if options.Tickets == nil {
+		options.Tickets = manager.localTickets
+	}
+	if options.Presence == nil {
+		options.Presence = manager.localPresence
+	}
+	origins := make([]string, len(options.AllowedOrigins))
+	for i, origin := range options.AllowedOrigins {
+		origins[i] = strings.TrimSuffix(strings.ToLower(origin), "/")
+	}
+	options.AllowedOrigins = origins
+
+	manager.mu.Lock()
+	defer manager.mu.Unlock()
+	manager.options = options
 }
 
-var Manager = &WebSocketManager{
-	clients:   make(map[*websocket.Conn]bool),
-	Broadcast: make(chan []byte),
+func (manager *WebSocketManager) currentOptions() Options {
+	manager.mu.RLock()
+	defer manager.mu.RUnlock()
+	return manager.options
 }
 
-var upgrader = websocket.Upgrader{
-	CheckOrigin: func(r *http.Request) bool {
+// checkOrigin admits requests without an Origin header (non-browser clients),
+// same-origin requests and the configured origins.
+func (manager *WebSocketManager) checkOrigin(r *http.Request) bool {
+	origin := strings.ToLower(r.Header.Get("Origin"))
+	if origin == "" {
 		return true
-	},
+	}
+	allowed := manager.currentOptions().AllowedOrigins
+	if slices.Contains(allowed, "*") || slices.Contains(allowed, origin) {
+		return true
+	}
+	return strings.TrimPrefix(strings.TrimPrefix(origin, "https://"), "http://") == strings.ToLower(r.Host)
+}
+
+// IssueTicket returns a ticket that opens one connection for the user before
+// it expires. Only its hash is kept.
+func (manager *WebSocketManager) IssueTicket(userID uuid.UUID) (string, time.Time, error) {
+	token, err := utils.GenerateToken()
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	options := manager.currentOptions()
+	expiresAt := time.Now().Add(options.TicketTTL)
+	if err := options.Tickets.Issue(utils.HashToken(token), userID, expiresAt); err != nil {
+		return "", time.Time{}, err
+	}
+	return token, expiresAt, nil
+}
+
+// RedeemTicket consumes a ticket and returns the user it was issued to.
+func (manager *WebSocketManager) RedeemTicket(token string) (uuid.UUID, bool) {
+	userID, ok, err := manager.currentOptions().Tickets.Redeem(utils.HashToken(token))
+	if err != nil {
+		log.Printf("Error redeeming a WebSocket ticket: %v", err)
+		return uuid.Nil, false
+	}
+	return userID, ok
 }
 
+// HandleConnections upgrades an authenticated request and serves the
+// connection for the caller. A client reconnecting after a disconnect passes
+// the seq of the last event it received as ?lastEventId= to be sent the
+// events it missed.
 func (manager *WebSocketManager) HandleConnections(c *gin.Context) {
+	userID, err := utils.GetUserIdFromHeader(c)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "Error: get userID from header", err.Error())
+		return
+	}
+	uuidUserID, err := utils.IsUUID(userID)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "Error: convert userID into UUID", err.Error())
+		return
+	}
+	var lastEventID int64
+	if value := c.Query("lastEventId"); value != "" {
+		lastEventID, err = strconv.ParseInt(value, 10, 64)
+		if err != nil || lastEventID < 0 {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Invalid lastEventId", "lastEventId must be the seq of an event")
+			return
+		}
+	}
+
+	upgrader := websocket.Upgrader{CheckOrigin: manager.checkOrigin}
 	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
 	if err != nil {
-		fmt.Println("WebSocket Upgrade Error:", err)
+		log.Printf("WebSocket upgrade error: %v", err)
 		return
 	}
+	manager.Serve(uuidUserID, conn, lastEventID)
+}
+
+// Serve registers an upgraded connection under userID and runs it until it
+// closes. When lastEventID is not zero the events published after it are
+// replayed first.
+func (manager *WebSocketManager) Serve(userID uuid.UUID, conn *websocket.Conn, lastEventID int64) {
+	options := manager.currentOptions()
+	client := newClient(manager, userID, conn, options)
+	// Registered before the replay starts so that nothing published in
+	// between is missed; live events are held back until it is done.
+	client.replaying = lastEventID > 0
+	manager.register(client)
+	defer manager.unregister(client)
+	if collab.Default != nil {
+		defer collab.Default.LeaveAll(client)
+	}
+
+	go client.writePump(options)
+	if client.replaying {
+		go client.replay(options, lastEventID)
+	}
+	client.readPump(options)
+}
+
+func (manager *WebSocketManager) register(c *client) {
+	manager.mu.Lock()
+	defer manager.mu.Unlock()
+
+	if manager.clients[c.userID] == nil {
+		manager.clients[c.userID] = make(map[*client]bool)
+	}
+	manager.clients[c.userID][c] = true
+}
+
+func (manager *WebSocketManager) unregister(c *client) {
 	manager.mu.Lock()
-	manager.clients[conn] = true
+	left := manager.leaveLocked(c)
+	if clients, ok := manager.clients[c.userID]; ok && clients[c] {
+		delete(clients, c)
+		if len(clients) == 0 {
+			delete(manager.clients, c.userID)
+		}
+	}
 	manager.mu.Unlock()
 
-	defer func() {
-		manager.mu.Lock()
-		delete(manager.clients, conn)
-		manager.mu.Unlock()
-		conn.Close()
-	}()
-
-	for {
-		_, msg, err := conn.ReadMessage()
-		if err != nil {
-			break
+	if left != uuid.Nil {
+		manager.forget(c, left)
+	}
+}
+
+// HandleEvent queues a domain event for the connections of its audience that
+// subscribed to a matching topic, and a presence event for the viewers of its
+// task. It never waits on a connection. It is subscribed to the event bus at
+// startup.
+func (manager *WebSocketManager) HandleEvent(event events.Event) {
+	message, err := json.Marshal(event)
+	if err != nil {
+		log.Printf("Error encoding %s event: %v", event.Type, err)
+		return
+	}
+	if events.IsPresence(event) {
+		manager.sendPresence(event, message)
+		return
+	}
+
+	manager.mu.RLock()
+	var targets []*client
+	for _, userID := range event.Audience {
+		for c := range manager.clients[userID] {
+			if c.wants(event) {
+				targets = append(targets, c)
+			}
 		}
-		manager.Broadcast <- msg
+	}
+	manager.mu.RUnlock()
+
+	for _, c := range targets {
+		c.deliver(event.Seq, message)
 	}
 }
 
-func (manager *WebSocketManager) BroadcastMessage(message []byte) {
-	manager.mu.Lock()
-	defer manager.mu.Unlock()
+// Stats is a snapshot of the connections and their send queues. The counters
+// are totals since the process started.
+type Stats struct {
+	Users             int    `json:"users"`
+	Connections       int    `json:"connections"`
+	QueueCapacity     int    `json:"queueCapacity"`
+	QueuedMessages    int    `json:"queuedMessages"`
+	MaxQueueDepth     int    `json:"maxQueueDepth"`
+	SlowClientPolicy  string `json:"slowClientPolicy"`
+	DroppedMessages   int64  `json:"droppedMessages"`
+	SlowDisconnects   int64  `json:"slowDisconnects"`
+	HeartbeatTimeouts int64  `json:"heartbeatTimeouts"`
+	ViewedTasks       int    `json:"viewedTasks"`
+}
 
-	for client := range manager.clients {
-		err := client.WriteMessage(websocket.TextMessage, message)
-		if err != nil {
-			client.Close()
-			delete(manager.clients, client)
+func (manager *WebSocketManager) Stats() Stats {
+	manager.mu.RLock()
+	defer manager.mu.RUnlock()
+
+	stats := Stats{
+		Users:             len(manager.clients),
+		QueueCapacity:     manager.options.SendQueueSize,
+		SlowClientPolicy:  manager.options.SlowClientPolicy,
+		DroppedMessages:   manager.dropped.Load(),
+		SlowDisconnects:   manager.slowDisconnects.Load(),
+		HeartbeatTimeouts: manager.heartbeatTimeouts.Load(),
+		ViewedTasks:       len(manager.viewers),
+	}
+	for _, clients := range manager.clients {
+		for c := range clients {
+			depth := len(c.send)
+			stats.Connections++
+			stats.QueuedMessages += depth
+			stats.MaxQueueDepth = max(stats.MaxQueueDepth, depth)
 		}
 	}
+	return stats
 }
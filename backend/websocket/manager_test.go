@@ -0,0 +1,497 @@
+package websocket
+
+import (
+	"ai-task-manager/events"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/gorilla/websocket"
+)
+
+type testPayload struct {
+	N    int    `json:"n"`
+	Data string `json:"data,omitempty"`
+}
+
+func testEvent(n int, data string, audience ...uuid.UUID) events.Event {
+	return events.New(events.TaskUpdated, uuid.Nil, testPayload{N: n, Data: data}, audience...)
+}
+
+// TestFullSendQueue fills the queues of connections nobody drains from
+// several publishers at once.
+func TestFullSendQueue(t *testing.T) {
+	const clients, publishers, perPublisher, queueSize = 20, 4, 50, 10
+
+	for _, policy := range []string{SlowClientDrop, SlowClientDisconnect} {
+		t.Run(policy, func(t *testing.T) {
+			manager := NewManager(Options{SendQueueSize: queueSize, SlowClientPolicy: policy})
+			var audience []uuid.UUID
+			var stalled []*client
+			for i := 0; i < clients; i++ {
+				c := newClient(manager, uuid.Must(uuid.NewV4()), nil, manager.currentOptions())
+				manager.register(c)
+				audience = append(audience, c.userID)
+				stalled = append(stalled, c)
+			}
+
+			var wg sync.WaitGroup
+			for p := 0; p < publishers; p++ {
+				wg.Add(1)
+				go func(p int) {
+					defer wg.Done()
+					for n := 0; n < perPublisher; n++ {
+						manager.HandleEvent(testEvent(p*perPublisher+n, "", audience...))
+					}
+				}(p)
+			}
+			wg.Wait()
+
+			stats := manager.Stats()
+			for _, c := range stalled {
+				if len(c.send) != queueSize {
+					t.Errorf("queue holds %d messages, want %d", len(c.send), queueSize)
+				}
+				select {
+				case <-c.done:
+					if policy == SlowClientDrop {
+						t.Error("a slow client was disconnected under the drop policy")
+					}
+				default:
+					if policy == SlowClientDisconnect {
+						t.Error("a slow client was kept under the disconnect policy")
+					}
+				}
+			}
+			if policy == SlowClientDrop {
+				if want := int64(clients * (publishers*perPublisher - queueSize)); stats.DroppedMessages != want {
+					t.Errorf("dropped %d messages, want %d", stats.DroppedMessages, want)
+				}
+				if stats.SlowDisconnects != 0 {
+					t.Errorf("%d slow disconnects, want 0", stats.SlowDisconnects)
+				}
+			} else {
+				// Every message past the first full queue counts, until the
+				// connection is gone.
+				if stats.SlowDisconnects < clients {
+					t.Errorf("%d slow disconnects, want at least %d", stats.SlowDisconnects, clients)
+				}
+				if stats.DroppedMessages != 0 {
+					t.Errorf("dropped %d messages, want 0", stats.DroppedMessages)
+				}
+			}
+		})
+	}
+}
+
+// testServer serves connections for the user in ?user=. With ?slow=1 the
+// server's socket buffer is shrunk, so a client that stops reading backs up
+// into its send queue after a few messages.
+func testServer(t *testing.T, manager *WebSocketManager) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userID, err := uuid.FromString(r.URL.Query().Get("user"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		upgrader := websocket.Upgrader{CheckOrigin: manager.checkOrigin}
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		if r.URL.Query().Get("slow") == "1" {
+			conn.UnderlyingConn().(*net.TCPConn).SetWriteBuffer(4096)
+		}
+		manager.Serve(userID, conn, 0)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func dial(t *testing.T, server *httptest.Server, userID uuid.UUID, slow bool) *websocket.Conn {
+	t.Helper()
+	url := "ws" + strings.TrimPrefix(server.URL, "http") + "/?user=" + userID.String()
+	dialer := websocket.Dialer{}
+	if slow {
+		url += "&slow=1"
+		dialer.NetDial = func(network, addr string) (net.Conn, error) {
+			conn, err := net.Dial(network, addr)
+			if err == nil {
+				conn.(*net.TCPConn).SetReadBuffer(4096)
+			}
+			return conn, err
+		}
+	}
+	conn, _, err := dialer.Dial(url, nil)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func waitFor(t *testing.T, what string, done func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(15 * time.Second)
+	for !done() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// TestSlowConnectionsAreEvicted connects many clients, some of which stop
+// reading. The readers get every event in order while the stalled ones are
+// disconnected, without holding anyone else up.
+func TestSlowConnectionsAreEvicted(t *testing.T) {
+	const readers, stalled, shared, flood = 40, 8, 100, 1000
+
+	manager := NewManager(Options{
+		SendQueueSize:    2 * shared,
+		SlowClientPolicy: SlowClientDisconnect,
+		WriteTimeout:     2 * time.Second,
+	})
+	server := testServer(t, manager)
+
+	var everyone, slowUsers []uuid.UUID
+	readerConns := make([]*websocket.Conn, readers)
+	for i := range readerConns {
+		userID := uuid.Must(uuid.NewV4())
+		everyone = append(everyone, userID)
+		readerConns[i] = dial(t, server, userID, false)
+	}
+	stalledConns := make([]*websocket.Conn, stalled)
+	for i := range stalledConns {
+		userID := uuid.Must(uuid.NewV4())
+		everyone = append(everyone, userID)
+		slowUsers = append(slowUsers, userID)
+		stalledConns[i] = dial(t, server, userID, true)
+	}
+	waitFor(t, "connections to register", func() bool {
+		return manager.Stats().Connections == readers+stalled
+	})
+
+	var slowClients []*client
+	manager.mu.RLock()
+	for _, userID := range slowUsers {
+		for c := range manager.clients[userID] {
+			slowClients = append(slowClients, c)
+		}
+	}
+	manager.mu.RUnlock()
+
+	var wg sync.WaitGroup
+	received := make([][]int, readers)
+	for i, conn := range readerConns {
+		wg.Add(1)
+		go func(i int, conn *websocket.Conn) {
+			defer wg.Done()
+			conn.SetReadDeadline(time.Now().Add(15 * time.Second))
+			for len(received[i]) < shared {
+				var event struct {
+					Payload testPayload `json:"payload"`
+				}
+				if err := conn.ReadJSON(&event); err != nil {
+					t.Errorf("reader %d: %v", i, err)
+					return
+				}
+				received[i] = append(received[i], event.Payload.N)
+			}
+		}(i, conn)
+	}
+
+	// Readers are sent fewer events than their queue holds, so only the
+	// stalled connections can fall behind.
+	for n := 0; n < shared; n++ {
+		manager.HandleEvent(testEvent(n, "", everyone...))
+	}
+	padding := strings.Repeat("x", 4096)
+	for n := 0; n < flood; n++ {
+		manager.HandleEvent(testEvent(n, padding, slowUsers...))
+	}
+	// The full queue closed them, rather than a write timing out.
+	for _, c := range slowClients {
+		c.closeMu.Lock()
+		code := c.closeCode
+		c.closeMu.Unlock()
+		if code != websocket.CloseTryAgainLater {
+			t.Errorf("stalled connection closed with code %d, want %d", code, websocket.CloseTryAgainLater)
+		}
+	}
+	wg.Wait()
+
+	for i, got := range received {
+		for n, value := range got {
+			if value != n {
+				t.Fatalf("reader %d got event %d at position %d", i, value, n)
+			}
+		}
+	}
+	waitFor(t, "stalled connections to be evicted", func() bool {
+		return manager.Stats().Connections == readers
+	})
+	if stats := manager.Stats(); stats.SlowDisconnects < stalled {
+		t.Errorf("%d slow disconnects, want at least %d", stats.SlowDisconnects, stalled)
+	}
+
+	// A stalled client that reads again finds its connection gone.
+	for _, conn := range stalledConns {
+		conn.SetReadDeadline(time.Now().Add(15 * time.Second))
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				break
+			}
+		}
+	}
+}
+
+func TestEventsOnlyReachTheirAudience(t *testing.T) {
+	const users, connectionsPerUser = 25, 2
+
+	manager := NewManager(Options{})
+	server := testServer(t, manager)
+
+	userIDs := make([]uuid.UUID, users)
+	conns := make([][]*websocket.Conn, users)
+	for i := range userIDs {
+		userIDs[i] = uuid.Must(uuid.NewV4())
+		for j := 0; j < connectionsPerUser; j++ {
+			conns[i] = append(conns[i], dial(t, server, userIDs[i], false))
+		}
+	}
+	waitFor(t, "connections to register", func() bool {
+		return manager.Stats().Connections == users*connectionsPerUser
+	})
+
+	// Each user is sent an event carrying its own index, from many
+	// goroutines at once.
+	var wg sync.WaitGroup
+	for i, userID := range userIDs {
+		wg.Add(1)
+		go func(i int, userID uuid.UUID) {
+			defer wg.Done()
+			manager.HandleEvent(testEvent(i, "", userID))
+		}(i, userID)
+	}
+	wg.Wait()
+
+	for i := range userIDs {
+		for _, conn := range conns[i] {
+			conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+			_, message, err := conn.ReadMessage()
+			if err != nil {
+				t.Fatalf("user %d: %v", i, err)
+			}
+			var event struct {
+				Payload testPayload `json:"payload"`
+			}
+			if err := json.Unmarshal(message, &event); err != nil {
+				t.Fatal(err)
+			}
+			if event.Payload.N != i {
+				t.Errorf("user %d was sent the event for user %d", i, event.Payload.N)
+			}
+		}
+	}
+
+	// Nothing else arrives: a ping is answered straight away, with no event
+	// queued ahead of it.
+	for i := range userIDs {
+		for _, conn := range conns[i] {
+			if err := conn.WriteJSON(command{Type: CommandPing, ID: "p"}); err != nil {
+				t.Fatal(err)
+			}
+			var r reply
+			if err := conn.ReadJSON(&r); err != nil {
+				t.Fatal(err)
+			}
+			if r.Type != "pong" {
+				t.Errorf("user %d was sent %q before the pong", i, r.Type)
+			}
+		}
+	}
+}
+
+func TestViewRequiresAccessToTheTask(t *testing.T) {
+	owner, stranger := uuid.Must(uuid.NewV4()), uuid.Must(uuid.NewV4())
+	taskID := uuid.Must(uuid.NewV4())
+	manager := NewManager(Options{
+		CanViewTask: func(userID, id uuid.UUID) (bool, error) {
+			return userID == owner && id == taskID, nil
+		},
+	})
+	server := testServer(t, manager)
+
+	view := func(userID uuid.UUID) reply {
+		conn := dial(t, server, userID, false)
+		conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		if err := conn.WriteJSON(command{Type: CommandView, ID: "v", TaskID: taskID.String()}); err != nil {
+			t.Fatal(err)
+		}
+		var r reply
+		if err := conn.ReadJSON(&r); err != nil {
+			t.Fatal(err)
+		}
+		return r
+	}
+
+	if r := view(stranger); r.Type != "error" || r.Code != ErrorInvalidTask {
+		t.Errorf("a stranger viewing the task got %q %q, want an %s error", r.Type, r.Code, ErrorInvalidTask)
+	}
+	r := view(owner)
+	if r.Type != "viewing" || len(r.Viewers) != 1 || r.Viewers[0].UserID != owner {
+		t.Errorf("the owner viewing the task got %q with viewers %v", r.Type, r.Viewers)
+	}
+	if viewers, err := manager.Viewers(taskID); err != nil || len(viewers) != 1 || viewers[0].UserID != owner {
+		t.Errorf("Viewers = %v, %v, want only the owner", viewers, err)
+	}
+}
+
+// TestPresenceAcrossInstances runs two managers sharing a presence store and
+// the signals, as two instances of the application would.
+func TestPresenceAcrossInstances(t *testing.T) {
+	owner, assignee := uuid.Must(uuid.NewV4()), uuid.Must(uuid.NewV4())
+	taskID := uuid.Must(uuid.NewV4())
+	store := newMemoryPresence()
+	var instances []*WebSocketManager
+	signal := func(signalled ...events.Event) {
+		for _, event := range signalled {
+			for _, instance := range instances {
+				instance.HandleEvent(event)
+			}
+		}
+	}
+	for i := 0; i < 2; i++ {
+		instances = append(instances, NewManager(Options{
+			CanViewTask: func(userID, id uuid.UUID) (bool, error) {
+				return (userID == owner || userID == assignee) && id == taskID, nil
+			},
+			Presence: store,
+			Signal:   signal,
+		}))
+	}
+
+	read := func(conn *websocket.Conn) map[string]any {
+		t.Helper()
+		conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		var message map[string]any
+		if err := conn.ReadJSON(&message); err != nil {
+			t.Fatal(err)
+		}
+		return message
+	}
+	view := func(conn *websocket.Conn) map[string]any {
+		t.Helper()
+		if err := conn.WriteJSON(command{Type: CommandView, ID: "v", TaskID: taskID.String()}); err != nil {
+			t.Fatal(err)
+		}
+		return read(conn)
+	}
+
+	ownerConn := dial(t, testServer(t, instances[0]), owner, false)
+	if r := view(ownerConn); r["type"] != "viewing" {
+		t.Fatalf("the owner viewing the task got %v", r)
+	}
+	assigneeConn := dial(t, testServer(t, instances[1]), assignee, false)
+	r := view(assigneeConn)
+	if viewers, _ := r["viewers"].([]any); len(viewers) != 2 {
+		t.Errorf("the assignee viewing the task got %v, want both viewers", r)
+	}
+
+	joined := read(ownerConn)
+	if payload, _ := joined["payload"].(map[string]any); joined["type"] != events.PresenceJoined || payload["userID"] != assignee.String() {
+		t.Errorf("the owner was sent %v, want the assignee joining", joined)
+	}
+	if viewers, err := instances[0].Viewers(taskID); err != nil || len(viewers) != 2 {
+		t.Errorf("Viewers on the owner's instance = %v, %v, want both users", viewers, err)
+	}
+
+	assigneeConn.Close()
+	left := read(ownerConn)
+	if payload, _ := left["payload"].(map[string]any); left["type"] != events.PresenceLeft || payload["userID"] != assignee.String() {
+		t.Errorf("the owner was sent %v, want the assignee leaving", left)
+	}
+	if viewers, err := instances[0].Viewers(taskID); err != nil || len(viewers) != 1 || viewers[0].UserID != owner {
+		t.Errorf("Viewers after the assignee left = %v, %v, want only the owner", viewers, err)
+	}
+}
+
+// memoryLog is an event log holding the events it is given, for replays.
+type memoryLog []events.Event
+
+func (l memoryLog) Append([]events.Event) error {
+	return nil
+}
+
+func (l memoryLog) Since(userID uuid.UUID, seq int64, limit int) ([]events.Event, error) {
+	var since []events.Event
+	for _, event := range l {
+		if event.Seq > seq && len(since) < limit {
+			since = append(since, event)
+		}
+	}
+	return since, nil
+}
+
+func (l memoryLog) Fetch([]int64) ([]events.Event, error) {
+	return nil, nil
+}
+
+func (l memoryLog) Bounds() (int64, int64, error) {
+	return l[0].Seq, l[len(l)-1].Seq, nil
+}
+
+// TestReplayKeepsEventsLoggedOutOfOrder holds back live events during a
+// replay, one of which was given a lower seq than the last replayed event
+// but was logged too late to be replayed.
+func TestReplayKeepsEventsLoggedOutOfOrder(t *testing.T) {
+	userID := uuid.Must(uuid.NewV4())
+	logged := func(seq int64) events.Event {
+		event := testEvent(int(seq), "", userID)
+		event.Seq = seq
+		return event
+	}
+	options := Options{EventLog: memoryLog{logged(4), logged(5), logged(7)}}
+	manager := NewManager(options)
+	c := newClient(manager, userID, nil, manager.currentOptions())
+	c.replaying = true
+	manager.register(c)
+
+	for _, seq := range []int64{7, 6} {
+		event := logged(seq)
+		message, err := json.Marshal(event)
+		if err != nil {
+			t.Fatal(err)
+		}
+		c.deliver(event.Seq, message)
+	}
+	c.replay(manager.currentOptions(), 4)
+
+	var got []string
+	for len(c.send) > 0 {
+		var message struct {
+			Type    string      `json:"type"`
+			Payload testPayload `json:"payload"`
+		}
+		if err := json.Unmarshal(<-c.send, &message); err != nil {
+			t.Fatal(err)
+		}
+		if message.Type == ReplyReplayed {
+			got = append(got, message.Type)
+		} else {
+			got = append(got, strconv.Itoa(message.Payload.N))
+		}
+	}
+	if want := []string{"5", "7", ReplyReplayed, "6"}; !slices.Equal(got, want) {
+		t.Errorf("sent %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,211 @@
+package websocket
+
+import (
+	"ai-task-manager/events"
+	"context"
+	"log"
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+const (
+	// typingTTL is how long a typing indicator lasts unless it is repeated.
+	typingTTL = 5 * time.Second
+	// typingInterval throttles the typing events one connection triggers.
+	typingInterval = 2 * time.Second
+)
+
+// Viewer is a user looking at a task through at least one connection.
+type Viewer struct {
+	UserID uuid.UUID `json:"userID"`
+	// Since is when the user's earliest connection started viewing the task.
+	Since  time.Time `json:"since"`
+	Typing bool      `json:"typing"`
+}
+
+// presence is the part of a client describing what it is looking at. It is
+// guarded by the manager's lock rather than the client's.
+type presence struct {
+	viewing    uuid.UUID
+	since      time.Time
+	lastTyping time.Time
+}
+
+// Viewers lists the users viewing a task on any instance, earliest first.
+func (manager *WebSocketManager) Viewers(taskID uuid.UUID) ([]Viewer, error) {
+	return manager.currentOptions().Presence.Viewers(taskID)
+}
+
+// canViewTask only lets the owner and the assignee of a task view it, the
+// same users who may read it over HTTP.
+func (manager *WebSocketManager) canViewTask(userID, taskID uuid.UUID) (bool, error) {
+	canView := manager.currentOptions().CanViewTask
+	if canView == nil {
+		return false, nil
+	}
+	return canView(userID, taskID)
+}
+
+// view makes c a viewer of taskID, leaving the task it viewed before, and
+// returns the task's viewers. Other viewers hear about a user joining or
+// leaving only when their first connection joins or their last one leaves.
+func (manager *WebSocketManager) view(c *client, taskID uuid.UUID) ([]Viewer, error) {
+	store := manager.currentOptions().Presence
+
+	manager.mu.Lock()
+	joining := c.presence.viewing != taskID
+	left := uuid.Nil
+	if joining {
+		left = manager.leaveLocked(c)
+		if manager.viewers[taskID] == nil {
+			manager.viewers[taskID] = make(map[*client]bool)
+		}
+		manager.viewers[taskID][c] = true
+		c.presence = presence{viewing: taskID, since: time.Now()}
+	}
+	since := c.presence.since
+	manager.mu.Unlock()
+
+	if left != uuid.Nil {
+		manager.forget(c, left)
+	}
+	if joining {
+		first, err := store.View(c.id, c.userID, taskID, since)
+		if err != nil {
+			manager.mu.Lock()
+			manager.leaveLocked(c)
+			manager.mu.Unlock()
+			return nil, err
+		}
+		if first {
+			manager.signal(events.NewPresence(events.PresenceJoined, taskID, c.userID))
+		}
+	}
+	return store.Viewers(taskID)
+}
+
+// leave stops c viewing its task. It reports whether it was viewing one.
+func (manager *WebSocketManager) leave(c *client) bool {
+	manager.mu.Lock()
+	taskID := manager.leaveLocked(c)
+	manager.mu.Unlock()
+
+	if taskID == uuid.Nil {
+		return false
+	}
+	manager.forget(c, taskID)
+	return true
+}
+
+// leaveLocked stops c viewing its task on this instance and returns the task,
+// or uuid.Nil when it was not viewing one.
+func (manager *WebSocketManager) leaveLocked(c *client) uuid.UUID {
+	taskID := c.presence.viewing
+	if taskID == uuid.Nil {
+		return uuid.Nil
+	}
+	delete(manager.viewers[taskID], c)
+	if len(manager.viewers[taskID]) == 0 {
+		delete(manager.viewers, taskID)
+	}
+	c.presence = presence{}
+	return taskID
+}
+
+// forget removes c from the viewers of taskID in the store and tells the
+// others when it was its user's last connection there.
+func (manager *WebSocketManager) forget(c *client, taskID uuid.UUID) {
+	last, err := manager.currentOptions().Presence.Leave(c.id, c.userID, taskID)
+	if err != nil {
+		log.Printf("Error removing a viewer of task %s: %v", taskID, err)
+		return
+	}
+	if last {
+		manager.signal(events.NewPresence(events.PresenceLeft, taskID, c.userID))
+	}
+}
+
+// typing tells the other viewers of c's task that its user is typing. It
+// reports whether c is viewing a task.
+func (manager *WebSocketManager) typing(c *client) bool {
+	manager.mu.Lock()
+	taskID := c.presence.viewing
+	if taskID == uuid.Nil {
+		manager.mu.Unlock()
+		return false
+	}
+	now := time.Now()
+	if now.Sub(c.presence.lastTyping) < typingInterval {
+		manager.mu.Unlock()
+		return true
+	}
+	c.presence.lastTyping = now
+	store := manager.options.Presence
+	manager.mu.Unlock()
+
+	until := now.Add(typingTTL)
+	if err := store.Typing(c.id, until); err != nil {
+		log.Printf("Error recording typing on task %s: %v", taskID, err)
+	}
+	manager.signal(events.NewPresenceTyping(taskID, c.userID, until))
+	return true
+}
+
+// signal shares a presence event with the viewers on every instance.
+func (manager *WebSocketManager) signal(event events.Event) {
+	if signal := manager.currentOptions().Signal; signal != nil {
+		signal(event)
+		return
+	}
+	manager.HandleEvent(event)
+}
+
+// sendPresence queues a presence event for the connections on this instance
+// viewing its task, except those of the user it is about.
+func (manager *WebSocketManager) sendPresence(event events.Event, message []byte) {
+	if len(event.Scope.TaskIDs) == 0 {
+		return
+	}
+	manager.mu.RLock()
+	var targets []*client
+	for c := range manager.viewers[event.Scope.TaskIDs[0]] {
+		if event.Actor == nil || c.userID != *event.Actor {
+			targets = append(targets, c)
+		}
+	}
+	manager.mu.RUnlock()
+
+	for _, c := range targets {
+		c.enqueue(message)
+	}
+}
+
+// RunPresence keeps the viewers connected to this instance listed in the
+// presence store until ctx is cancelled.
+func (manager *WebSocketManager) RunPresence(ctx context.Context) {
+	ticker := time.NewTicker(presenceRefreshInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		manager.mu.RLock()
+		store := manager.options.Presence
+		var connectionIDs []uuid.UUID
+		for _, clients := range manager.viewers {
+			for c := range clients {
+				connectionIDs = append(connectionIDs, c.id)
+			}
+		}
+		manager.mu.RUnlock()
+
+		if err := store.Refresh(connectionIDs); err != nil {
+			log.Printf("Error refreshing %d viewers: %v", len(connectionIDs), err)
+		}
+	}
+}
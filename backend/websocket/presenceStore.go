@@ -0,0 +1,224 @@
+package websocket
+
+import (
+	"ai-task-manager/models"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	// presenceRefreshInterval is how often an instance tells the store its
+	// connections are still viewing their tasks.
+	presenceRefreshInterval = 15 * time.Second
+	// presenceTTL is how long a connection stays listed without a refresh,
+	// so the viewers of an instance that went away disappear after it.
+	presenceTTL = 3 * presenceRefreshInterval
+)
+
+// PresenceStore records which connections view which task, so that the
+// viewers of a task can be listed whichever instance they are connected to.
+type PresenceStore interface {
+	// View records that a connection of userID views taskID. It reports
+	// whether it is the user's first connection viewing it.
+	View(connectionID, userID, taskID uuid.UUID, since time.Time) (first bool, err error)
+	// Leave forgets a connection. It reports whether it was the user's last
+	// connection viewing taskID.
+	Leave(connectionID, userID, taskID uuid.UUID) (last bool, err error)
+	// Typing records that the user of a connection types until until.
+	Typing(connectionID uuid.UUID, until time.Time) error
+	// Viewers lists the users viewing taskID, earliest first.
+	Viewers(taskID uuid.UUID) ([]Viewer, error)
+	// Refresh keeps the given connections listed and forgets the ones that
+	// expired.
+	Refresh(connectionIDs []uuid.UUID) error
+}
+
+// viewersOf merges the connections viewing a task into one viewer per user,
+// earliest first.
+func viewersOf(connections []models.TaskViewer, now time.Time) []Viewer {
+	byUser := make(map[uuid.UUID]int)
+	viewers := []Viewer{}
+	for _, connection := range connections {
+		i, ok := byUser[connection.UserID]
+		if !ok {
+			i = len(viewers)
+			byUser[connection.UserID] = i
+			viewers = append(viewers, Viewer{UserID: connection.UserID, Since: connection.Since})
+		}
+		viewer := &viewers[i]
+		if connection.Since.Before(viewer.Since) {
+			viewer.Since = connection.Since
+		}
+		viewer.Typing = viewer.Typing || connection.TypingUntil.After(now)
+	}
+	slices.SortFunc(viewers, func(a, b Viewer) int {
+		return a.Since.Compare(b.Since)
+	})
+	return viewers
+}
+
+// memoryPresence is the store of a single instance. Its connections always
+// leave when they close, so nothing expires.
+type memoryPresence struct {
+	mu          sync.Mutex
+	connections map[uuid.UUID]models.TaskViewer
+}
+
+func newMemoryPresence() *memoryPresence {
+	return &memoryPresence{connections: make(map[uuid.UUID]models.TaskViewer)}
+}
+
+func (p *memoryPresence) View(connectionID, userID, taskID uuid.UUID, since time.Time) (bool, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	first := !p.viewingLocked(connectionID, userID, taskID)
+	p.connections[connectionID] = models.TaskViewer{
+		ConnectionID: connectionID,
+		TaskID:       taskID,
+		UserID:       userID,
+		Since:        since,
+		SeenAt:       since,
+	}
+	return first, nil
+}
+
+func (p *memoryPresence) Leave(connectionID, userID, taskID uuid.UUID) (bool, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if _, ok := p.connections[connectionID]; !ok {
+		return false, nil
+	}
+	delete(p.connections, connectionID)
+	return !p.viewingLocked(connectionID, userID, taskID), nil
+}
+
+// viewingLocked reports whether a connection of userID other than
+// connectionID views taskID.
+func (p *memoryPresence) viewingLocked(connectionID, userID, taskID uuid.UUID) bool {
+	for id, connection := range p.connections {
+		if id != connectionID && connection.UserID == userID && connection.TaskID == taskID {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *memoryPresence) Typing(connectionID uuid.UUID, until time.Time) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if connection, ok := p.connections[connectionID]; ok {
+		connection.TypingUntil = until
+		p.connections[connectionID] = connection
+	}
+	return nil
+}
+
+func (p *memoryPresence) Viewers(taskID uuid.UUID) ([]Viewer, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var connections []models.TaskViewer
+	for _, connection := range p.connections {
+		if connection.TaskID == taskID {
+			connections = append(connections, connection)
+		}
+	}
+	return viewersOf(connections, time.Now()), nil
+}
+
+func (p *memoryPresence) Refresh([]uuid.UUID) error {
+	return nil
+}
+
+type dbPresence struct {
+	db *gorm.DB
+}
+
+// NewDBPresence returns a PresenceStore kept in the task_viewers table and
+// shared by every instance using the database. Each instance has to run
+// WebSocketManager.RunPresence so its viewers do not expire.
+func NewDBPresence(db *gorm.DB) PresenceStore {
+	return &dbPresence{db: db}
+}
+
+// lockViewer serialises the changes to one user's connections viewing a
+// task until tx ends, so that exactly one of them is first or last.
+func lockViewer(tx *gorm.DB, userID, taskID uuid.UUID) error {
+	return tx.Exec("SELECT pg_advisory_xact_lock(hashtextextended(?, 0))", taskID.String()+userID.String()).Error
+}
+
+// otherConnections counts the live connections of userID other than
+// connectionID viewing taskID.
+func otherConnections(tx *gorm.DB, connectionID, userID, taskID uuid.UUID) (int64, error) {
+	var count int64
+	err := tx.Model(&models.TaskViewer{}).
+		Where("task_id = ? AND user_id = ? AND connection_id <> ? AND seen_at > ?", taskID, userID, connectionID, time.Now().Add(-presenceTTL)).
+		Count(&count).Error
+	return count, err
+}
+
+func (p *dbPresence) View(connectionID, userID, taskID uuid.UUID, since time.Time) (bool, error) {
+	var first bool
+	err := p.db.Transaction(func(tx *gorm.DB) error {
+		if err := lockViewer(tx, userID, taskID); err != nil {
+			return err
+		}
+		viewer := models.TaskViewer{
+			ConnectionID: connectionID,
+			TaskID:       taskID,
+			UserID:       userID,
+			Since:        since,
+			SeenAt:       time.Now(),
+		}
+		if err := tx.Clauses(clause.OnConflict{UpdateAll: true}).Create(&viewer).Error; err != nil {
+			return err
+		}
+		others, err := otherConnections(tx, connectionID, userID, taskID)
+		first = others == 0
+		return err
+	})
+	return first, err
+}
+
+func (p *dbPresence) Leave(connectionID, userID, taskID uuid.UUID) (bool, error) {
+	var last bool
+	err := p.db.Transaction(func(tx *gorm.DB) error {
+		if err := lockViewer(tx, userID, taskID); err != nil {
+			return err
+		}
+		result := tx.Delete(&models.TaskViewer{}, "connection_id = ?", connectionID)
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+		others, err := otherConnections(tx, connectionID, userID, taskID)
+		last = others == 0
+		return err
+	})
+	return last, err
+}
+
+func (p *dbPresence) Typing(connectionID uuid.UUID, until time.Time) error {
+	return p.db.Model(&models.TaskViewer{}).Where("connection_id = ?", connectionID).Update("typing_until", until).Error
+}
+
+func (p *dbPresence) Viewers(taskID uuid.UUID) ([]Viewer, error) {
+	var connections []models.TaskViewer
+	if err := p.db.Where("task_id = ? AND seen_at > ?", taskID, time.Now().Add(-presenceTTL)).Find(&connections).Error; err != nil {
+		return nil, err
+	}
+	return viewersOf(connections, time.Now()), nil
+}
+
+func (p *dbPresence) Refresh(connectionIDs []uuid.UUID) error {
+	now := time.Now()
+	if len(connectionIDs) > 0 {
+		if err := p.db.Model(&models.TaskViewer{}).Where("connection_id IN ?", connectionIDs).Update("seen_at", now).Error; err != nil {
+			return err
+		}
+	}
+	return p.db.Where("seen_at <= ?", now.Add(-presenceTTL)).Delete(&models.TaskViewer{}).Error
+}
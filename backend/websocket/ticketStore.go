@@ -0,0 +1,96 @@
+package websocket
+
+import (
+	"ai-task-manager/models"
+	"sync"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// TicketStore keeps the connection tickets that were issued and not redeemed
+// yet, by the hash of the ticket.
+type TicketStore interface {
+	// Issue records a ticket for userID valid until expiresAt and forgets
+	// the tickets that expired.
+	Issue(hash string, userID uuid.UUID, expiresAt time.Time) error
+	// Redeem forgets a ticket and returns the user it was issued to. It
+	// reports false when the ticket is unknown, already redeemed or expired.
+	Redeem(hash string) (uuid.UUID, bool, error)
+}
+
+// ticket is a single-use credential for opening a connection from a browser,
+// which cannot set an Authorization header on the upgrade request.
+type ticket struct {
+	userID    uuid.UUID
+	expiresAt time.Time
+}
+
+// memoryTickets is the store of a single instance.
+type memoryTickets struct {
+	mu      sync.Mutex
+	tickets map[string]ticket
+}
+
+func newMemoryTickets() *memoryTickets {
+	return &memoryTickets{tickets: make(map[string]ticket)}
+}
+
+func (m *memoryTickets) Issue(hash string, userID uuid.UUID, expiresAt time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := time.Now()
+	for other, t := range m.tickets {
+		if now.After(t.expiresAt) {
+			delete(m.tickets, other)
+		}
+	}
+	m.tickets[hash] = ticket{userID: userID, expiresAt: expiresAt}
+	return nil
+}
+
+func (m *memoryTickets) Redeem(hash string) (uuid.UUID, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	t, ok := m.tickets[hash]
+	if !ok {
+		return uuid.Nil, false, nil
+	}
+	delete(m.tickets, hash)
+	if time.Now().After(t.expiresAt) {
+		return uuid.Nil, false, nil
+	}
+	return t.userID, true, nil
+}
+
+type dbTickets struct {
+	db *gorm.DB
+}
+
+// NewDBTickets returns a TicketStore kept in the websocket_tickets table, so
+// that a ticket issued by one instance can be redeemed on any other.
+func NewDBTickets(db *gorm.DB) TicketStore {
+	return &dbTickets{db: db}
+}
+
+func (d *dbTickets) Issue(hash string, userID uuid.UUID, expiresAt time.Time) error {
+	if err := d.db.Where("expires_at < ?", time.Now()).Delete(&models.WebSocketTicket{}).Error; err != nil {
+		return err
+	}
+	return d.db.Create(&models.WebSocketTicket{TicketHash: hash, UserID: userID, ExpiresAt: expiresAt}).Error
+}
+
+// Redeem deletes the ticket and reads it back in one statement, so that two
+// instances redeeming the same ticket at once cannot both succeed.
+func (d *dbTickets) Redeem(hash string) (uuid.UUID, bool, error) {
+	var redeemed []models.WebSocketTicket
+	if err := d.db.Model(&redeemed).Clauses(clause.Returning{}).Where("ticket_hash = ?", hash).Delete(&redeemed).Error; err != nil {
+		return uuid.Nil, false, err
+	}
+	if len(redeemed) == 0 || time.Now().After(redeemed[0].ExpiresAt) {
+		return uuid.Nil, false, nil
+	}
+	return redeemed[0].UserID, true, nil
+}
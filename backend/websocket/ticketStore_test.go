@@ -0,0 +1,83 @@
+package websocket
+
+import (
+	"ai-task-manager/database/dbtest"
+	"ai-task-manager/utils"
+	"database/sql/driver"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+// TestTicketsAcrossInstances issues a ticket on one manager and redeems it on
+// another sharing the ticket store, as behind a load balancer.
+func TestTicketsAcrossInstances(t *testing.T) {
+	store := newMemoryTickets()
+	issuer := NewManager(Options{Tickets: store})
+	redeemer := NewManager(Options{Tickets: store})
+	userID := uuid.Must(uuid.NewV4())
+
+	token, _, err := issuer.IssueTicket(userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, ok := redeemer.RedeemTicket(token); !ok || got != userID {
+		t.Fatalf("RedeemTicket on the other instance = %s, %v, want %s", got, ok, userID)
+	}
+	if _, ok := issuer.RedeemTicket(token); ok {
+		t.Error("the ticket was redeemed twice")
+	}
+
+	expiring := NewManager(Options{Tickets: store, TicketTTL: time.Millisecond})
+	token, _, err = expiring.IssueTicket(userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(5 * time.Millisecond)
+	if _, ok := redeemer.RedeemTicket(token); ok {
+		t.Error("an expired ticket was redeemed")
+	}
+}
+
+func TestDBTicketsStoreHashesAndAreRedeemedOnce(t *testing.T) {
+	db, fake := dbtest.New(t)
+	var mu sync.Mutex
+	rows := make(map[string][]driver.Value)
+	fake.On(`^INSERT INTO "websocket_tickets"`, func(args []any) dbtest.Result {
+		mu.Lock()
+		defer mu.Unlock()
+		rows[args[0].(string)] = []driver.Value{args[0], args[1].(uuid.UUID).String(), args[2]}
+		return dbtest.Result{RowsAffected: 1}
+	})
+	fake.On(`^DELETE FROM "websocket_tickets" WHERE ticket_hash = \$1 RETURNING`, func(args []any) dbtest.Result {
+		mu.Lock()
+		defer mu.Unlock()
+		result := dbtest.Result{Columns: []string{"ticket_hash", "user_id", "expires_at"}}
+		if row, ok := rows[args[0].(string)]; ok {
+			delete(rows, args[0].(string))
+			result.Rows = append(result.Rows, row)
+		}
+		return result
+	})
+
+	manager := NewManager(Options{Tickets: NewDBTickets(db)})
+	userID := uuid.Must(uuid.NewV4())
+	token, _, err := manager.IssueTicket(userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	inserts := fake.Statements(`^INSERT INTO "websocket_tickets"`)
+	if len(inserts) != 1 || slices.Contains(inserts[0].Args, any(token)) || !slices.Contains(inserts[0].Args, any(utils.HashToken(token))) {
+		t.Fatalf("the ticket was not stored by its hash alone: %v", inserts)
+	}
+
+	if got, ok := manager.RedeemTicket(token); !ok || got != userID {
+		t.Fatalf("RedeemTicket = %s, %v, want %s", got, ok, userID)
+	}
+	if _, ok := manager.RedeemTicket(token); ok {
+		t.Error("the ticket was redeemed twice")
+	}
+}
@@ -20,6 +20,10 @@ export const updateUserProfile = async (userId, userData) => {
   return api.patch(`/users/update-user-profile/c/${userId}`, userData)
 }
 
+export const changePassword = async (currentPassword, newPassword) => {
+  return api.post('/users/change-password', { currentPassword, newPassword })
+}
+
 export const deleteUserAccount = async () => {
   return api.delete('/users/delete-user-profile')
 }